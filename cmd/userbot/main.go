@@ -11,7 +11,11 @@ import (
 	"syscall"
 	"time"
 
+	"github.com/larriantoniy/tg_user_bot/internal/adapters/admin"
+	"github.com/larriantoniy/tg_user_bot/internal/adapters/metrics"
+	"github.com/larriantoniy/tg_user_bot/internal/adapters/moderation"
 	neuro "github.com/larriantoniy/tg_user_bot/internal/adapters/neuro"
+	"github.com/larriantoniy/tg_user_bot/internal/adapters/store"
 	"github.com/larriantoniy/tg_user_bot/internal/adapters/tg"
 	"github.com/larriantoniy/tg_user_bot/internal/config"
 	"github.com/larriantoniy/tg_user_bot/internal/domain"
@@ -51,16 +55,49 @@ func main() {
 		return
 	}
 	cfgRepo := config.NewJSONSessionConfigRepo(baseDir)
+	if err := validatePrompts(cfg, cfgRepo); err != nil {
+		logger.Error("prompt templates are invalid", "error", err)
+		os.Exit(1)
+	}
+	if err := neuro.ValidateProviders(cfg); err != nil {
+		logger.Error("neuro providers are invalid", "error", err)
+		os.Exit(1)
+	}
+	if _, err := moderation.NewRules(cfg.Moderation.Categories, cfg.Moderation.Rules); err != nil {
+		logger.Error("moderation rules are invalid", "error", err)
+		os.Exit(1)
+	}
+	postFilter, err := newPostFilter(cfg.Filter)
+	if err != nil {
+		logger.Error("post filter rules are invalid", "error", err)
+		os.Exit(1)
+	}
 
 	// фабрику делаем без tdParams – их теперь создаёт NewClientFromJSON
 	factory := func(sc *ports.SessionConfig, l *slog.Logger) (ports.TelegramClient, error) {
 		// можно логгер завязывать на сессию:
 		sessionLogger := l.With("session", sc.SessionName)
 	    sessionLogger.Info("factory", "sc.SessionName", sc.SessionName)
-		return tg.NewClientFromJSON(cfg.ApiID, cfg.ApiHash, baseDir, sc.SessionName, sessionLogger, 0)
+		// на старте промпты проверены, а сессии, добавленные Watch, проверяем здесь — до подъёма TDLib
+		if err := neuro.ValidatePrompts(cfg.Prompts, []*ports.SessionConfig{sc}); err != nil {
+			sessionLogger.Error("session prompt config is invalid", "error", err)
+			return nil, fmt.Errorf("invalid prompt config: %w", err)
+		}
+		cli, err := tg.NewClientFromJSON(cfg.ApiID, cfg.ApiHash, baseDir, sc.SessionName, sessionLogger, 0)
+		if err != nil {
+			return nil, err
+		}
+		vision := cfg.Vision.Enabled
+		if sc.Vision != nil {
+			vision = *sc.Vision
+		}
+		cli.SetVision(vision, cfg.Vision.MaxImageBytes)
+		cli.SetThreadContext(cfg.Thread.Replies)
+		cli.SetConversation(cfg.Conversation.Enabled)
+		return cli, nil
 	}
 
-	runner := useCases.NewRunner(cfgRepo, logger, factory)
+	runner := useCases.NewRunner(cfgRepo, logger, factory, restartPolicy(cfg.Supervisor))
 
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
@@ -74,29 +111,124 @@ func main() {
 		cancel()
 	}()
 
-	clientsCh, err := runner.StartAll(ctx)
+	commentStore, err := newCommentStore(ctx, cfg.Store, logger)
+	if err != nil {
+		logger.Error("comment store init error", "driver", cfg.Store.Driver, "error", err)
+		os.Exit(1)
+	}
+	defer commentStore.Close()
+
+	commentQueue, err := newCommentQueue(ctx, cfg, logger)
+	if err != nil {
+		logger.Error("comment queue init error", "driver", cfg.Store.Driver, "error", err)
+		os.Exit(1)
+	}
+	defer commentQueue.Close()
+
+	promMetrics := metrics.NewPrometheus()
+	// один breaker на провайдера на весь процесс: лежащий LLM отбивается сразу во всех сессиях
+	breakers := neuro.NewBreakers(cfg.NeuroBreaker, logger, promMetrics)
+	// учёт расходов тоже общий: общий лимит считается по всем сессиям
+	usageLedger, err := newUsageLedger(cfg, logger)
+	if err != nil {
+		logger.Error("usage ledger init error", "path", cfg.Usage.Path, "error", err)
+		os.Exit(1)
+	}
+	defer usageLedger.Close()
+	accounting := neuro.NewAccounting(cfg.Usage, usageLedger, logger, promMetrics)
+
+	adminSrv := admin.NewServer(cfg.AdminAddr, cfg.AdminToken, runner, logger)
+	// /metrics сознательно без токена: его скрейпит Prometheus снаружи контейнера через опубликованный порт
+	adminSrv.Handle("/metrics", promMetrics.Handler())
+	adminSrv.HandleBreakers(breakers)
+	adminSrv.HandleUsage(accounting)
+	go func() {
+		if err := adminSrv.Run(ctx); err != nil {
+			logger.Error("admin server error", "error", err)
+		}
+	}()
+
+	events, unsubscribe := runner.Subscribe(64)
+	defer unsubscribe()
+	go func() {
+		for ev := range events {
+			logger.Info("session event",
+				"session", ev.Session,
+				"type", ev.Type,
+				"restarts", ev.Restarts,
+				"backoff", ev.Backoff,
+				"permanent", ev.Permanent,
+				"error", ev.Error,
+			)
+		}
+	}()
+
+	sessionsCh, err := runner.StartAll(ctx)
 	if err != nil {
 		logger.Error("runner.StartAll error", "error", err)
 		os.Exit(1)
 	}
+	if cfg.Supervisor.WatchInterval > 0 {
+		// новые папки в base_dir подхватываются без рестарта остальных сессий
+		go runner.Watch(ctx, cfg.Supervisor.WatchInterval)
+	}
 
-	for cli := range clientsCh {
-		neuro, err := neuro.NewNeuro(cfg, logger)
+	for rs := range sessionsCh {
+		cli := rs.Client
+		neuro, err := neuro.NewNeuro(cfg, logger, rs.Name, rs.Config, promMetrics, breakers, accounting)
 		if err != nil {
 			logger.Error("neuro.NewNeuro error", "error", err)
-			cli.Close()
+			runner.MarkStopped(rs.Name, err)
 			continue
 		}
 
-		sender := useCases.NewSender(logger, cli, neuro, cfg.Owner)
-		go func(c ports.TelegramClient) {
-			defer c.Close()
+		limiter := useCases.NewCommentLimiter(rs.Name, commentStore)
+		sessionLogger := logger.With("session", rs.Name)
+		sender := useCases.NewSender(sessionLogger, cli, neuro, limiter, commentQueue, promMetrics, cfg.Owner)
+		if cfg.Owner != "" {
+			sender.SetUsage(accounting)
+		}
+		if rules, ok := validationRules(cfg.Validation); ok {
+			sender.SetValidator(useCases.NewCommentValidator(rules))
+		}
+		if postFilter != nil {
+			sender.SetPostFilter(postFilter)
+		}
+		if rules, ok := dedupRules(cfg.Dedup); ok {
+			sender.SetDedup(&rules)
+		}
+		if cfg.Conversation.Enabled {
+			rules := conversationRules(cfg.Conversation)
+			sender.SetConversation(&rules)
+		}
+		// классификатором служит нейросеть самой сессии: её провайдеры, breaker и бюджет
+		moderator, err := moderation.NewFromConfig(cfg.Moderation, neuro, sessionLogger)
+		if err != nil {
+			sessionLogger.Error("moderation init error, posts are not moderated", "error", err)
+		} else if moderator != nil {
+			sender.SetModerator(moderator)
+		}
+		if cfg.Approval {
+			if cfg.Owner == "" {
+				sessionLogger.Warn("approval mode requires OWNER, comments are scheduled without approval")
+			} else {
+				sender.SetApproval(true)
+			}
+		}
+		worker := useCases.NewCommentWorker(sessionLogger, rs.Name, commentQueue, sender, cfg.Queue.PollInterval, cfg.Queue.StaleAfter)
+		// всё, что привязано к клиенту, живёт в rs.Ctx: при рестарте сессии он отменяется,
+		// а сам клиент закрывает Runner
+		go worker.Run(rs.Ctx)
+		runner.Bind(rs.Name, sender)
 
+		go func(ctx context.Context, name string, c ports.TelegramClient) {
 			msgCh, err := c.Listen()
 			if err != nil {
 				logger.Error("Listen error", "error", err)
+				runner.MarkStopped(name, err)
 				return
 			}
+			defer func() { runner.MarkStopped(name, c.StopReason()) }()
 
 			inFlight := make(chan struct{}, maxInFlightComments)
 			for m := range msgCh {
@@ -104,12 +236,31 @@ func main() {
 				inFlight <- struct{}{}
 				go func(msg domain.Message) {
 					defer func() { <-inFlight }()
+					if msg.IsPrivate {
+						// команды владельца: черновики (режим одобрения) и /usage
+						if err := sender.HandleOwnerMessage(ctx, &msg); err != nil {
+							logger.Error("HandleOwnerMessage error", "error", err)
+						}
+						return
+					}
 					c.ImitateReading(ctx, msg.ChatID)
 					if err := sender.SendComment(ctx, &msg); err != nil {
-						if errors.Is(err, tg.ErrRateLimited) {
-							logger.Error("SendComment: rate limited for this client", "error", err)
-							// эта горутина просто заканчивается,
-							// а сам TDLib-клиент уже закрыт внутри tg.SendMessage
+						if errors.Is(err, ports.ErrRateLimited) {
+							// сессия ждёт FLOOD_WAIT и продолжит сама, клиент остаётся живым
+							logger.Warn("SendComment: session is in FLOOD_WAIT, post skipped", "error", err)
+							return
+						}
+						if errors.Is(err, ports.ErrNeuroQuota) {
+							// Sender сам не зовёт нейросеть до конца паузы по квоте
+							logger.Warn("SendComment: LLM quota exhausted, post skipped", "error", err)
+							return
+						}
+						if errors.Is(err, ports.ErrNeuroBudget) {
+							logger.Warn("SendComment: LLM budget exceeded, post skipped", "error", err)
+							return
+						}
+						if errors.Is(err, ports.ErrNeuroCircuitOpen) {
+							logger.Warn("SendComment: neuro circuit open, post skipped", "error", err)
 							return
 						}
 						logger.Error("SendComment error", "error", err)
@@ -117,11 +268,167 @@ func main() {
 				}(msg)
 
 			}
-		}(cli)
+		}(rs.Ctx, rs.Name, cli)
 	}
 	logger.Info("exit")
 }
 
+// newUsageLedger: при store.driver=memory расход живёт до рестарта, иначе — в файле usage.path
+func newUsageLedger(cfg *config.AppConfig, logger *slog.Logger) (ports.UsageLedger, error) {
+	if cfg.Store.Driver == config.StoreDriverMemory {
+		return store.NewMemoryUsage(), nil
+	}
+	return store.NewFileUsage(cfg.Usage.Path, logger)
+}
+
+// validationRules накладывает настройки валидатора на правила по умолчанию; false — валидатор выключен
+func validationRules(vc config.ValidationConfig) (useCases.ValidationRules, bool) {
+	if vc.Enabled != nil && !*vc.Enabled {
+		return useCases.ValidationRules{}, false
+	}
+	r := useCases.DefaultValidationRules
+	limit := func(dst *int, v, off int) {
+		switch {
+		case v < 0:
+			*dst = off
+		case v > 0:
+			*dst = v
+		}
+	}
+	limit(&r.MaxWords, vc.MaxWords, 0)
+	limit(&r.MaxChars, vc.MaxChars, 0)
+	limit(&r.MinEmoji, vc.MinEmoji, 0)
+	limit(&r.MaxEmoji, vc.MaxEmoji, -1)
+	limit(&r.EchoWords, vc.EchoWords, 0)
+	limit(&r.Regenerate, vc.Regenerate, 0)
+	r.AllowLinks = vc.AllowLinks
+	r.AllowMentions = vc.AllowMentions
+	r.AllowQuestions = vc.AllowQuestions
+	r.BannedPhrases = append(append([]string(nil), r.BannedPhrases...), vc.BannedPhrases...)
+	return r, true
+}
+
+// newPostFilter собирает фильтр постов: общие правила поверх встроенных, правила каналов поверх общих.
+// nil, nil — фильтр выключен.
+func newPostFilter(fc config.FilterConfig) (*useCases.PostFilter, error) {
+	if fc.Enabled != nil && !*fc.Enabled {
+		return nil, nil
+	}
+	def := filterRules(useCases.DefaultFilterRules, fc.FilterRulesConfig)
+	channels := make(map[string]useCases.FilterRules, len(fc.Channels))
+	for key, rc := range fc.Channels {
+		channels[key] = filterRules(def, rc)
+	}
+	return useCases.NewPostFilter(def, channels)
+}
+
+func filterRules(base useCases.FilterRules, rc config.FilterRulesConfig) useCases.FilterRules {
+	r := base
+	limit := func(dst *int, v int) {
+		switch {
+		case v < 0:
+			*dst = 0
+		case v > 0:
+			*dst = v
+		}
+	}
+	limit(&r.MinChars, rc.MinChars)
+	limit(&r.MaxChars, rc.MaxChars)
+	flag := func(dst *bool, v *bool) {
+		if v != nil {
+			*dst = *v
+		}
+	}
+	flag(&r.SkipForwarded, rc.SkipForwarded)
+	flag(&r.SkipLinks, rc.SkipLinks)
+	flag(&r.SkipAds, rc.SkipAds)
+	r.Include = append(append([]string(nil), r.Include...), rc.Include...)
+	r.Exclude = append(append([]string(nil), r.Exclude...), rc.Exclude...)
+	r.AdMarkers = append(append([]string(nil), r.AdMarkers...), rc.AdMarkers...)
+	return r
+}
+
+// dedupRules накладывает настройки поиска повторов на правила по умолчанию; false — выключен
+func dedupRules(dc config.DedupConfig) (useCases.DedupRules, bool) {
+	if dc.Enabled != nil && !*dc.Enabled {
+		return useCases.DedupRules{}, false
+	}
+	r := useCases.DefaultDedupRules
+	if dc.Threshold > 0 {
+		r.Threshold = dc.Threshold
+	}
+	history := func(dst *int, v int) {
+		switch {
+		case v < 0:
+			*dst = 0
+		case v > 0:
+			*dst = v
+		}
+	}
+	history(&r.SessionHistory, dc.SessionHistory)
+	history(&r.GlobalHistory, dc.GlobalHistory)
+	return r, true
+}
+
+// conversationRules накладывает лимиты режима переписки на правила по умолчанию
+func conversationRules(cc config.ConversationConfig) useCases.ConversationRules {
+	r := useCases.DefaultConversationRules
+	limit := func(dst *int, v int) {
+		switch {
+		case v < 0:
+			*dst = 0
+		case v > 0:
+			*dst = v
+		}
+	}
+	limit(&r.MaxDepth, cc.MaxDepth)
+	limit(&r.MaxPerUser, cc.MaxPerUser)
+	limit(&r.MaxPerThread, cc.MaxPerThread)
+	if cc.Window > 0 {
+		r.Window = cc.Window
+	}
+	return r
+}
+
+// restartPolicy накладывает настройки supervisor из конфига на политику по умолчанию
+func restartPolicy(sc config.SupervisorConfig) useCases.RestartPolicy {
+	p := useCases.DefaultRestartPolicy
+	if sc.InitialBackoff > 0 {
+		p.InitialBackoff = sc.InitialBackoff
+	}
+	if sc.MaxBackoff > 0 {
+		p.MaxBackoff = sc.MaxBackoff
+	}
+	switch {
+	case sc.MaxRestarts < 0:
+		p.MaxRestarts = 0
+	case sc.MaxRestarts > 0:
+		p.MaxRestarts = sc.MaxRestarts
+	}
+	if sc.ResetAfter > 0 {
+		p.ResetAfter = sc.ResetAfter
+	}
+	return p
+}
+
+// validatePrompts проверяет шаблоны промптов и их выбор в каждой сессии до запуска клиентов
+func validatePrompts(cfg *config.AppConfig, repo ports.SessionConfigRepo) error {
+	ctx := context.Background()
+	names, err := repo.ListSessions(ctx)
+	if err != nil {
+		return err
+	}
+	sessions := make([]*ports.SessionConfig, 0, len(names))
+	for _, name := range names {
+		sc, err := repo.GetSessionConfig(ctx, name)
+		if err != nil {
+			return fmt.Errorf("session %s: %w", name, err)
+		}
+		sessions = append(sessions, sc)
+	}
+	return neuro.ValidatePrompts(cfg.Prompts, sessions)
+}
+
 func setupLogger(env string) *slog.Logger {
 	var logger *slog.Logger
 
@@ -143,6 +450,31 @@ func setupLogger(env string) *slog.Logger {
 	return logger
 }
 
+func newCommentStore(ctx context.Context, sc config.StoreConfig, logger *slog.Logger) (ports.CommentStore, error) {
+	switch sc.Driver {
+	case config.StoreDriverMemory:
+		logger.Warn("comment store is in memory: seen threads are lost on restart")
+		return store.NewMemoryStore(sc.TTL), nil
+	case config.StoreDriverRedis:
+		return store.NewRedisStore(ctx, sc.RedisAddr, sc.RedisPassword, sc.RedisDB, sc.RedisPrefix, sc.TTL)
+	default:
+		return store.NewFileStore(sc.Path, sc.TTL, logger)
+	}
+}
+
+func newCommentQueue(ctx context.Context, cfg *config.AppConfig, logger *slog.Logger) (ports.CommentQueue, error) {
+	sc := cfg.Store
+	switch sc.Driver {
+	case config.StoreDriverMemory:
+		logger.Warn("comment queue is in memory: planned comments are lost on restart")
+		return store.NewMemoryQueue(), nil
+	case config.StoreDriverRedis:
+		return store.NewRedisQueue(ctx, sc.RedisAddr, sc.RedisPassword, sc.RedisDB, sc.RedisPrefix)
+	default:
+		return store.NewFileQueue(cfg.Queue.Path, logger)
+	}
+}
+
 func runAuthMode(logger *slog.Logger, cfg *config.AppConfig) error {
 	cli, err := tg.NewClientFromJSON(
 		cfg.ApiID,
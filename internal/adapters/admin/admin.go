@@ -0,0 +1,283 @@
+package admin
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/larriantoniy/tg_user_bot/internal/domain"
+)
+
+// SessionController — то, что админке нужно от раннера сессий
+type SessionController interface {
+	Sessions(ctx context.Context) []domain.SessionStatus
+	Session(ctx context.Context, name string) (domain.SessionStatus, error)
+	PauseSession(name string, paused bool) error
+	PauseChat(name string, chatID int64, paused bool) error
+}
+
+// BreakerSource — состояние circuit breaker'ов LLM-провайдеров
+type BreakerSource interface {
+	Breakers() []domain.BreakerStatus
+}
+
+// UsageSource — сводка расходов на LLM; пустой session — по всем сессиям
+type UsageSource interface {
+	UsageReport(ctx context.Context, session string) (domain.UsageReport, error)
+}
+
+// Server — встроенный HTTP API для управления сессиями без рестарта контейнера.
+//
+//	GET  /healthz
+//	GET  /metrics (если подключён через Handle; без токена, как и /healthz)
+//	GET  /sessions
+//	GET  /sessions/{name}
+//	POST /sessions/{name}/pause | /resume
+//	POST /sessions/{name}/chats/{chat_id}/pause | /resume
+//	GET  /neuro/breakers (если подключён HandleBreakers)
+//	GET  /neuro/usage[?session=name] (если подключён HandleUsage)
+type Server struct {
+	srv    *http.Server
+	ctrl   SessionController
+	logger *slog.Logger
+	token  string // если задан, нужен заголовок Authorization: Bearer <token>
+	mux    *http.ServeMux
+}
+
+func NewServer(addr, token string, ctrl SessionController, logger *slog.Logger) *Server {
+	s := &Server{
+		ctrl:   ctrl,
+		logger: logger,
+		token:  token,
+		mux:    http.NewServeMux(),
+	}
+	s.mux.HandleFunc("/healthz", s.handleHealth)
+	s.mux.HandleFunc("/sessions", s.auth(s.handleSessions))
+	s.mux.HandleFunc("/sessions/", s.auth(s.handleSession))
+
+	s.srv = &http.Server{
+		Addr:              addr,
+		Handler:           s.mux,
+		ReadHeaderTimeout: 5 * time.Second,
+	}
+	return s
+}
+
+// Handle регистрирует дополнительный обработчик без авторизации — для /metrics:
+// Prometheus скрейпит без Bearer-токена, а метрики только читаются и ничего не меняют
+func (s *Server) Handle(pattern string, h http.Handler) {
+	s.mux.Handle(pattern, h)
+}
+
+// HandleBreakers подключает GET /neuro/breakers
+func (s *Server) HandleBreakers(src BreakerSource) {
+	s.mux.HandleFunc("/neuro/breakers", s.auth(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+			return
+		}
+		writeJSON(w, http.StatusOK, src.Breakers())
+	}))
+}
+
+// HandleUsage подключает GET /neuro/usage — расход на LLM за месяц
+func (s *Server) HandleUsage(src UsageSource) {
+	s.mux.HandleFunc("/neuro/usage", s.auth(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+			return
+		}
+		rep, err := src.UsageReport(r.Context(), r.URL.Query().Get("session"))
+		if err != nil {
+			s.logger.Error("admin: usage report", "error", err)
+			writeError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		writeJSON(w, http.StatusOK, rep)
+	}))
+}
+
+// Run слушает addr до отмены ctx.
+// Без токена слушаем только loopback: иначе pause/resume открыты всем, кому виден порт.
+func (s *Server) Run(ctx context.Context) error {
+	if err := checkListen(s.srv.Addr, s.token); err != nil {
+		return err
+	}
+
+	errCh := make(chan error, 1)
+	go func() {
+		s.logger.Info("admin server listening", "addr", s.srv.Addr)
+		errCh <- s.srv.ListenAndServe()
+	}()
+
+	select {
+	case err := <-errCh:
+		if errors.Is(err, http.ErrServerClosed) {
+			return nil
+		}
+		return err
+	case <-ctx.Done():
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		return s.srv.Shutdown(shutdownCtx)
+	}
+}
+
+// checkListen не даёт открыть админку без токена наружу
+func checkListen(addr, token string) error {
+	if token == "" && !isLoopbackAddr(addr) {
+		return fmt.Errorf("admin: refusing to listen on %q without ADMIN_TOKEN; set the token or bind to 127.0.0.1", addr)
+	}
+	return nil
+}
+
+// isLoopbackAddr — адрес вида host:port, где host — localhost или loopback-IP.
+// Пустой host (":7231") слушает все интерфейсы, поэтому не loopback.
+func isLoopbackAddr(addr string) bool {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil || host == "" {
+		return false
+	}
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+func (s *Server) auth(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if s.token != "" {
+			got := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+			if subtle.ConstantTimeCompare([]byte(got), []byte(s.token)) != 1 {
+				writeError(w, http.StatusUnauthorized, "unauthorized")
+				return
+			}
+		}
+		next(w, r)
+	}
+}
+
+func (s *Server) handleHealth(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+}
+
+func (s *Server) handleSessions(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	writeJSON(w, http.StatusOK, s.ctrl.Sessions(r.Context()))
+}
+
+// handleSession разбирает /sessions/{name}[/pause|/resume|/chats/{chat_id}/pause|/resume]
+func (s *Server) handleSession(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(strings.Trim(strings.TrimPrefix(r.URL.Path, "/sessions/"), "/"), "/")
+	name := parts[0]
+	if name == "" {
+		writeError(w, http.StatusNotFound, "not found")
+		return
+	}
+
+	switch {
+	case len(parts) == 1:
+		if r.Method != http.MethodGet {
+			writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+			return
+		}
+		st, err := s.ctrl.Session(r.Context(), name)
+		if err != nil {
+			s.writeCtrlError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, st)
+
+	case len(parts) == 2:
+		paused, ok := pauseAction(parts[1])
+		if !ok {
+			writeError(w, http.StatusNotFound, "not found")
+			return
+		}
+		if r.Method != http.MethodPost {
+			writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+			return
+		}
+		if err := s.ctrl.PauseSession(name, paused); err != nil {
+			s.writeCtrlError(w, err)
+			return
+		}
+		s.logger.Info("admin: session pause changed", "session", name, "paused", paused)
+		s.writeSession(w, r, name)
+
+	case len(parts) == 4 && parts[1] == "chats":
+		chatID, err := strconv.ParseInt(parts[2], 10, 64)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "invalid chat_id")
+			return
+		}
+		paused, ok := pauseAction(parts[3])
+		if !ok {
+			writeError(w, http.StatusNotFound, "not found")
+			return
+		}
+		if r.Method != http.MethodPost {
+			writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+			return
+		}
+		if err := s.ctrl.PauseChat(name, chatID, paused); err != nil {
+			s.writeCtrlError(w, err)
+			return
+		}
+		s.logger.Info("admin: chat pause changed", "session", name, "chat_id", chatID, "paused", paused)
+		s.writeSession(w, r, name)
+
+	default:
+		writeError(w, http.StatusNotFound, "not found")
+	}
+}
+
+func (s *Server) writeSession(w http.ResponseWriter, r *http.Request, name string) {
+	st, err := s.ctrl.Session(r.Context(), name)
+	if err != nil {
+		s.writeCtrlError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, st)
+}
+
+func (s *Server) writeCtrlError(w http.ResponseWriter, err error) {
+	if errors.Is(err, domain.ErrSessionNotFound) {
+		writeError(w, http.StatusNotFound, err.Error())
+		return
+	}
+	s.logger.Error("admin request failed", "error", err)
+	writeError(w, http.StatusInternalServerError, err.Error())
+}
+
+func pauseAction(action string) (paused bool, ok bool) {
+	switch action {
+	case "pause":
+		return true, true
+	case "resume":
+		return false, true
+	}
+	return false, false
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, msg string) {
+	writeJSON(w, status, map[string]string{"error": msg})
+}
@@ -0,0 +1,239 @@
+package admin
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/larriantoniy/tg_user_bot/internal/domain"
+	"gopkg.in/yaml.v3"
+)
+
+type fakeController struct {
+	sessions    map[string]*domain.SessionStatus
+	pausedChats map[int64]bool
+}
+
+func newFakeController() *fakeController {
+	return &fakeController{
+		sessions: map[string]*domain.SessionStatus{
+			"alpha": {Name: "alpha", State: domain.SessionAuthorized, PendingComments: 2},
+			"beta":  {Name: "beta", State: domain.SessionRateLimited},
+		},
+		pausedChats: make(map[int64]bool),
+	}
+}
+
+func (f *fakeController) Sessions(ctx context.Context) []domain.SessionStatus {
+	return []domain.SessionStatus{*f.sessions["alpha"], *f.sessions["beta"]}
+}
+
+func (f *fakeController) Session(ctx context.Context, name string) (domain.SessionStatus, error) {
+	st, ok := f.sessions[name]
+	if !ok {
+		return domain.SessionStatus{}, domain.ErrSessionNotFound
+	}
+	return *st, nil
+}
+
+func (f *fakeController) PauseSession(name string, paused bool) error {
+	st, ok := f.sessions[name]
+	if !ok {
+		return domain.ErrSessionNotFound
+	}
+	st.Paused = paused
+	return nil
+}
+
+func (f *fakeController) PauseChat(name string, chatID int64, paused bool) error {
+	st, ok := f.sessions[name]
+	if !ok {
+		return domain.ErrSessionNotFound
+	}
+	f.pausedChats[chatID] = paused
+	st.PausedChats = nil
+	for id, p := range f.pausedChats {
+		if p {
+			st.PausedChats = append(st.PausedChats, id)
+		}
+	}
+	return nil
+}
+
+func do(t *testing.T, s *Server, method, path, token string) (*httptest.ResponseRecorder, domain.SessionStatus) {
+	t.Helper()
+	req := httptest.NewRequest(method, path, nil)
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	rec := httptest.NewRecorder()
+	s.mux.ServeHTTP(rec, req)
+
+	var st domain.SessionStatus
+	_ = json.Unmarshal(rec.Body.Bytes(), &st)
+	return rec, st
+}
+
+func TestAdminRoutes(t *testing.T) {
+	ctrl := newFakeController()
+	s := NewServer(":0", "", ctrl, slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	rec, _ := do(t, s, http.MethodGet, "/sessions", "")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("GET /sessions = %d", rec.Code)
+	}
+	var list []domain.SessionStatus
+	if err := json.Unmarshal(rec.Body.Bytes(), &list); err != nil || len(list) != 2 {
+		t.Fatalf("GET /sessions body = %s", rec.Body.String())
+	}
+
+	rec, st := do(t, s, http.MethodGet, "/sessions/alpha", "")
+	if rec.Code != http.StatusOK || st.PendingComments != 2 {
+		t.Errorf("GET /sessions/alpha = %d %+v", rec.Code, st)
+	}
+
+	rec, st = do(t, s, http.MethodPost, "/sessions/alpha/pause", "")
+	if rec.Code != http.StatusOK || !st.Paused {
+		t.Errorf("POST pause = %d %+v, want paused", rec.Code, st)
+	}
+	rec, st = do(t, s, http.MethodPost, "/sessions/alpha/resume", "")
+	if rec.Code != http.StatusOK || st.Paused {
+		t.Errorf("POST resume = %d %+v, want not paused", rec.Code, st)
+	}
+
+	rec, st = do(t, s, http.MethodPost, "/sessions/alpha/chats/-100123/pause", "")
+	if rec.Code != http.StatusOK || len(st.PausedChats) != 1 || st.PausedChats[0] != -100123 {
+		t.Errorf("POST chat pause = %d %+v", rec.Code, st)
+	}
+
+	if rec, _ := do(t, s, http.MethodGet, "/sessions/alpha/pause", ""); rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("GET pause = %d, want 405", rec.Code)
+	}
+	if rec, _ := do(t, s, http.MethodPost, "/sessions/alpha/chats/abc/pause", ""); rec.Code != http.StatusBadRequest {
+		t.Errorf("POST chat pause with bad id = %d, want 400", rec.Code)
+	}
+	if rec, _ := do(t, s, http.MethodGet, "/sessions/gamma", ""); rec.Code != http.StatusNotFound {
+		t.Errorf("GET unknown session = %d, want 404", rec.Code)
+	}
+}
+
+func TestAdminToken(t *testing.T) {
+	s := NewServer(":0", "secret", newFakeController(), slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	if rec, _ := do(t, s, http.MethodGet, "/sessions", ""); rec.Code != http.StatusUnauthorized {
+		t.Errorf("GET /sessions without token = %d, want 401", rec.Code)
+	}
+	if rec, _ := do(t, s, http.MethodGet, "/sessions", "wrong"); rec.Code != http.StatusUnauthorized {
+		t.Errorf("GET /sessions with wrong token = %d, want 401", rec.Code)
+	}
+	if rec, _ := do(t, s, http.MethodGet, "/sessions", "secret"); rec.Code != http.StatusOK {
+		t.Errorf("GET /sessions with token = %d, want 200", rec.Code)
+	}
+	if rec, _ := do(t, s, http.MethodGet, "/healthz", ""); rec.Code != http.StatusOK {
+		t.Errorf("GET /healthz = %d, want 200 without token", rec.Code)
+	}
+	s.Handle("/metrics", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	if rec, _ := do(t, s, http.MethodGet, "/metrics", ""); rec.Code != http.StatusOK {
+		t.Errorf("GET /metrics = %d, want 200 without token", rec.Code)
+	}
+}
+
+func TestAdminRunRequiresTokenOffLoopback(t *testing.T) {
+	s := NewServer(":0", "", newFakeController(), slog.New(slog.NewTextHandler(io.Discard, nil)))
+	if err := s.Run(context.Background()); err == nil {
+		t.Fatal("Run() on all interfaces without token = nil, want error")
+	}
+
+	for addr, want := range map[string]bool{
+		"127.0.0.1:7231": true,
+		"localhost:7231": true,
+		"[::1]:7231":     true,
+		":7231":          false,
+		"0.0.0.0:7231":   false,
+		"10.0.0.5:7231":  false,
+	} {
+		if got := isLoopbackAddr(addr); got != want {
+			t.Errorf("isLoopbackAddr(%q) = %v, want %v", addr, got, want)
+		}
+	}
+}
+
+// конфиг из образа должен поднимать админку на опубликованном порту, если задан ADMIN_TOKEN
+func TestShippedConfigAdminListens(t *testing.T) {
+	data, err := os.ReadFile("../../../config/dev.yaml")
+	if err != nil {
+		t.Fatalf("read dev.yaml: %v", err)
+	}
+	var cfg struct {
+		AdminAddr string `yaml:"admin_addr"`
+	}
+	if err := yaml.Unmarshal(data, &cfg); err != nil {
+		t.Fatalf("parse dev.yaml: %v", err)
+	}
+	if isLoopbackAddr(cfg.AdminAddr) {
+		t.Errorf("admin_addr = %q: loopback is unreachable through the port published by docker-compose", cfg.AdminAddr)
+	}
+	if err := checkListen(cfg.AdminAddr, "secret"); err != nil {
+		t.Errorf("checkListen(%q) with token error = %v", cfg.AdminAddr, err)
+	}
+	if err := checkListen(cfg.AdminAddr, ""); err == nil {
+		t.Errorf("checkListen(%q) without token = nil, want error", cfg.AdminAddr)
+	}
+}
+
+type fakeBreakers []domain.BreakerStatus
+
+func (f fakeBreakers) Breakers() []domain.BreakerStatus { return f }
+
+func TestAdminBreakers(t *testing.T) {
+	s := NewServer(":0", "secret", newFakeController(), slog.New(slog.NewTextHandler(io.Discard, nil)))
+	s.HandleBreakers(fakeBreakers{
+		{Provider: "openrouter", State: domain.BreakerOpen, Failures: 5},
+		{Provider: "local", State: domain.BreakerClosed},
+	})
+
+	if rec, _ := do(t, s, http.MethodGet, "/neuro/breakers", ""); rec.Code != http.StatusUnauthorized {
+		t.Errorf("GET /neuro/breakers without token = %d, want 401", rec.Code)
+	}
+	rec, _ := do(t, s, http.MethodGet, "/neuro/breakers", "secret")
+	var got []domain.BreakerStatus
+	if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil || rec.Code != http.StatusOK {
+		t.Fatalf("GET /neuro/breakers = %d %s", rec.Code, rec.Body.String())
+	}
+	if len(got) != 2 || got[0].State != domain.BreakerOpen || got[0].Failures != 5 {
+		t.Errorf("breakers = %+v, want open openrouter first", got)
+	}
+	if rec, _ := do(t, s, http.MethodPost, "/neuro/breakers", "secret"); rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("POST /neuro/breakers = %d, want 405", rec.Code)
+	}
+}
+
+type fakeUsage struct{ got string }
+
+func (f *fakeUsage) UsageReport(ctx context.Context, session string) (domain.UsageReport, error) {
+	f.got = session
+	return domain.UsageReport{Session: session, Currency: "USD", Month: domain.UsageTotals{Requests: 3, Cost: 0.25}}, nil
+}
+
+func TestAdminUsage(t *testing.T) {
+	s := NewServer(":0", "secret", newFakeController(), slog.New(slog.NewTextHandler(io.Discard, nil)))
+	src := &fakeUsage{}
+	s.HandleUsage(src)
+
+	rec, _ := do(t, s, http.MethodGet, "/neuro/usage?session=alpha", "secret")
+	var got domain.UsageReport
+	if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil || rec.Code != http.StatusOK {
+		t.Fatalf("GET /neuro/usage = %d %s", rec.Code, rec.Body.String())
+	}
+	if src.got != "alpha" || got.Session != "alpha" || got.Month.Requests != 3 || got.Month.Cost != 0.25 {
+		t.Errorf("usage = %+v (asked for %q), want alpha report", got, src.got)
+	}
+	if rec, _ := do(t, s, http.MethodGet, "/neuro/usage", ""); rec.Code != http.StatusUnauthorized {
+		t.Errorf("GET /neuro/usage without token = %d, want 401", rec.Code)
+	}
+}
@@ -0,0 +1,150 @@
+package metrics
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/larriantoniy/tg_user_bot/internal/domain"
+	"github.com/larriantoniy/tg_user_bot/internal/ports"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "tg_warm_bot"
+
+var (
+	_ ports.Metrics = (*Prometheus)(nil)
+	_ ports.Metrics = Nop{}
+)
+
+// Prometheus — реализация ports.Metrics со своим реестром, отдаётся через Handler()
+type Prometheus struct {
+	reg *prometheus.Registry
+
+	postsReceived   *prometheus.CounterVec
+	commentsSkipped *prometheus.CounterVec
+	commentsSent    *prometheus.CounterVec
+	neuroDuration   *prometheus.HistogramVec
+	neuroTokens     *prometheus.CounterVec
+	neuroBreaker    *prometheus.GaugeVec
+	neuroCost       *prometheus.CounterVec
+}
+
+func NewPrometheus() *Prometheus {
+	p := &Prometheus{
+		reg: prometheus.NewRegistry(),
+		postsReceived: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "channel_posts_received_total",
+			Help:      "Channel posts with a discussion thread received by the session.",
+		}, []string{"session"}),
+		commentsSkipped: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "comments_skipped_total",
+			Help:      "Posts or planned comments dropped without commenting, by reason.",
+		}, []string{"session", "reason"}),
+		commentsSent: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "comments_sent_total",
+			Help:      "Comments posted to discussion threads.",
+		}, []string{"session"}),
+		neuroDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "neuro_request_duration_seconds",
+			Help:      "Latency of a single LLM HTTP request.",
+			Buckets:   []float64{0.25, 0.5, 1, 2, 4, 8, 15, 30, 60},
+		}, []string{"session", "model", "status"}),
+		neuroTokens: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "neuro_tokens_total",
+			Help:      "LLM tokens reported in usage, by type (prompt/completion).",
+		}, []string{"session", "model", "type"}),
+		neuroBreaker: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "neuro_circuit_state",
+			Help:      "Circuit breaker state of an LLM provider: 0 closed, 1 half-open, 2 open.",
+		}, []string{"provider"}),
+		neuroCost: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "neuro_cost_total",
+			Help:      "LLM spend computed from the usage price table, in the configured currency.",
+		}, []string{"session", "model"}),
+	}
+
+	p.reg.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		p.postsReceived,
+		p.commentsSkipped,
+		p.commentsSent,
+		p.neuroDuration,
+		p.neuroTokens,
+		p.neuroBreaker,
+		p.neuroCost,
+	)
+	return p
+}
+
+// Handler отдаёт метрики в формате Prometheus
+func (p *Prometheus) Handler() http.Handler {
+	return promhttp.HandlerFor(p.reg, promhttp.HandlerOpts{})
+}
+
+// Registry нужен, чтобы другие адаптеры могли регистрировать свои коллекторы
+func (p *Prometheus) Registry() *prometheus.Registry {
+	return p.reg
+}
+
+func (p *Prometheus) PostReceived(session string) {
+	p.postsReceived.WithLabelValues(session).Inc()
+}
+
+func (p *Prometheus) CommentSkipped(session string, reason domain.SkipReason) {
+	p.commentsSkipped.WithLabelValues(session, string(reason)).Inc()
+}
+
+func (p *Prometheus) CommentSent(session string) {
+	p.commentsSent.WithLabelValues(session).Inc()
+}
+
+func (p *Prometheus) NeuroCall(session, model string, d time.Duration, usage domain.Usage, err error) {
+	status := "ok"
+	if err != nil {
+		status = "error"
+	}
+	p.neuroDuration.WithLabelValues(session, model, status).Observe(d.Seconds())
+	if usage.PromptTokens > 0 {
+		p.neuroTokens.WithLabelValues(session, model, "prompt").Add(float64(usage.PromptTokens))
+	}
+	if usage.CompletionTokens > 0 {
+		p.neuroTokens.WithLabelValues(session, model, "completion").Add(float64(usage.CompletionTokens))
+	}
+}
+
+func (p *Prometheus) NeuroBreakerState(provider string, state domain.BreakerState) {
+	v := 0.0
+	switch state {
+	case domain.BreakerHalfOpen:
+		v = 1
+	case domain.BreakerOpen:
+		v = 2
+	}
+	p.neuroBreaker.WithLabelValues(provider).Set(v)
+}
+
+func (p *Prometheus) NeuroCost(session, model string, cost float64) {
+	if cost > 0 {
+		p.neuroCost.WithLabelValues(session, model).Add(cost)
+	}
+}
+
+// Nop — пустая реализация для тестов и режимов без метрик
+type Nop struct{}
+
+func (Nop) PostReceived(string)                                          {}
+func (Nop) CommentSkipped(string, domain.SkipReason)                     {}
+func (Nop) CommentSent(string)                                           {}
+func (Nop) NeuroCall(string, string, time.Duration, domain.Usage, error) {}
+func (Nop) NeuroBreakerState(string, domain.BreakerState)                {}
+func (Nop) NeuroCost(string, string, float64)                            {}
@@ -0,0 +1,44 @@
+package metrics
+
+import (
+	"errors"
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/larriantoniy/tg_user_bot/internal/domain"
+)
+
+func TestPrometheusExposition(t *testing.T) {
+	p := NewPrometheus()
+
+	p.PostReceived("s1")
+	p.PostReceived("s1")
+	p.CommentSkipped("s1", domain.SkipAlreadySeen)
+	p.CommentSent("s1")
+	p.NeuroCall("s1", "model-a", 300*time.Millisecond, domain.Usage{PromptTokens: 80, CompletionTokens: 12}, nil)
+	p.NeuroCall("s1", "model-a", time.Second, domain.Usage{}, errors.New("status 500"))
+	p.NeuroBreakerState("openrouter", domain.BreakerOpen)
+
+	rec := httptest.NewRecorder()
+	p.Handler().ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	body, _ := io.ReadAll(rec.Body)
+	out := string(body)
+
+	for _, want := range []string{
+		`tg_warm_bot_channel_posts_received_total{session="s1"} 2`,
+		`tg_warm_bot_comments_skipped_total{reason="already_seen",session="s1"} 1`,
+		`tg_warm_bot_comments_sent_total{session="s1"} 1`,
+		`tg_warm_bot_neuro_tokens_total{model="model-a",session="s1",type="prompt"} 80`,
+		`tg_warm_bot_neuro_tokens_total{model="model-a",session="s1",type="completion"} 12`,
+		`tg_warm_bot_neuro_request_duration_seconds_count{model="model-a",session="s1",status="ok"} 1`,
+		`tg_warm_bot_neuro_request_duration_seconds_count{model="model-a",session="s1",status="error"} 1`,
+		`tg_warm_bot_neuro_circuit_state{provider="openrouter"} 2`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("/metrics output does not contain %q", want)
+		}
+	}
+}
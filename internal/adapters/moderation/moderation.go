@@ -0,0 +1,179 @@
+package moderation
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/larriantoniy/tg_user_bot/internal/config"
+	"github.com/larriantoniy/tg_user_bot/internal/domain"
+	"github.com/larriantoniy/tg_user_bot/internal/ports"
+)
+
+var (
+	_ ports.Moderator = (*Rules)(nil)
+	_ ports.Moderator = (*Moderator)(nil)
+)
+
+// builtin — встроенные правила. Слово или фраза совпадают только целиком
+// («погибли» не ловит «погиб»), «слово*» — начало слова, /.../ — регулярное
+// выражение без учёта регистра. Списки подобраны так, чтобы не задевать финансовые
+// новости: «президент ФРС», «торговая война», «катастрофическое падение» проходят.
+var builtin = map[string][]string{
+	domain.ModerationTragedy: {
+		"теракт*", "террорист*", "погиб", "погибла", "погибли", "погибло", "погибших", "погибшие", "гибель", "гибели",
+		"авиакатастроф*", "трагедия", "трагедии", "соболезнован*", "скончался", "скончалась",
+		"убийство", "убийства", "убит", "убиты", "убитых", "стрельба", "стрельбы", "землетрясени*", "траур*",
+		"killed", "tragedy", "condolences", "terrorist*", "terror attack",
+	},
+	domain.ModerationPolitics: {
+		"выборы", "выборах", "выборов", "госдум*", "депутат", "депутаты", "депутатов", "оппозици*",
+		"митинг", "митинги", "митингов", "боевые действия", "военные действия", "мобилизаци*",
+		"election", "elections",
+	},
+	domain.ModerationAdult: {
+		"порн*", "эротик*", "эротическ*", "секс", "сексуальн*", "nsfw", "porn*", "onlyfans", `/(^|\D)18\+/`,
+	},
+	domain.ModerationOffensive: {
+		"хуй*", "хуе*", "пизд*", "ебат*", "ебан*", "бляд*", "сука", "суки", "мудак*", "дебил*",
+		"идиот", "идиоты", "идиотов", "fuck*", "shit",
+	},
+}
+
+type rule struct {
+	category string
+	pattern  string // как в конфиге, для логов
+	keyword  string // нормализованное слово или фраза; пусто — re
+	prefix   bool   // keyword — начало слова, а не слово целиком
+	re       *regexp.Regexp
+}
+
+// Rules — локальная модерация по ключевым словам и регуляркам
+type Rules struct {
+	rules []rule
+}
+
+// NewRules собирает встроенные категории (пусто — все) и свои правила из конфига
+func NewRules(categories []string, custom map[string][]string) (*Rules, error) {
+	if len(categories) == 0 {
+		categories = domain.ModerationCategories
+	}
+	r := &Rules{}
+	for _, c := range categories {
+		patterns, ok := builtin[c]
+		if !ok {
+			return nil, fmt.Errorf("moderation: unknown category %q, known: %s", c, strings.Join(domain.ModerationCategories, ", "))
+		}
+		if err := r.add(c, patterns); err != nil {
+			return nil, err
+		}
+	}
+	names := make([]string, 0, len(custom))
+	for c := range custom {
+		names = append(names, c)
+	}
+	sort.Strings(names)
+	for _, c := range names {
+		if err := r.add(c, custom[c]); err != nil {
+			return nil, err
+		}
+	}
+	return r, nil
+}
+
+func (r *Rules) add(category string, patterns []string) error {
+	for _, p := range patterns {
+		p = strings.TrimSpace(p)
+		if p == "" {
+			continue
+		}
+		if len(p) > 2 && strings.HasPrefix(p, "/") && strings.HasSuffix(p, "/") {
+			re, err := regexp.Compile("(?i)" + p[1:len(p)-1])
+			if err != nil {
+				return fmt.Errorf("moderation: category %q: pattern %s: %w", category, p, err)
+			}
+			r.rules = append(r.rules, rule{category: category, pattern: p, re: re})
+			continue
+		}
+		word, prefix := strings.CutSuffix(p, "*")
+		r.rules = append(r.rules, rule{category: category, pattern: p, keyword: normalize(word), prefix: prefix})
+	}
+	return nil
+}
+
+// Moderate находит первое сработавшее правило; ошибки не бывает
+func (r *Rules) Moderate(ctx context.Context, target domain.ModerationTarget, text string) (domain.ModerationVerdict, error) {
+	norm := " " + normalize(text) + " "
+	for _, ru := range r.rules {
+		var hit bool
+		switch {
+		case ru.re != nil:
+			hit = ru.re.MatchString(text)
+		case ru.prefix:
+			hit = strings.Contains(norm, " "+ru.keyword)
+		default:
+			hit = strings.Contains(norm, " "+ru.keyword+" ")
+		}
+		if hit {
+			return domain.ModerationVerdict{Flagged: true, Category: ru.category, Rule: ru.pattern, Source: "rules"}, nil
+		}
+	}
+	return domain.ModerationVerdict{}, nil
+}
+
+// normalize — слова в нижнем регистре через пробел, без знаков препинания
+func normalize(text string) string {
+	fields := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	return strings.Join(fields, " ")
+}
+
+// Moderator — локальные правила, затем (если есть) LLM-классификатор
+type Moderator struct {
+	rules      *Rules
+	classifier ports.Moderator // nil — только правила
+	failOpen   bool            // классификатор не ответил — пропускаем, а не блокируем
+	logger     *slog.Logger
+}
+
+func New(rules *Rules, classifier ports.Moderator, failOpen bool, logger *slog.Logger) *Moderator {
+	return &Moderator{rules: rules, classifier: classifier, failOpen: failOpen, logger: logger}
+}
+
+// NewFromConfig — правила и классификатор по настройкам; nil, nil — модерация выключена
+func NewFromConfig(mc config.ModerationConfig, classifier ports.Moderator, logger *slog.Logger) (*Moderator, error) {
+	if !mc.Enabled {
+		return nil, nil
+	}
+	rules, err := NewRules(mc.Categories, mc.Rules)
+	if err != nil {
+		return nil, err
+	}
+	if !mc.LLM {
+		classifier = nil
+	}
+	return New(rules, classifier, mc.FailOpen, logger), nil
+}
+
+func (m *Moderator) Moderate(ctx context.Context, target domain.ModerationTarget, text string) (domain.ModerationVerdict, error) {
+	if v, _ := m.rules.Moderate(ctx, target, text); v.Flagged {
+		return v, nil
+	}
+	if m.classifier == nil {
+		return domain.ModerationVerdict{}, nil
+	}
+	v, err := m.classifier.Moderate(ctx, target, text)
+	if err != nil {
+		if m.failOpen {
+			m.logger.Warn("Moderation classifier failed, text allowed", "target", target, "error", err)
+			return domain.ModerationVerdict{}, nil
+		}
+		return domain.ModerationVerdict{}, fmt.Errorf("moderation classifier: %w", err)
+	}
+	return v, nil
+}
@@ -0,0 +1,135 @@
+package moderation
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"testing"
+
+	"github.com/larriantoniy/tg_user_bot/internal/config"
+	"github.com/larriantoniy/tg_user_bot/internal/domain"
+)
+
+func TestRulesModerate(t *testing.T) {
+	r, err := NewRules(nil, map[string][]string{
+		"scam": {"пирамид*", `/гарантированн\S* доход/`},
+	})
+	if err != nil {
+		t.Fatalf("NewRules() error = %v", err)
+	}
+
+	tests := []struct {
+		text         string
+		wantCategory string
+	}{
+		{"Ставка ЦБ осталась на уровне 16%, рынок ждал снижения", ""},
+		{"В результате аварии погибли 12 человек", domain.ModerationTragedy},
+		{"Итоги ВЫБОРОВ в Госдуму", domain.ModerationPolitics},
+		{"Канал 18+ только для взрослых", domain.ModerationAdult},
+		{"Автор — идиот", domain.ModerationOffensive},
+		{"Вступайте в нашу пирамиду", "scam"},
+		{"Гарантированный доход 30% в месяц", "scam"},
+		{"Инвестиции без гарантий доходности", ""},
+		{"В перестрелке убиты трое", domain.ModerationTragedy},
+		{"Теракты в столице", domain.ModerationTragedy}, // теракт* — начало слова
+		{"Рубль пробил 100", ""},
+	}
+	for _, tt := range tests {
+		v, err := r.Moderate(context.Background(), domain.ModeratePost, tt.text)
+		if err != nil {
+			t.Fatalf("Moderate(%q) error = %v", tt.text, err)
+		}
+		if v.Flagged != (tt.wantCategory != "") || v.Category != tt.wantCategory {
+			t.Errorf("Moderate(%q) = %+v, want category %q", tt.text, v, tt.wantCategory)
+		}
+		if v.Flagged && (v.Rule == "" || v.Source != "rules") {
+			t.Errorf("Moderate(%q) = %+v, want rule and source", tt.text, v)
+		}
+	}
+}
+
+// TestRulesPassFinanceHeadlines — обычные финансовые новости не похожи на чувствительные темы
+func TestRulesPassFinanceHeadlines(t *testing.T) {
+	r, err := NewRules(nil, nil)
+	if err != nil {
+		t.Fatalf("NewRules() error = %v", err)
+	}
+	headlines := []string{
+		"Президент ФРС Пауэлл намекнул на снижение ставки",
+		"Торговая война США и Китая давит на сырьевые рынки",
+		"Военные расходы бюджета выросли, акции ВПК в плюсе",
+		"Катастрофическое падение крипты: биткоин минус 15%",
+		"Убитые акции девелоперов восстановятся к осени",
+		"Идиотская ошибка трейдера стоила фонду $40 млн",
+		"Сбербанк отчитался о рекордной прибыли за квартал",
+		"Гибелью для рубля аналитики называют новые санкции",
+		"Президентский фонд поддержит экспорт",
+		"Дивиденды Газпрома: совет директоров против выплаты",
+	}
+	for _, h := range headlines {
+		if v, _ := r.Moderate(context.Background(), domain.ModeratePost, h); v.Flagged {
+			t.Errorf("Moderate(%q) = %+v, want not flagged", h, v)
+		}
+	}
+}
+
+func TestNewRulesErrors(t *testing.T) {
+	if _, err := NewRules([]string{"sports"}, nil); err == nil {
+		t.Error("NewRules(unknown category) error = nil")
+	}
+	if _, err := NewRules(nil, map[string][]string{"x": {"/(/"}}); err == nil {
+		t.Error("NewRules(bad regexp) error = nil")
+	}
+	// только политика: трагедии не ловим
+	r, _ := NewRules([]string{domain.ModerationPolitics}, nil)
+	if v, _ := r.Moderate(context.Background(), domain.ModeratePost, "Погибли люди"); v.Flagged {
+		t.Errorf("Moderate() = %+v with only politics enabled", v)
+	}
+}
+
+type stubClassifier struct {
+	verdict domain.ModerationVerdict
+	err     error
+	calls   int
+}
+
+func (c *stubClassifier) Moderate(ctx context.Context, target domain.ModerationTarget, text string) (domain.ModerationVerdict, error) {
+	c.calls++
+	return c.verdict, c.err
+}
+
+func TestModeratorClassifier(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	// классификатор выключен в конфиге — не зовём
+	c := &stubClassifier{verdict: domain.ModerationVerdict{Flagged: true, Category: "adult", Source: "llm"}}
+	m, _ := NewFromConfig(config.ModerationConfig{Enabled: true}, c, logger)
+	if v, _ := m.Moderate(context.Background(), domain.ModeratePost, "Обычный пост"); v.Flagged || c.calls != 0 {
+		t.Errorf("llm off: verdict %+v, classifier calls %d", v, c.calls)
+	}
+
+	m, _ = NewFromConfig(config.ModerationConfig{Enabled: true, LLM: true}, c, logger)
+	if v, _ := m.Moderate(context.Background(), domain.ModeratePost, "Обычный пост"); !v.Flagged || v.Source != "llm" {
+		t.Errorf("llm on: verdict %+v, want flagged by llm", v)
+	}
+	// правила срабатывают раньше и без запроса к нейросети
+	c.calls = 0
+	if v, _ := m.Moderate(context.Background(), domain.ModeratePost, "Теракт в центре"); v.Source != "rules" || c.calls != 0 {
+		t.Errorf("rules first: verdict %+v, classifier calls %d", v, c.calls)
+	}
+
+	c.err = errors.New("neuro down")
+	if _, err := m.Moderate(context.Background(), domain.ModeratePost, "Обычный пост"); err == nil {
+		t.Error("fail closed: error = nil")
+	}
+	m, _ = NewFromConfig(config.ModerationConfig{Enabled: true, LLM: true, FailOpen: true}, c, logger)
+	if v, err := m.Moderate(context.Background(), domain.ModeratePost, "Обычный пост"); err != nil || v.Flagged {
+		t.Errorf("fail open: verdict %+v, error %v", v, err)
+	}
+
+	// по умолчанию модерация выключена
+	if m, err := NewFromConfig(config.ModerationConfig{LLM: true}, c, logger); m != nil || err != nil {
+		t.Errorf("disabled by default: moderator %v, error %v", m, err)
+	}
+}
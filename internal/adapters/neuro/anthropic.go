@@ -0,0 +1,75 @@
+package neuro
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/larriantoniy/tg_user_bot/internal/config"
+	"github.com/larriantoniy/tg_user_bot/internal/domain"
+)
+
+// anthropicVersion — версия messages API, которую мы поддерживаем
+const anthropicVersion = "2023-06-01"
+
+// anthropicProvider — Anthropic-style messages API (POST /v1/messages)
+type anthropicProvider struct {
+	cfg  config.ProviderConfig
+	http *httpJSON
+}
+
+func newAnthropicProvider(pc config.ProviderConfig, h *httpJSON) (provider, error) {
+	if pc.Model == "" {
+		return nil, fmt.Errorf("model is required")
+	}
+	return &anthropicProvider{cfg: pc, http: h}, nil
+}
+
+func (p *anthropicProvider) Name() string           { return p.cfg.Name }
+func (p *anthropicProvider) Model() string          { return p.cfg.Model }
+func (p *anthropicProvider) Timeout() time.Duration { return p.cfg.Timeout }
+
+func (p *anthropicProvider) Complete(ctx context.Context, req chatRequest) (completion, error) {
+	content := []domain.AnthropicContent{{Type: "text", Text: req.Post}}
+	for _, url := range req.ImageURLs {
+		src := &domain.AnthropicImageSource{Type: "url", URL: url}
+		if mime, data, ok := splitDataURL(url); ok {
+			src = &domain.AnthropicImageSource{Type: "base64", MediaType: mime, Data: data}
+		}
+		content = append(content, domain.AnthropicContent{Type: "image", Source: src})
+	}
+
+	body := domain.AnthropicMessagesBody{
+		Model:       p.cfg.Model,
+		System:      req.System,
+		MaxTokens:   p.cfg.MaxTokens,
+		Temperature: *p.cfg.Temperature,
+		Messages:    []domain.AnthropicMessage{{Role: domain.RoleUser, Content: content}},
+	}
+
+	var resp domain.AnthropicResponse
+	headers := map[string]string{
+		"x-api-key":         p.cfg.Token,
+		"anthropic-version": anthropicVersion,
+	}
+	if err := p.http.post(ctx, p.cfg.Addr, headers, body, &resp); err != nil {
+		return completion{}, err
+	}
+
+	usage := domain.Usage{
+		PromptTokens:     resp.Usage.InputTokens,
+		CompletionTokens: resp.Usage.OutputTokens,
+		TotalTokens:      resp.Usage.InputTokens + resp.Usage.OutputTokens,
+	}
+	var text strings.Builder
+	for _, c := range resp.Content {
+		if c.Type == "text" {
+			text.WriteString(c.Text)
+		}
+	}
+	if text.Len() == 0 {
+		return completion{Usage: usage}, fmt.Errorf("empty content: %w", errEmptyResponse)
+	}
+	return completion{Text: text.String(), Usage: usage}, nil
+}
@@ -0,0 +1,201 @@
+package neuro
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/larriantoniy/tg_user_bot/internal/config"
+	"github.com/larriantoniy/tg_user_bot/internal/domain"
+	"github.com/larriantoniy/tg_user_bot/internal/ports"
+)
+
+// Breakers — circuit breaker'ы провайдеров, общие для всех сессий процесса:
+// если провайдер лежит, об этом узнаёт каждая сессия, а не только та, что упёрлась первой.
+type Breakers struct {
+	cfg     config.BreakerConfig
+	logger  *slog.Logger
+	metrics ports.Metrics
+
+	mu       sync.Mutex
+	breakers map[string]*breaker // по имени провайдера
+}
+
+const (
+	defaultBreakerFailures = 5
+	defaultBreakerOpenFor  = 30 * time.Second
+)
+
+func NewBreakers(cfg config.BreakerConfig, logger *slog.Logger, metrics ports.Metrics) *Breakers {
+	if cfg.Failures == 0 {
+		cfg.Failures = defaultBreakerFailures
+	}
+	if cfg.OpenFor <= 0 {
+		cfg.OpenFor = defaultBreakerOpenFor
+	}
+	if cfg.HalfOpenProbes <= 0 {
+		cfg.HalfOpenProbes = 1
+	}
+	return &Breakers{
+		cfg:      cfg,
+		logger:   logger,
+		metrics:  metrics,
+		breakers: make(map[string]*breaker),
+	}
+}
+
+// Breakers возвращает состояние всех известных провайдеров (для админки)
+func (bs *Breakers) Breakers() []domain.BreakerStatus {
+	bs.mu.Lock()
+	list := make([]*breaker, 0, len(bs.breakers))
+	for _, b := range bs.breakers {
+		list = append(list, b)
+	}
+	bs.mu.Unlock()
+
+	out := make([]domain.BreakerStatus, 0, len(list))
+	for _, b := range list {
+		out = append(out, b.status())
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Provider < out[j].Provider })
+	return out
+}
+
+// get отдаёт breaker провайдера; nil — breaker выключен в конфиге
+func (bs *Breakers) get(provider string) *breaker {
+	if bs == nil || bs.cfg.Failures < 0 {
+		return nil
+	}
+	bs.mu.Lock()
+	defer bs.mu.Unlock()
+	b, ok := bs.breakers[provider]
+	if !ok {
+		b = &breaker{
+			provider: provider,
+			cfg:      bs.cfg,
+			logger:   bs.logger,
+			metrics:  bs.metrics,
+			state:    domain.BreakerClosed,
+		}
+		bs.breakers[provider] = b
+		bs.metrics.NeuroBreakerState(provider, domain.BreakerClosed)
+	}
+	return b
+}
+
+// breaker одного провайдера.
+// closed → (Failures ошибок подряд) → open → (OpenFor) → half_open → удача: closed / ошибка: open.
+type breaker struct {
+	provider string
+	cfg      config.BreakerConfig
+	logger   *slog.Logger
+	metrics  ports.Metrics
+
+	mu       sync.Mutex
+	state    domain.BreakerState
+	failures int
+	openedAt time.Time
+	probes   int // пробных запросов в полёте в half_open
+}
+
+// allow решает, можно ли сейчас идти к провайдеру; ошибка — ports.ErrNeuroCircuitOpen
+func (b *breaker) allow() error {
+	if b == nil {
+		return nil
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.state == domain.BreakerOpen {
+		wait := b.cfg.OpenFor - time.Since(b.openedAt)
+		if wait > 0 {
+			return &ports.NeuroError{
+				RetryAfter: wait,
+				Kind:       ports.ErrNeuroCircuitOpen,
+				Err:        fmt.Errorf("circuit open, next probe in %s", wait.Round(time.Second)),
+			}
+		}
+		b.setState(domain.BreakerHalfOpen)
+		b.probes = 0
+	}
+	if b.state == domain.BreakerHalfOpen {
+		if b.probes >= b.cfg.HalfOpenProbes {
+			return &ports.NeuroError{
+				Kind: ports.ErrNeuroCircuitOpen,
+				Err:  errors.New("circuit half-open, probe in flight"),
+			}
+		}
+		b.probes++
+	}
+	return nil
+}
+
+// record учитывает исход запроса, пропущенного allow
+func (b *breaker) record(err error) {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.state == domain.BreakerHalfOpen && b.probes > 0 {
+		b.probes--
+	}
+	if errors.Is(err, context.Canceled) {
+		// остановка сессии — не повод считать провайдера лежащим
+		return
+	}
+	// провайдер ответил (пусть даже 4xx или «нет квоты») — он жив
+	if !errors.Is(err, ports.ErrNeuroTransient) {
+		b.failures = 0
+		if b.state != domain.BreakerClosed {
+			b.setState(domain.BreakerClosed)
+			b.logger.Info("Neuro circuit closed", "provider", b.provider)
+		}
+		return
+	}
+
+	b.failures++
+	switch {
+	case b.state == domain.BreakerHalfOpen:
+		b.open(err)
+	case b.state == domain.BreakerClosed && b.failures >= b.cfg.Failures:
+		b.open(err)
+	}
+}
+
+func (b *breaker) open(err error) {
+	b.openedAt = time.Now()
+	b.setState(domain.BreakerOpen)
+	b.logger.Warn("Neuro circuit opened",
+		"provider", b.provider,
+		"failures", b.failures,
+		"open_for", b.cfg.OpenFor,
+		"error", err,
+	)
+}
+
+func (b *breaker) setState(state domain.BreakerState) {
+	b.state = state
+	b.metrics.NeuroBreakerState(b.provider, state)
+}
+
+func (b *breaker) status() domain.BreakerStatus {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	st := domain.BreakerStatus{
+		Provider: b.provider,
+		State:    b.state,
+		Failures: b.failures,
+	}
+	if b.state != domain.BreakerClosed {
+		opened := b.openedAt
+		retry := opened.Add(b.cfg.OpenFor)
+		st.OpenedAt, st.RetryAt = &opened, &retry
+	}
+	return st
+}
@@ -0,0 +1,147 @@
+package neuro
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/larriantoniy/tg_user_bot/internal/adapters/metrics"
+	"github.com/larriantoniy/tg_user_bot/internal/adapters/neurofake"
+	"github.com/larriantoniy/tg_user_bot/internal/config"
+	"github.com/larriantoniy/tg_user_bot/internal/domain"
+	"github.com/larriantoniy/tg_user_bot/internal/ports"
+)
+
+// newBreakerNeuro — Neuro сессии session с общими breakers
+func newBreakerNeuro(t *testing.T, session string, breakers *Breakers, providers ...config.ProviderConfig) *Neuro {
+	t.Helper()
+	n, err := NewNeuro(&config.AppConfig{Providers: providers},
+		slog.New(slog.NewTextHandler(io.Discard, nil)), session, nil, metrics.Nop{}, breakers, nil)
+	if err != nil {
+		t.Fatalf("NewNeuro() error = %v", err)
+	}
+	n.retry = testRetryPolicy
+	return n
+}
+
+func testBreakers() *Breakers {
+	return NewBreakers(config.BreakerConfig{Failures: 3, OpenFor: 50 * time.Millisecond},
+		slog.New(slog.NewTextHandler(io.Discard, nil)), metrics.Nop{})
+}
+
+func breakerState(t *testing.T, bs *Breakers, provider string) domain.BreakerState {
+	t.Helper()
+	for _, st := range bs.Breakers() {
+		if st.Provider == provider {
+			return st.State
+		}
+	}
+	t.Fatalf("no breaker for provider %q", provider)
+	return ""
+}
+
+func TestBreakerSharedAcrossSessions(t *testing.T) {
+	srv := neurofake.New()
+	defer srv.Close()
+	srv.SetDefault(neurofake.ReplyStatus(http.StatusInternalServerError))
+
+	bs := testBreakers()
+	pc := config.ProviderConfig{Name: "main", Type: ProviderOpenAI, Addr: srv.URL()}
+	alpha := newBreakerNeuro(t, "alpha", bs, pc)
+	beta := newBreakerNeuro(t, "beta", bs, pc)
+	ctx := context.Background()
+
+	// три неудачные попытки alpha открывают breaker
+	if _, err := alpha.GetComment(ctx, &domain.Message{Text: "Пост"}); !errors.Is(err, ports.ErrNeuroTransient) {
+		t.Fatalf("alpha GetComment() error = %v, want transient", err)
+	}
+	if st := breakerState(t, bs, "main"); st != domain.BreakerOpen {
+		t.Fatalf("breaker state = %s, want open", st)
+	}
+
+	// beta даже не ходит к провайдеру
+	_, err := beta.GetComment(ctx, &domain.Message{Text: "Пост"})
+	if !errors.Is(err, ports.ErrNeuroCircuitOpen) {
+		t.Errorf("beta GetComment() error = %v, want ErrNeuroCircuitOpen", err)
+	}
+	if got := len(srv.Requests()); got != 3 {
+		t.Errorf("requests = %d, want 3 (no requests while open)", got)
+	}
+
+	// после OpenFor пробный запрос удался — breaker закрыт
+	time.Sleep(60 * time.Millisecond)
+	srv.Enqueue(neurofake.ReplyText("Снова работаем 👍"))
+	if got, err := beta.GetComment(ctx, &domain.Message{Text: "Пост"}); err != nil || got != "Снова работаем 👍" {
+		t.Fatalf("probe GetComment() = %q, %v", got, err)
+	}
+	if st := breakerState(t, bs, "main"); st != domain.BreakerClosed {
+		t.Errorf("breaker state after probe = %s, want closed", st)
+	}
+}
+
+func TestBreakerFailedProbeReopens(t *testing.T) {
+	srv := neurofake.New()
+	defer srv.Close()
+	srv.SetDefault(neurofake.ReplyStatus(http.StatusBadGateway))
+
+	bs := testBreakers()
+	n := newBreakerNeuro(t, "alpha", bs, config.ProviderConfig{Name: "main", Type: ProviderOpenAI, Addr: srv.URL()})
+	ctx := context.Background()
+
+	_, _ = n.GetComment(ctx, &domain.Message{Text: "Пост"})
+	time.Sleep(60 * time.Millisecond)
+
+	_, err := n.GetComment(ctx, &domain.Message{Text: "Пост"})
+	if err == nil {
+		t.Fatal("probe GetComment() error = nil, want error")
+	}
+	// одна пробная попытка, дальше breaker снова открыт и повторов нет
+	if got := len(srv.Requests()); got != 4 {
+		t.Errorf("requests = %d, want 3 + 1 probe", got)
+	}
+	if st := breakerState(t, bs, "main"); st != domain.BreakerOpen {
+		t.Errorf("breaker state after failed probe = %s, want open", st)
+	}
+}
+
+func TestBreakerOpenProviderFallsThrough(t *testing.T) {
+	primary, secondary := neurofake.New(), neurofake.New()
+	defer primary.Close()
+	defer secondary.Close()
+	primary.SetDefault(neurofake.ReplyStatus(http.StatusServiceUnavailable))
+	secondary.SetDefault(neurofake.ReplyText("Запасной 👍"))
+
+	bs := testBreakers()
+	n := newBreakerNeuro(t, "alpha", bs,
+		config.ProviderConfig{Name: "primary", Type: ProviderOpenAI, Addr: primary.URL()},
+		config.ProviderConfig{Name: "secondary", Type: ProviderOpenAI, Addr: secondary.URL()},
+	)
+	for i := 0; i < 3; i++ {
+		if got, err := n.GetComment(context.Background(), &domain.Message{Text: "Пост"}); err != nil || got != "Запасной 👍" {
+			t.Fatalf("GetComment() #%d = %q, %v", i, got, err)
+		}
+	}
+	// primary открылся после первых трёх попыток и больше не получал запросов
+	if got := len(primary.Requests()); got != 3 {
+		t.Errorf("primary requests = %d, want 3", got)
+	}
+}
+
+func TestBreakerIgnoresClientErrors(t *testing.T) {
+	srv := neurofake.New()
+	defer srv.Close()
+	srv.SetDefault(neurofake.ReplyStatus(http.StatusBadRequest))
+
+	bs := testBreakers()
+	n := newBreakerNeuro(t, "alpha", bs, config.ProviderConfig{Name: "main", Type: ProviderOpenAI, Addr: srv.URL()})
+	for i := 0; i < 5; i++ {
+		_, _ = n.GetComment(context.Background(), &domain.Message{Text: "Пост"})
+	}
+	if st := breakerState(t, bs, "main"); st != domain.BreakerClosed {
+		t.Errorf("breaker state = %s, want closed: 4xx means the provider is up", st)
+	}
+}
@@ -0,0 +1,59 @@
+package neuro
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/larriantoniy/tg_user_bot/internal/adapters/neurofake"
+	"github.com/larriantoniy/tg_user_bot/internal/config"
+	"github.com/larriantoniy/tg_user_bot/internal/domain"
+)
+
+func TestNeuroModerate(t *testing.T) {
+	tests := []struct {
+		answer       string
+		wantCategory string
+		wantErr      bool
+	}{
+		{answer: "SAFE"},
+		{answer: "safe."},
+		{answer: "Tragedy", wantCategory: domain.ModerationTragedy},
+		{answer: "**scam**", wantCategory: "scam"},
+		{answer: "Не могу определить", wantErr: true},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.answer, func(t *testing.T) {
+			srv := neurofake.New()
+			defer srv.Close()
+			srv.SetDefault(neurofake.ReplyText(tt.answer))
+
+			n := newTestNeuro(t, srv)
+			n.moderation = moderationCategories(config.ModerationConfig{Rules: map[string][]string{"scam": {"пирамид"}}})
+			v, err := n.Moderate(context.Background(), domain.ModeratePost, "Текст поста")
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Moderate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if v.Category != tt.wantCategory || v.Flagged != (tt.wantCategory != "") {
+				t.Errorf("Moderate() = %+v, want category %q", v, tt.wantCategory)
+			}
+
+			reqs := srv.Requests()
+			if len(reqs) != 1 {
+				t.Fatalf("requests = %d, want 1", len(reqs))
+			}
+			var sent strings.Builder
+			for _, m := range reqs[0].Body.Messages {
+				for _, c := range m.Content {
+					sent.WriteString(c.Text + "\n")
+				}
+			}
+			for _, want := range []string{"tragedy", "scam", "SAFE", "<post>\nТекст поста\n</post>"} {
+				if !strings.Contains(sent.String(), want) {
+					t.Errorf("request does not contain %q: %s", want, sent.String())
+				}
+			}
+		})
+	}
+}
@@ -1,132 +1,240 @@
 package neuro
 
 import (
-	"bytes"
 	"context"
-	"encoding/json"
+	"encoding/base64"
+	"errors"
 	"fmt"
-	"io"
 	"log/slog"
 	"net/http"
+	"os"
+	"strings"
 	"time"
 
 	"github.com/larriantoniy/tg_user_bot/internal/config"
 	"github.com/larriantoniy/tg_user_bot/internal/domain"
+	"github.com/larriantoniy/tg_user_bot/internal/ports"
 )
 
-const systemPrompt = "Экспертный комментарий к посту в Telegram. Русский язык. До 12 слов. Доброжелательно и уверенно. Без вопросов и выдуманных фактов. Ровно одно эмодзи.\nТекст поста:\n"
-
 type Neuro struct {
 	client  *http.Client
 	ctx     *context.Context
 	logger  *slog.Logger 
-	baseURL string // https://openrouter.ai/api/v1
-	apiKey  string // TOKEN neuro
-	// заготовленный http.Request
+	session string // метка сессии для метрик
+	metrics ports.Metrics
+	maxImageBytes int64 // картинки крупнее не отправляем
+	prompts *Prompts
+	providers  []provider    // цепочка fallback: следующий пробуем, если предыдущий не ответил
+	retry      retryPolicy   // повторы у одного провайдера
+	breakers   *Breakers     // общие для всех сессий
+	usage      *Accounting   // общий учёт расходов; nil — не считаем
+	moderation []string      // категории для Moderate
+	threadTokens int         // бюджет на комментарии обсуждения в промпте; 0 — не прикладывать
 }
 
-func NewNeuro(cfg *config.AppConfig, logger *slog.Logger) (*Neuro, error) {
-	if cfg.NeuroToken == "" {
+// NewNeuro собирает клиента для сессии. sc задаёт выбор шаблона промпта; nil — шаблоны по умолчанию.
+// breakers и usage общие для процесса; nil breakers — у клиента будут свои, nil usage — без учёта расходов.
+func NewNeuro(cfg *config.AppConfig, logger *slog.Logger, session string, sc *ports.SessionConfig, metrics ports.Metrics, breakers *Breakers, usage *Accounting) (*Neuro, error) {
+	if len(cfg.Providers) == 0 && cfg.NeuroToken == "" {
 		logger.Warn("Neuro token is empty; requests will fail with 401")
 	}
+	prompts, err := NewPrompts(cfg.Prompts, sc)
+	if err != nil {
+		return nil, err
+	}
+	if breakers == nil {
+		breakers = NewBreakers(cfg.NeuroBreaker, logger, metrics)
+	}
+	client := &http.Client{}
+	providers, err := newProviders(cfg, &httpJSON{client: client, logger: logger})
+	if err != nil {
+		return nil, err
+	}
 	// 3) Собираем объект Neuro
 	return &Neuro{
-		client:  &http.Client{},
+		client:  client,
 		logger:  logger,
-		baseURL: cfg.NeuroAddr,
-		apiKey:  cfg.NeuroToken,
+		session: session,
+		metrics: metrics,
+		maxImageBytes: cfg.Vision.MaxImageBytes,
+		prompts: prompts,
+		providers:  providers,
+		retry:      defaultRetryPolicy,
+		breakers:   breakers,
+		usage:      usage,
+		moderation: moderationCategories(cfg.Moderation),
+		threadTokens: cfg.Thread.MaxTokens,
 	}, nil
 }
 
-func retry(attempts int, sleep time.Duration, fn func() error) error {
-	var err error
-	for i := 0; i < attempts; i++ {
-		if err = fn(); err == nil {
-			return nil
+// GetComment спрашивает провайдеров по очереди, пока кто-то не ответит
+func (n *Neuro) GetComment(ctx context.Context, msg *domain.Message) (string, error) {
+	prompt, err := n.prompts.Render(msg)
+	if err != nil {
+		return "", err
+	}
+	// пост — данные в user-сообщении, инструкции — только в system
+	post := wrapPost(msg.Text)
+	if thread := wrapReplies("comments", msg.Thread, n.threadTokens); thread != "" {
+		prompt += "\n\n" + threadGuard
+		post += "\n\n" + thread
+	}
+	if msg.IsReply {
+		if chain := wrapReplies("conversation", msg.ReplyChain, maxConversationTokens); chain != "" {
+			prompt += "\n\n" + conversationGuard
+			post += "\n\n" + chain
 		}
-		time.Sleep(sleep)
 	}
-	return err
+	return n.ask(ctx, chatRequest{
+		System:    prompt,
+		Post:      post,
+		ImageURLs: n.imageURLs(msg),
+	}, usageChannel(msg))
 }
 
-func (n *Neuro) GetComment(ctx context.Context, msg *domain.Message) (string, error) {
-	// Ответ нейросети
-	var nr domain.NeuroResponse
+// ask проходит цепочку провайдеров; channel — метка канала в учёте расходов
+func (n *Neuro) ask(ctx context.Context, req chatRequest, channel string) (string, error) {
+	// лимит расходов исчерпан — к провайдерам не ходим вовсе
+	if err := n.usage.check(ctx, n.session); err != nil {
+		return "", err
+	}
 
-	err := retry(3, time.Second, func() error {
-		content := []domain.MessageContent{
-			{
-				Type: "text", // "text" для промпта
-				Text: systemPrompt + msg.Text,
-			}, 
+	var errs []error
+	quota := 0
+	for i, p := range n.providers {
+		res, err := n.complete(ctx, p, req)
+		if err == nil || res.Usage != (domain.Usage{}) {
+			// пустой ответ тоже тарифицируется: токены промпта провайдер уже посчитал
+			n.usage.record(ctx, n.session, channel, p.Name(), p.Model(), res.Usage)
 		}
-		if msg.PhotoFile != "" { 
-			content = append(content, domain.MessageContent{
-				Type: "image_url",
-				ImageUrl: &domain.ImageUrl{
-					Url: msg.PhotoFile,
-				},
-			})
+		if err == nil {
+			n.logger.Info("After neuro processing",
+				"provider", p.Name(),
+				"model", p.Model(),
+				"prompt_tokens", res.Usage.PromptTokens,
+				"completion_tokens", res.Usage.CompletionTokens,
+				"result", res.Text,
+			)
+			return res.Text, nil
 		}
-
-		body := domain.DefaultNeuroBody{
-			Model:            domain.MistralModel, // например "mistral-small-2506"
-			Temperature:      0.4,
-			TopP:             0.9,
-			PresencePenalty:  0.2,
-			FrequencyPenalty: 0.3,
-			MaxTokens:        120,
-			Messages: []domain.NeuroMessage{
-				{
-					Role: domain.RoleUser,
-					Content: content,
-				},
-			},
+		if errors.Is(err, ports.ErrNeuroQuota) {
+			quota++
 		}
-
-		bodyBytes, err := json.Marshal(body)
-		if err != nil {
-			return fmt.Errorf("marshal body: %w", err)
+		errs = append(errs, fmt.Errorf("%s: request failed: %w", p.Name(), err))
+		if ctx.Err() != nil {
+			break
 		}
-
-		bodyBytesReader := bytes.NewReader(bodyBytes)
-		req, err := http.NewRequestWithContext(ctx, http.MethodPost, n.baseURL, bodyBytesReader)
-		if err != nil {
-			return fmt.Errorf("new request: %w", err)
+		if i < len(n.providers)-1 {
+			n.logger.Warn("Neuro provider failed, trying next",
+				"provider", p.Name(),
+				"next", n.providers[i+1].Name(),
+				"error", err,
+			)
 		}
-		req.Header.Set("Content-Type", "application/json")
-		req.Header.Set("Authorization", "Bearer "+n.apiKey)
-
-		n.logger.Info("Neuro request", "url", req.URL.String())
+	}
+	if quota > 0 && quota < len(errs) {
+		// квота кончилась не у всех: для Sender это временный сбой, а не повод ждать квоту
+		for i, err := range errs {
+			if errors.Is(err, ports.ErrNeuroQuota) {
+				errs[i] = errors.New(err.Error())
+			}
+		}
+	}
+	return "", errors.Join(errs...)
+}
 
-		resp, err := n.client.Do(req)
+// complete — запрос к одному провайдеру с повторами; таймаут у каждой попытки свой.
+// Usage — сумма по всем попыткам, в том числе неудачным.
+func (n *Neuro) complete(ctx context.Context, p provider, req chatRequest) (completion, error) {
+	var out completion
+	b := n.breakers.get(p.Name())
+	err := n.retry.do(ctx, func() error {
+		// открытый breaker — не временная ошибка, retryPolicy дальше не пойдёт
+		if err := b.allow(); err != nil {
+			return err
+		}
+		attemptCtx, cancel := context.WithTimeout(ctx, p.Timeout())
+		defer cancel()
+
+		started := time.Now()
+		res, err := p.Complete(attemptCtx, req)
+		b.record(err)
+		n.metrics.NeuroCall(n.session, p.Model(), time.Since(started), res.Usage, err)
+		usage := addUsage(out.Usage, res.Usage)
 		if err != nil {
-			n.logger.Error("HTTP request to neuro failed", "err", err)
+			out.Usage = usage
 			return err
 		}
-		defer resp.Body.Close()
+		out = res
+		out.Usage = usage
+		return nil
+	})
+	return out, err
+}
 
-		if resp.StatusCode != http.StatusOK {
-			data, _ := io.ReadAll(resp.Body)
-			n.logger.Error("Neuro API returned error",
-				"status", resp.StatusCode,
-				"body", string(data),
-			)
-			return fmt.Errorf("status %d: %s", resp.StatusCode, string(data))
-		}
+func addUsage(a, b domain.Usage) domain.Usage {
+	return domain.Usage{
+		PromptTokens:     a.PromptTokens + b.PromptTokens,
+		CompletionTokens: a.CompletionTokens + b.CompletionTokens,
+		TotalTokens:      a.TotalTokens + b.TotalTokens,
+	}
+}
 
-		return json.NewDecoder(resp.Body).Decode(&nr)
-	})
+// maxPostImages — сколько картинок альбома отправлять: каждая стоит заметных токенов
+const maxPostImages = 4
 
-	if err != nil {
-		return "", fmt.Errorf("request failed: %w", err)
+// imageURLs — картинки поста для vision-модели: весь альбом, но не больше maxPostImages
+func (n *Neuro) imageURLs(msg *domain.Message) []string {
+	photos := msg.PhotoFiles
+	if len(photos) == 0 && msg.PhotoFile != "" {
+		photos = []string{msg.PhotoFile}
 	}
+	var out []string
+	for _, photo := range photos {
+		if len(out) == maxPostImages {
+			break
+		}
+		if url := n.imageURL(photo); url != "" {
+			out = append(out, url)
+		}
+	}
+	return out
+}
 
-	if len(nr.Choices) == 0 {
-		return "", fmt.Errorf("empty choices")
+// imageURL превращает PhotoFile в то, что понимает vision-модель: http(s) и data URL
+// идут как есть, локальный файл (скачанный TDLib) кодируется в base64 data URL.
+// "" — картинку не отправляем (нет, не читается, слишком большая или не картинка).
+func (n *Neuro) imageURL(photo string) string {
+	if photo == "" {
+		return ""
+	}
+	if strings.HasPrefix(photo, "http://") || strings.HasPrefix(photo, "https://") || strings.HasPrefix(photo, "data:") {
+		return photo
 	}
 
-	n.logger.Info("After neuro processing", "result", nr.Choices[0].Message.Content)
+	info, err := os.Stat(photo)
+	if err != nil {
+		n.logger.Warn("Post image is not readable, sending text only", "path", photo, "error", err)
+		return ""
+	}
+	if n.maxImageBytes > 0 && info.Size() > n.maxImageBytes {
+		n.logger.Warn("Post image is too large, sending text only",
+			"path", photo,
+			"size", info.Size(),
+			"max_size", n.maxImageBytes,
+		)
+		return ""
+	}
 
-	return nr.Choices[0].Message.Content, nil
+	data, err := os.ReadFile(photo)
+	if err != nil {
+		n.logger.Warn("Post image is not readable, sending text only", "path", photo, "error", err)
+		return ""
+	}
+	mime := http.DetectContentType(data)
+	if !strings.HasPrefix(mime, "image/") {
+		n.logger.Warn("Post media is not an image, sending text only", "path", photo, "mime", mime)
+		return ""
+	}
+	return "data:" + mime + ";base64," + base64.StdEncoding.EncodeToString(data)
 }
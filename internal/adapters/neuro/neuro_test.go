@@ -0,0 +1,333 @@
+package neuro
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/larriantoniy/tg_user_bot/internal/adapters/metrics"
+	"github.com/larriantoniy/tg_user_bot/internal/adapters/neurofake"
+	"github.com/larriantoniy/tg_user_bot/internal/config"
+	"github.com/larriantoniy/tg_user_bot/internal/domain"
+)
+
+func newTestNeuro(t *testing.T, srv *neurofake.Server) *Neuro {
+	t.Helper()
+	n, err := NewNeuro(&config.AppConfig{
+		NeuroAddr:  srv.URL(),
+		NeuroToken: "test-token",
+	}, slog.New(slog.NewTextHandler(io.Discard, nil)), "test-session", nil, metrics.Nop{}, nil, nil)
+	if err != nil {
+		t.Fatalf("NewNeuro() error = %v", err)
+	}
+	n.retry = testRetryPolicy
+	return n
+}
+
+// testRetryPolicy — те же три попытки, но без секундных пауз
+var testRetryPolicy = retryPolicy{Attempts: 3, BaseDelay: time.Millisecond, MaxDelay: 10 * time.Millisecond}
+
+func expectedBody(content ...domain.MessageContent) domain.DefaultNeuroBody {
+	return domain.DefaultNeuroBody{
+		Model:            domain.MistralModel,
+		Temperature:      0.4,
+		TopP:             0.9,
+		PresencePenalty:  0.2,
+		FrequencyPenalty: 0.3,
+		MaxTokens:        120,
+		Messages: []domain.NeuroMessage{
+			{Role: domain.RoleSystem, Content: []domain.MessageContent{{Type: "text", Text: systemPrompt + "\n\n" + postGuard}}},
+			{Role: domain.RoleUser, Content: content},
+		},
+	}
+}
+
+func TestGetCommentTextOnly(t *testing.T) {
+	srv := neurofake.New()
+	defer srv.Close()
+	srv.Enqueue(neurofake.ReplyFixture(neurofake.FixtureComment))
+
+	n := newTestNeuro(t, srv)
+	got, err := n.GetComment(context.Background(), &domain.Message{Text: "Рынок растёт третий день"})
+	if err != nil {
+		t.Fatalf("GetComment() error = %v", err)
+	}
+
+	want, _ := neurofake.FixtureResponse(neurofake.FixtureComment)
+	if got != want.Choices[0].Message.Content {
+		t.Errorf("GetComment() = %q, want %q", got, want.Choices[0].Message.Content)
+	}
+
+	reqs := srv.Requests()
+	if len(reqs) != 1 {
+		t.Fatalf("requests = %d, want 1", len(reqs))
+	}
+	req := reqs[0]
+	if req.Method != http.MethodPost {
+		t.Errorf("method = %s, want POST", req.Method)
+	}
+	if auth := req.Header.Get("Authorization"); auth != "Bearer test-token" {
+		t.Errorf("Authorization = %q, want %q", auth, "Bearer test-token")
+	}
+	if ct := req.Header.Get("Content-Type"); ct != "application/json" {
+		t.Errorf("Content-Type = %q, want application/json", ct)
+	}
+
+	wantBody := expectedBody(domain.MessageContent{
+		Type: "text",
+		Text: "<post>\nРынок растёт третий день\n</post>",
+	})
+	if !reflect.DeepEqual(req.Body, wantBody) {
+		t.Errorf("request body = %+v, want %+v", req.Body, wantBody)
+	}
+}
+
+func TestGetCommentWithImage(t *testing.T) {
+	srv := neurofake.New()
+	defer srv.Close()
+	srv.Enqueue(neurofake.ReplyFixture(neurofake.FixtureCommentImage))
+
+	n := newTestNeuro(t, srv)
+	msg := &domain.Message{Text: "Смотрите график", PhotoFile: "https://example.com/chart.png"}
+	if _, err := n.GetComment(context.Background(), msg); err != nil {
+		t.Fatalf("GetComment() error = %v", err)
+	}
+
+	wantBody := expectedBody(
+		domain.MessageContent{Type: "text", Text: "<post>\nСмотрите график\n</post>"},
+		domain.MessageContent{Type: "image_url", ImageUrl: &domain.ImageUrl{Url: "https://example.com/chart.png"}},
+	)
+	reqs := srv.Requests()
+	if len(reqs) != 1 {
+		t.Fatalf("requests = %d, want 1", len(reqs))
+	}
+	if !reflect.DeepEqual(reqs[0].Body, wantBody) {
+		t.Errorf("request body = %+v, want %+v", reqs[0].Body, wantBody)
+	}
+}
+
+func TestGetCommentWithAlbum(t *testing.T) {
+	srv := neurofake.New()
+	defer srv.Close()
+	srv.Enqueue(neurofake.ReplyFixture(neurofake.FixtureCommentImage))
+
+	n := newTestNeuro(t, srv)
+	msg := &domain.Message{Text: "Фото с выставки", PhotoFile: "https://example.com/1.png"}
+	for i := 1; i <= maxPostImages+2; i++ {
+		msg.PhotoFiles = append(msg.PhotoFiles, fmt.Sprintf("https://example.com/%d.png", i))
+	}
+	if _, err := n.GetComment(context.Background(), msg); err != nil {
+		t.Fatalf("GetComment() error = %v", err)
+	}
+
+	want := []domain.MessageContent{{Type: "text", Text: "<post>\nФото с выставки\n</post>"}}
+	for i := 1; i <= maxPostImages; i++ {
+		want = append(want, domain.MessageContent{Type: "image_url", ImageUrl: &domain.ImageUrl{Url: fmt.Sprintf("https://example.com/%d.png", i)}})
+	}
+	reqs := srv.Requests()
+	if len(reqs) != 1 {
+		t.Fatalf("requests = %d, want 1", len(reqs))
+	}
+	if wantBody := expectedBody(want...); !reflect.DeepEqual(reqs[0].Body, wantBody) {
+		t.Errorf("request body = %+v, want %+v", reqs[0].Body, wantBody)
+	}
+}
+
+// pngHeader — начало PNG-файла, по нему http.DetectContentType узнаёт image/png
+var pngHeader = []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR")
+
+func TestGetCommentWithLocalImage(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "photo.png")
+	if err := os.WriteFile(path, pngHeader, 0o644); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		maxBytes int64
+		want     []domain.MessageContent
+	}{
+		{
+			name: "sent as data URL",
+			want: []domain.MessageContent{
+				{Type: "text", Text: "<post>\nСмотрите график\n</post>"},
+				{Type: "image_url", ImageUrl: &domain.ImageUrl{Url: "data:image/png;base64," + base64.StdEncoding.EncodeToString(pngHeader)}},
+			},
+		},
+		{
+			name:     "too large is dropped",
+			maxBytes: 4,
+			want: []domain.MessageContent{
+				{Type: "text", Text: "<post>\nСмотрите график\n</post>"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			srv := neurofake.New()
+			defer srv.Close()
+			srv.Enqueue(neurofake.ReplyFixture(neurofake.FixtureCommentImage))
+
+			n := newTestNeuro(t, srv)
+			n.maxImageBytes = tt.maxBytes
+			if _, err := n.GetComment(context.Background(), &domain.Message{Text: "Смотрите график", PhotoFile: path}); err != nil {
+				t.Fatalf("GetComment() error = %v", err)
+			}
+
+			reqs := srv.Requests()
+			if len(reqs) != 1 {
+				t.Fatalf("requests = %d, want 1", len(reqs))
+			}
+			if wantBody := expectedBody(tt.want...); !reflect.DeepEqual(reqs[0].Body, wantBody) {
+				t.Errorf("request body = %+v, want %+v", reqs[0].Body, wantBody)
+			}
+		})
+	}
+}
+
+func TestGetCommentWithThread(t *testing.T) {
+	srv := neurofake.New()
+	defer srv.Close()
+	srv.Enqueue(neurofake.ReplyFixture(neurofake.FixtureComment))
+
+	n := newTestNeuro(t, srv)
+	n.threadTokens = 300
+	msg := &domain.Message{
+		Text:   "Рынок растёт третий день",
+		Thread: []domain.ThreadReply{{Text: "Наконец-то рост"}, {Text: "Отличный разбор 👍", Own: true}},
+	}
+	if _, err := n.GetComment(context.Background(), msg); err != nil {
+		t.Fatalf("GetComment() error = %v", err)
+	}
+
+	reqs := srv.Requests()
+	if len(reqs) != 1 {
+		t.Fatalf("requests = %d, want 1", len(reqs))
+	}
+	wantBody := expectedBody(domain.MessageContent{
+		Type: "text",
+		Text: "<post>\nРынок растёт третий день\n</post>\n\n<comments>\n- Наконец-то рост\n- (наш) Отличный разбор 👍\n</comments>",
+	})
+	wantBody.Messages[0].Content[0].Text += "\n\n" + threadGuard
+	if !reflect.DeepEqual(reqs[0].Body, wantBody) {
+		t.Errorf("request body = %+v, want %+v", reqs[0].Body, wantBody)
+	}
+}
+
+func TestGetCommentReplyToReply(t *testing.T) {
+	srv := neurofake.New()
+	defer srv.Close()
+	srv.Enqueue(neurofake.ReplyFixture(neurofake.FixtureComment))
+
+	n := newTestNeuro(t, srv)
+	msg := &domain.Message{
+		Text:    "Рынок растёт третий день",
+		IsReply: true,
+		ReplyChain: []domain.ThreadReply{
+			{Text: "Отличный разбор 👍", Own: true},
+			{Text: "А почему?"},
+		},
+	}
+	if _, err := n.GetComment(context.Background(), msg); err != nil {
+		t.Fatalf("GetComment() error = %v", err)
+	}
+
+	reqs := srv.Requests()
+	if len(reqs) != 1 {
+		t.Fatalf("requests = %d, want 1", len(reqs))
+	}
+	wantBody := expectedBody(domain.MessageContent{
+		Type: "text",
+		Text: "<post>\nРынок растёт третий день\n</post>\n\n<conversation>\n- (наш) Отличный разбор 👍\n- А почему?\n</conversation>",
+	})
+	wantBody.Messages[0].Content[0].Text += "\n\n" + conversationGuard
+	if !reflect.DeepEqual(reqs[0].Body, wantBody) {
+		t.Errorf("request body = %+v, want %+v", reqs[0].Body, wantBody)
+	}
+}
+
+func TestGetCommentFailures(t *testing.T) {
+	tests := []struct {
+		name         string
+		replies      []neurofake.Reply
+		want         string
+		wantErr      string
+		wantRequests int
+	}{
+		{
+			name:         "retry after 500 succeeds",
+			replies:      []neurofake.Reply{neurofake.ReplyStatus(http.StatusInternalServerError), neurofake.ReplyText("Вторая попытка 👍")},
+			want:         "Вторая попытка 👍",
+			wantRequests: 2,
+		},
+		{
+			name:         "retry after 429 succeeds",
+			replies:      []neurofake.Reply{neurofake.ReplyStatus(http.StatusTooManyRequests), neurofake.ReplyText("После лимита 👍")},
+			want:         "После лимита 👍",
+			wantRequests: 2,
+		},
+		{
+			name: "500 on every attempt",
+			replies: []neurofake.Reply{
+				neurofake.ReplyStatus(http.StatusInternalServerError),
+				neurofake.ReplyStatus(http.StatusInternalServerError),
+				neurofake.ReplyStatus(http.StatusInternalServerError),
+			},
+			wantErr:      "status 500",
+			wantRequests: 3,
+		},
+		{
+			name: "malformed body",
+			replies: []neurofake.Reply{
+				neurofake.ReplyMalformed(),
+				neurofake.ReplyMalformed(),
+				neurofake.ReplyMalformed(),
+			},
+			wantErr:      "request failed",
+			wantRequests: 3,
+		},
+		{
+			name:         "empty choices",
+			replies:      []neurofake.Reply{neurofake.ReplyFixture(neurofake.FixtureEmptyChoices)},
+			wantErr:      "empty choices",
+			wantRequests: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			srv := neurofake.New()
+			defer srv.Close()
+			srv.Enqueue(tt.replies...)
+
+			n := newTestNeuro(t, srv)
+			got, err := n.GetComment(context.Background(), &domain.Message{Text: "Пост"})
+
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("GetComment() error = %v, want containing %q", err, tt.wantErr)
+				}
+			} else if err != nil {
+				t.Fatalf("GetComment() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("GetComment() = %q, want %q", got, tt.want)
+			}
+			if reqs := srv.Requests(); len(reqs) != tt.wantRequests {
+				t.Errorf("requests = %d, want %d", len(reqs), tt.wantRequests)
+			}
+		})
+	}
+}
@@ -0,0 +1,70 @@
+package neuro
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/larriantoniy/tg_user_bot/internal/config"
+	"github.com/larriantoniy/tg_user_bot/internal/domain"
+)
+
+// ollamaProvider — локальный Ollama, POST /api/chat без стриминга
+type ollamaProvider struct {
+	cfg  config.ProviderConfig
+	http *httpJSON
+}
+
+func newOllamaProvider(pc config.ProviderConfig, h *httpJSON) (provider, error) {
+	if pc.Model == "" {
+		return nil, fmt.Errorf("model is required")
+	}
+	return &ollamaProvider{cfg: pc, http: h}, nil
+}
+
+func (p *ollamaProvider) Name() string           { return p.cfg.Name }
+func (p *ollamaProvider) Model() string          { return p.cfg.Model }
+func (p *ollamaProvider) Timeout() time.Duration { return p.cfg.Timeout }
+
+func (p *ollamaProvider) Complete(ctx context.Context, req chatRequest) (completion, error) {
+	user := domain.OllamaMessage{Role: domain.RoleUser, Content: req.Post}
+	for _, url := range req.ImageURLs {
+		// Ollama принимает только сами байты картинки, ссылки не скачивает
+		if _, data, ok := splitDataURL(url); ok {
+			user.Images = append(user.Images, data)
+		} else {
+			p.http.logger.Warn("Ollama accepts only inline images, image skipped", "provider", p.cfg.Name)
+		}
+	}
+
+	body := domain.OllamaChatBody{
+		Model: p.cfg.Model,
+		Messages: []domain.OllamaMessage{
+			{Role: domain.RoleSystem, Content: req.System},
+			user,
+		},
+		Options: domain.OllamaOptions{
+			Temperature: *p.cfg.Temperature,
+			NumPredict:  p.cfg.MaxTokens,
+		},
+	}
+
+	var resp domain.OllamaChatResponse
+	var headers map[string]string
+	if p.cfg.Token != "" {
+		headers = map[string]string{"Authorization": "Bearer " + p.cfg.Token}
+	}
+	if err := p.http.post(ctx, p.cfg.Addr, headers, body, &resp); err != nil {
+		return completion{}, err
+	}
+
+	usage := domain.Usage{
+		PromptTokens:     resp.PromptEvalCount,
+		CompletionTokens: resp.EvalCount,
+		TotalTokens:      resp.PromptEvalCount + resp.EvalCount,
+	}
+	if resp.Message.Content == "" {
+		return completion{Usage: usage}, fmt.Errorf("empty message: %w", errEmptyResponse)
+	}
+	return completion{Text: resp.Message.Content, Usage: usage}, nil
+}
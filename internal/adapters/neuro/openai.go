@@ -0,0 +1,66 @@
+package neuro
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/larriantoniy/tg_user_bot/internal/config"
+	"github.com/larriantoniy/tg_user_bot/internal/domain"
+)
+
+// openAIProvider — OpenAI-совместимый chat completions (OpenRouter и аналоги)
+type openAIProvider struct {
+	cfg  config.ProviderConfig
+	http *httpJSON
+}
+
+func newOpenAIProvider(pc config.ProviderConfig, h *httpJSON) (provider, error) {
+	if pc.Model == "" {
+		pc.Model = domain.MistralModel
+	}
+	return &openAIProvider{cfg: pc, http: h}, nil
+}
+
+func (p *openAIProvider) Name() string           { return p.cfg.Name }
+func (p *openAIProvider) Model() string          { return p.cfg.Model }
+func (p *openAIProvider) Timeout() time.Duration { return p.cfg.Timeout }
+
+func (p *openAIProvider) Complete(ctx context.Context, req chatRequest) (completion, error) {
+	content := []domain.MessageContent{{Type: "text", Text: req.Post}}
+	for _, url := range req.ImageURLs {
+		content = append(content, domain.MessageContent{
+			Type:     "image_url",
+			ImageUrl: &domain.ImageUrl{Url: url},
+		})
+	}
+
+	body := domain.DefaultNeuroBody{
+		Model:            p.cfg.Model,
+		Temperature:      *p.cfg.Temperature,
+		TopP:             0.9,
+		PresencePenalty:  0.2,
+		FrequencyPenalty: 0.3,
+		MaxTokens:        p.cfg.MaxTokens,
+		Messages: []domain.NeuroMessage{
+			{
+				Role:    domain.RoleSystem,
+				Content: []domain.MessageContent{{Type: "text", Text: req.System}},
+			},
+			{
+				Role:    domain.RoleUser,
+				Content: content,
+			},
+		},
+	}
+
+	var nr domain.NeuroResponse
+	headers := map[string]string{"Authorization": "Bearer " + p.cfg.Token}
+	if err := p.http.post(ctx, p.cfg.Addr, headers, body, &nr); err != nil {
+		return completion{}, err
+	}
+	if len(nr.Choices) == 0 {
+		return completion{Usage: nr.Usage}, fmt.Errorf("empty choices: %w", errEmptyResponse)
+	}
+	return completion{Text: nr.Choices[0].Message.Content, Usage: nr.Usage}, nil
+}
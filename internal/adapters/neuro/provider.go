@@ -0,0 +1,178 @@
+package neuro
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/larriantoniy/tg_user_bot/internal/config"
+	"github.com/larriantoniy/tg_user_bot/internal/domain"
+	"github.com/larriantoniy/tg_user_bot/internal/ports"
+)
+
+const (
+	ProviderOpenAI    = "openai"    // chat completions: OpenRouter, vLLM, LM Studio и т.п.
+	ProviderOllama    = "ollama"    // локальный /api/chat
+	ProviderAnthropic = "anthropic" // messages API
+
+	defaultTemperature     = 0.4
+	defaultMaxTokens       = 120
+	defaultProviderTimeout = 60 * time.Second
+)
+
+// errEmptyResponse — модель ответила без текста; повтор у того же провайдера не поможет
+var errEmptyResponse = fmt.Errorf("model returned no text: %w", ports.ErrNeuroEmpty)
+
+// chatRequest — запрос к модели, не зависящий от провайдера
+type chatRequest struct {
+	System    string   // инструкции
+	Post      string   // пост, уже обёрнутый wrapPost, комментарии обсуждения и переписка из wrapReplies
+	ImageURLs []string // http(s) или data URL; пусто — без картинок
+}
+
+// completion — ответ модели, не зависящий от провайдера
+type completion struct {
+	Text  string
+	Usage domain.Usage
+}
+
+// provider — один LLM API. Реализации переводят chatRequest в формат своего API.
+type provider interface {
+	Name() string
+	Model() string
+	Timeout() time.Duration
+	Complete(ctx context.Context, req chatRequest) (completion, error)
+}
+
+type providerFactory func(pc config.ProviderConfig, h *httpJSON) (provider, error)
+
+// providerTypes — реестр поддерживаемых API, ключ — config.ProviderConfig.Type
+var providerTypes = map[string]providerFactory{
+	ProviderOpenAI:    newOpenAIProvider,
+	ProviderOllama:    newOllamaProvider,
+	ProviderAnthropic: newAnthropicProvider,
+}
+
+// newProviders собирает цепочку fallback из конфига. Без providers — один openai
+// по NeuroAddr/NeuroToken с прежними настройками.
+func newProviders(cfg *config.AppConfig, h *httpJSON) ([]provider, error) {
+	pcs := cfg.Providers
+	if len(pcs) == 0 {
+		pcs = []config.ProviderConfig{{
+			Name:  ProviderOpenAI,
+			Type:  ProviderOpenAI,
+			Addr:  cfg.NeuroAddr,
+			Token: cfg.NeuroToken,
+		}}
+	}
+
+	out := make([]provider, 0, len(pcs))
+	for _, pc := range pcs {
+		factory, ok := providerTypes[pc.Type]
+		if !ok {
+			return nil, fmt.Errorf("provider %q: unknown type %q (supported: %s)", pc.Name, pc.Type, supportedTypes())
+		}
+		if pc.Name == "" {
+			pc.Name = pc.Type
+		}
+		if pc.Temperature == nil {
+			t := defaultTemperature
+			pc.Temperature = &t
+		}
+		if pc.MaxTokens == 0 {
+			pc.MaxTokens = defaultMaxTokens
+		}
+		if pc.Timeout == 0 {
+			pc.Timeout = defaultProviderTimeout
+		}
+		p, err := factory(pc, h)
+		if err != nil {
+			return nil, fmt.Errorf("provider %q: %w", pc.Name, err)
+		}
+		out = append(out, p)
+	}
+	return out, nil
+}
+
+func supportedTypes() string {
+	types := make([]string, 0, len(providerTypes))
+	for t := range providerTypes {
+		types = append(types, t)
+	}
+	sort.Strings(types)
+	return strings.Join(types, ", ")
+}
+
+// httpJSON — общий для провайдеров POST с JSON-телом и JSON-ответом
+type httpJSON struct {
+	client *http.Client
+	logger *slog.Logger
+}
+
+func (h *httpJSON) post(ctx context.Context, url string, headers map[string]string, body, out any) error {
+	bodyBytes, err := json.Marshal(body)
+	if err != nil {
+		return fmt.Errorf("marshal body: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(bodyBytes))
+	if err != nil {
+		return fmt.Errorf("new request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+
+	h.logger.Info("Neuro request", "url", req.URL.String())
+
+	resp, err := h.client.Do(req)
+	if err != nil {
+		h.logger.Error("HTTP request to neuro failed", "err", err)
+		// сеть и таймаут попытки; отмену общего ctx retryPolicy проверяет сама
+		return &ports.NeuroError{Kind: ports.ErrNeuroTransient, Err: err}
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		data, _ := io.ReadAll(resp.Body)
+		h.logger.Error("Neuro API returned error",
+			"status", resp.StatusCode,
+			"body", string(data),
+		)
+		return classifyStatus(resp.StatusCode, resp.Header, data, fmt.Errorf("status %d: %s", resp.StatusCode, string(data)))
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		// оборванное или битое тело — обычно сбой прокси, повтор помогает
+		return &ports.NeuroError{Status: resp.StatusCode, Kind: ports.ErrNeuroTransient, Err: fmt.Errorf("decode response: %w", err)}
+	}
+	return nil
+}
+
+// splitDataURL разбирает data:<mime>;base64,<data>; ok=false — это не base64 data URL
+func splitDataURL(u string) (mime, data string, ok bool) {
+	rest, found := strings.CutPrefix(u, "data:")
+	if !found {
+		return "", "", false
+	}
+	meta, data, found := strings.Cut(rest, ",")
+	if !found {
+		return "", "", false
+	}
+	mime, found = strings.CutSuffix(meta, ";base64")
+	return mime, data, found
+}
+
+// ValidateProviders проверяет цепочку провайдеров при старте, до запуска сессий
+func ValidateProviders(cfg *config.AppConfig) error {
+	_, err := newProviders(cfg, &httpJSON{client: http.DefaultClient, logger: slog.Default()})
+	return err
+}
@@ -0,0 +1,223 @@
+package neuro
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/larriantoniy/tg_user_bot/internal/adapters/metrics"
+	"github.com/larriantoniy/tg_user_bot/internal/adapters/neurofake"
+	"github.com/larriantoniy/tg_user_bot/internal/config"
+	"github.com/larriantoniy/tg_user_bot/internal/domain"
+)
+
+func newChainNeuro(t *testing.T, providers ...config.ProviderConfig) *Neuro {
+	t.Helper()
+	n, err := NewNeuro(&config.AppConfig{Providers: providers},
+		slog.New(slog.NewTextHandler(io.Discard, nil)), "test-session", nil, metrics.Nop{}, nil, nil)
+	if err != nil {
+		t.Fatalf("NewNeuro() error = %v", err)
+	}
+	n.retry = testRetryPolicy
+	return n
+}
+
+const testSystem = systemPrompt + "\n\n" + postGuard
+
+func TestProviderFallbackChain(t *testing.T) {
+	primary, secondary := neurofake.New(), neurofake.New()
+	defer primary.Close()
+	defer secondary.Close()
+	primary.SetDefault(neurofake.ReplyStatus(http.StatusBadGateway))
+	secondary.Enqueue(neurofake.ReplyText("Запасной ответ 👍"))
+
+	temperature := 0.7
+	n := newChainNeuro(t,
+		config.ProviderConfig{Name: "primary", Type: ProviderOpenAI, Addr: primary.URL(), Token: "a"},
+		config.ProviderConfig{Name: "secondary", Type: ProviderOpenAI, Addr: secondary.URL(), Token: "b", Model: "openai/gpt-4o-mini", Temperature: &temperature, MaxTokens: 60},
+	)
+	got, err := n.GetComment(context.Background(), &domain.Message{Text: "Пост"})
+	if err != nil {
+		t.Fatalf("GetComment() error = %v", err)
+	}
+	if got != "Запасной ответ 👍" {
+		t.Errorf("GetComment() = %q, want reply of secondary", got)
+	}
+	if reqs := primary.Requests(); len(reqs) != 3 {
+		t.Errorf("primary requests = %d, want 3 attempts", len(reqs))
+	}
+
+	reqs := secondary.Requests()
+	if len(reqs) != 1 {
+		t.Fatalf("secondary requests = %d, want 1", len(reqs))
+	}
+	body := reqs[0].Body
+	if body.Model != "openai/gpt-4o-mini" || body.Temperature != 0.7 || body.MaxTokens != 60 {
+		t.Errorf("secondary body = %+v, want per-provider model, temperature and max_tokens", body)
+	}
+	if auth := reqs[0].Header.Get("Authorization"); auth != "Bearer b" {
+		t.Errorf("Authorization = %q, want secondary token", auth)
+	}
+}
+
+func TestProviderChainAllFail(t *testing.T) {
+	first, second := neurofake.New(), neurofake.New()
+	defer first.Close()
+	defer second.Close()
+	second.Enqueue(neurofake.ReplyFixture(neurofake.FixtureEmptyChoices))
+
+	n := newChainNeuro(t,
+		config.ProviderConfig{Name: "first", Type: ProviderOpenAI, Addr: first.URL()},
+		config.ProviderConfig{Name: "second", Type: ProviderOpenAI, Addr: second.URL()},
+	)
+	_, err := n.GetComment(context.Background(), &domain.Message{Text: "Пост"})
+	if err == nil {
+		t.Fatal("GetComment() error = nil, want error")
+	}
+	for _, want := range []string{"first: request failed: status 500", "second: request failed: empty choices"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error %q does not contain %q", err, want)
+		}
+	}
+	// пустой ответ не повторяем у того же провайдера
+	if reqs := second.Requests(); len(reqs) != 1 {
+		t.Errorf("second requests = %d, want 1", len(reqs))
+	}
+}
+
+func TestProviderZeroTemperature(t *testing.T) {
+	srv := neurofake.New()
+	defer srv.Close()
+	srv.Enqueue(neurofake.ReplyFixture(neurofake.FixtureComment))
+
+	zero := 0.0
+	n := newChainNeuro(t, config.ProviderConfig{Type: ProviderOpenAI, Addr: srv.URL(), Temperature: &zero})
+	if _, err := n.GetComment(context.Background(), &domain.Message{Text: "Пост"}); err != nil {
+		t.Fatalf("GetComment() error = %v", err)
+	}
+	// temperature: 0 из конфига уходит в запрос, а не подменяется умолчанием
+	if raw := string(srv.Requests()[0].Raw); !strings.Contains(raw, `"temperature":0,`) {
+		t.Errorf("request body = %s, want temperature 0", raw)
+	}
+}
+
+func TestOllamaProvider(t *testing.T) {
+	srv := neurofake.New()
+	defer srv.Close()
+	srv.Enqueue(neurofake.ReplyJSON(http.StatusOK, domain.OllamaChatResponse{
+		Model:           "llama3.2-vision",
+		Message:         domain.OllamaMessage{Role: domain.RoleAssistant, Content: "Локально 👍"},
+		Done:            true,
+		PromptEvalCount: 40,
+		EvalCount:       8,
+	}))
+
+	n := newChainNeuro(t, config.ProviderConfig{Type: ProviderOllama, Addr: srv.URL(), Model: "llama3.2-vision", MaxTokens: 80})
+	msg := &domain.Message{Text: "Пост", PhotoFile: "data:image/png;base64,AAAA"}
+	got, err := n.GetComment(context.Background(), msg)
+	if err != nil {
+		t.Fatalf("GetComment() error = %v", err)
+	}
+	if got != "Локально 👍" {
+		t.Errorf("GetComment() = %q, want %q", got, "Локально 👍")
+	}
+
+	var body domain.OllamaChatBody
+	if err := json.Unmarshal(srv.Requests()[0].Raw, &body); err != nil {
+		t.Fatalf("decode request: %v", err)
+	}
+	want := domain.OllamaChatBody{
+		Model: "llama3.2-vision",
+		Messages: []domain.OllamaMessage{
+			{Role: domain.RoleSystem, Content: testSystem},
+			{Role: domain.RoleUser, Content: "<post>\nПост\n</post>", Images: []string{"AAAA"}},
+		},
+		Options: domain.OllamaOptions{Temperature: defaultTemperature, NumPredict: 80},
+	}
+	if !reflect.DeepEqual(body, want) {
+		t.Errorf("request body = %+v, want %+v", body, want)
+	}
+}
+
+func TestAnthropicProvider(t *testing.T) {
+	srv := neurofake.New()
+	defer srv.Close()
+	srv.Enqueue(neurofake.ReplyJSON(http.StatusOK, domain.AnthropicResponse{
+		ID:      "msg_1",
+		Content: []domain.AnthropicContent{{Type: "text", Text: "Через messages API 👍"}},
+		Usage:   domain.AnthropicUsage{InputTokens: 50, OutputTokens: 9},
+	}))
+
+	n := newChainNeuro(t, config.ProviderConfig{Type: ProviderAnthropic, Addr: srv.URL(), Token: "sk-test", Model: "claude-haiku"})
+	msg := &domain.Message{Text: "Пост", PhotoFile: "https://example.com/chart.png"}
+	got, err := n.GetComment(context.Background(), msg)
+	if err != nil {
+		t.Fatalf("GetComment() error = %v", err)
+	}
+	if got != "Через messages API 👍" {
+		t.Errorf("GetComment() = %q, want %q", got, "Через messages API 👍")
+	}
+
+	req := srv.Requests()[0]
+	if key := req.Header.Get("x-api-key"); key != "sk-test" {
+		t.Errorf("x-api-key = %q, want sk-test", key)
+	}
+	if v := req.Header.Get("anthropic-version"); v != anthropicVersion {
+		t.Errorf("anthropic-version = %q, want %q", v, anthropicVersion)
+	}
+	var body domain.AnthropicMessagesBody
+	if err := json.Unmarshal(req.Raw, &body); err != nil {
+		t.Fatalf("decode request: %v", err)
+	}
+	want := domain.AnthropicMessagesBody{
+		Model:       "claude-haiku",
+		System:      testSystem,
+		MaxTokens:   defaultMaxTokens,
+		Temperature: defaultTemperature,
+		Messages: []domain.AnthropicMessage{{
+			Role: domain.RoleUser,
+			Content: []domain.AnthropicContent{
+				{Type: "text", Text: "<post>\nПост\n</post>"},
+				{Type: "image", Source: &domain.AnthropicImageSource{Type: "url", URL: "https://example.com/chart.png"}},
+			},
+		}},
+	}
+	if !reflect.DeepEqual(body, want) {
+		t.Errorf("request body = %+v, want %+v", body, want)
+	}
+}
+
+func TestNewNeuroRejectsBadProviders(t *testing.T) {
+	tests := []struct {
+		name    string
+		pc      config.ProviderConfig
+		wantErr string
+	}{
+		{
+			name:    "unknown type",
+			pc:      config.ProviderConfig{Name: "x", Type: "gemini", Addr: "http://localhost"},
+			wantErr: `unknown type "gemini"`,
+		},
+		{
+			name:    "ollama without model",
+			pc:      config.ProviderConfig{Name: "local", Type: ProviderOllama, Addr: "http://localhost:11434/api/chat"},
+			wantErr: "model is required",
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewNeuro(&config.AppConfig{Providers: []config.ProviderConfig{tt.pc}},
+				slog.New(slog.NewTextHandler(io.Discard, nil)), "test-session", nil, metrics.Nop{}, nil, nil)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("NewNeuro() error = %v, want containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,115 @@
+package neuro
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/larriantoniy/tg_user_bot/internal/ports"
+)
+
+// retryPolicy — повторы запроса к одному провайдеру
+type retryPolicy struct {
+	Attempts  int
+	BaseDelay time.Duration // пауза перед второй попыткой, дальше удваивается
+	MaxDelay  time.Duration // потолок паузы; Retry-After длиннее — сразу идём к следующему провайдеру
+}
+
+var defaultRetryPolicy = retryPolicy{
+	Attempts:  3,
+	BaseDelay: time.Second,
+	MaxDelay:  20 * time.Second,
+}
+
+// do повторяет fn, пока ошибка временная (ports.ErrNeuroTransient) и не кончились попытки.
+// Ожидание прерывается отменой ctx.
+func (p retryPolicy) do(ctx context.Context, fn func() error) error {
+	var err error
+	for attempt := 0; attempt < p.Attempts; attempt++ {
+		if err = fn(); err == nil || !errors.Is(err, ports.ErrNeuroTransient) {
+			return err
+		}
+		if attempt == p.Attempts-1 {
+			break
+		}
+
+		delay := p.backoff(attempt)
+		if ra, ok := ports.NeuroRetryAfter(err); ok {
+			if ra > p.MaxDelay {
+				return err
+			}
+			if ra > delay {
+				delay = ra
+			}
+		}
+
+		t := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return errors.Join(err, ctx.Err())
+		case <-t.C:
+		}
+	}
+	return err
+}
+
+// backoff — экспоненциальная пауза с jitter: случайно в [d/2, d], d = BaseDelay·2^attempt
+func (p retryPolicy) backoff(attempt int) time.Duration {
+	if p.BaseDelay <= 0 {
+		return 0
+	}
+	d := p.BaseDelay << attempt
+	if d <= 0 || d > p.MaxDelay { // d <= 0 — переполнение сдвига
+		d = p.MaxDelay
+	}
+	half := d / 2
+	return half + time.Duration(rand.Int63n(int64(d-half)+1))
+}
+
+// classifyStatus превращает неуспешный HTTP-ответ в ports.NeuroError
+func classifyStatus(status int, header http.Header, body []byte, err error) *ports.NeuroError {
+	ne := &ports.NeuroError{
+		Status:     status,
+		RetryAfter: parseRetryAfter(header.Get("Retry-After"), time.Now()),
+		Err:        err,
+	}
+	switch {
+	case status == http.StatusPaymentRequired || isQuotaBody(body):
+		ne.Kind = ports.ErrNeuroQuota
+	case status == http.StatusTooManyRequests || status == http.StatusRequestTimeout || status >= 500:
+		ne.Kind = ports.ErrNeuroTransient
+	}
+	return ne
+}
+
+// isQuotaBody — провайдеры отдают исчерпанную квоту то 429, то 403 с особым текстом
+func isQuotaBody(body []byte) bool {
+	s := strings.ToLower(string(body))
+	return strings.Contains(s, "insufficient_quota") ||
+		strings.Contains(s, "quota exceeded") ||
+		strings.Contains(s, "credit balance") ||
+		strings.Contains(s, "insufficient credits")
+}
+
+// parseRetryAfter понимает оба формата заголовка: секунды и HTTP-дату
+func parseRetryAfter(v string, now time.Time) time.Duration {
+	v = strings.TrimSpace(v)
+	if v == "" {
+		return 0
+	}
+	if secs, err := strconv.Atoi(v); err == nil {
+		if secs < 0 {
+			return 0
+		}
+		return time.Duration(secs) * time.Second
+	}
+	if at, err := http.ParseTime(v); err == nil && at.After(now) {
+		return at.Sub(now)
+	}
+	return 0
+}
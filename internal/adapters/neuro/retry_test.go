@@ -0,0 +1,186 @@
+package neuro
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/larriantoniy/tg_user_bot/internal/adapters/neurofake"
+	"github.com/larriantoniy/tg_user_bot/internal/config"
+	"github.com/larriantoniy/tg_user_bot/internal/domain"
+	"github.com/larriantoniy/tg_user_bot/internal/ports"
+)
+
+func withRetryAfter(r neurofake.Reply, v string) neurofake.Reply {
+	r.Header = http.Header{"Retry-After": []string{v}}
+	return r
+}
+
+func TestRetryClassifiesErrors(t *testing.T) {
+	tests := []struct {
+		name           string
+		reply          neurofake.Reply
+		wantKind       error
+		wantRequests   int
+		wantRetryAfter time.Duration
+	}{
+		{
+			name:         "bad request is not retried",
+			reply:        neurofake.ReplyStatus(http.StatusBadRequest),
+			wantRequests: 1,
+		},
+		{
+			name:         "payment required is quota",
+			reply:        neurofake.ReplyStatus(http.StatusPaymentRequired),
+			wantKind:     ports.ErrNeuroQuota,
+			wantRequests: 1,
+		},
+		{
+			name: "429 with insufficient_quota is quota",
+			reply: neurofake.ReplyJSON(http.StatusTooManyRequests, map[string]any{
+				"error": map[string]string{"type": "insufficient_quota", "message": "You exceeded your current quota"},
+			}),
+			wantKind:     ports.ErrNeuroQuota,
+			wantRequests: 1,
+		},
+		{
+			name:         "503 is retried",
+			reply:        neurofake.ReplyStatus(http.StatusServiceUnavailable),
+			wantKind:     ports.ErrNeuroTransient,
+			wantRequests: 3,
+		},
+		{
+			name:           "long Retry-After gives up at once",
+			reply:          withRetryAfter(neurofake.ReplyStatus(http.StatusTooManyRequests), "120"),
+			wantKind:       ports.ErrNeuroTransient,
+			wantRequests:   1,
+			wantRetryAfter: 2 * time.Minute,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			srv := neurofake.New()
+			defer srv.Close()
+			srv.SetDefault(tt.reply)
+
+			n := newTestNeuro(t, srv)
+			_, err := n.GetComment(context.Background(), &domain.Message{Text: "Пост"})
+			if err == nil {
+				t.Fatal("GetComment() error = nil, want error")
+			}
+			for _, kind := range []error{ports.ErrNeuroQuota, ports.ErrNeuroTransient} {
+				if got := errors.Is(err, kind); got != (kind == tt.wantKind) {
+					t.Errorf("errors.Is(%v, %v) = %v", err, kind, got)
+				}
+			}
+			if got := len(srv.Requests()); got != tt.wantRequests {
+				t.Errorf("requests = %d, want %d", got, tt.wantRequests)
+			}
+			if got, _ := ports.NeuroRetryAfter(err); got != tt.wantRetryAfter {
+				t.Errorf("NeuroRetryAfter() = %s, want %s", got, tt.wantRetryAfter)
+			}
+		})
+	}
+}
+
+func TestRetryHonorsRetryAfter(t *testing.T) {
+	srv := neurofake.New()
+	defer srv.Close()
+	srv.Enqueue(
+		withRetryAfter(neurofake.ReplyStatus(http.StatusTooManyRequests), "1"),
+		neurofake.ReplyText("После паузы 👍"),
+	)
+
+	n := newTestNeuro(t, srv)
+	n.retry.MaxDelay = 2 * time.Second
+	started := time.Now()
+	got, err := n.GetComment(context.Background(), &domain.Message{Text: "Пост"})
+	if err != nil {
+		t.Fatalf("GetComment() error = %v", err)
+	}
+	if got != "После паузы 👍" {
+		t.Errorf("GetComment() = %q, want second reply", got)
+	}
+	// backoff тестовой политики — миллисекунды, значит ждали именно Retry-After
+	if elapsed := time.Since(started); elapsed < time.Second {
+		t.Errorf("retried after %s, want at least Retry-After 1s", elapsed)
+	}
+}
+
+func TestRetryStopsOnContextCancel(t *testing.T) {
+	srv := neurofake.New()
+	defer srv.Close()
+	srv.SetDefault(neurofake.ReplyStatus(http.StatusInternalServerError))
+
+	n := newTestNeuro(t, srv)
+	n.retry = retryPolicy{Attempts: 3, BaseDelay: time.Minute, MaxDelay: time.Minute}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	started := time.Now()
+	_, err := n.GetComment(ctx, &domain.Message{Text: "Пост"})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("GetComment() error = %v, want context.DeadlineExceeded", err)
+	}
+	if elapsed := time.Since(started); elapsed > 5*time.Second {
+		t.Errorf("GetComment() returned after %s, want prompt return on cancel", elapsed)
+	}
+	if got := len(srv.Requests()); got != 1 {
+		t.Errorf("requests = %d, want 1", got)
+	}
+}
+
+func TestQuotaOfOneProviderIsNotQuotaOfChain(t *testing.T) {
+	first, second := neurofake.New(), neurofake.New()
+	defer first.Close()
+	defer second.Close()
+	first.SetDefault(neurofake.ReplyStatus(http.StatusPaymentRequired))
+	second.SetDefault(neurofake.ReplyStatus(http.StatusBadGateway))
+
+	n := newChainNeuro(t,
+		config.ProviderConfig{Name: "first", Type: ProviderOpenAI, Addr: first.URL()},
+		config.ProviderConfig{Name: "second", Type: ProviderOpenAI, Addr: second.URL()},
+	)
+	_, err := n.GetComment(context.Background(), &domain.Message{Text: "Пост"})
+	if errors.Is(err, ports.ErrNeuroQuota) {
+		t.Errorf("error %v is quota, want only transient when a fallback failed differently", err)
+	}
+	if !errors.Is(err, ports.ErrNeuroTransient) {
+		t.Errorf("error %v is not transient", err)
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2026, 5, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		value string
+		want  time.Duration
+	}{
+		{"", 0},
+		{"30", 30 * time.Second},
+		{"-5", 0},
+		{now.Add(90 * time.Second).Format(http.TimeFormat), 90 * time.Second},
+		{now.Add(-time.Minute).Format(http.TimeFormat), 0},
+		{"soon", 0},
+	}
+	for _, tt := range tests {
+		if got := parseRetryAfter(tt.value, now); got != tt.want {
+			t.Errorf("parseRetryAfter(%q) = %s, want %s", tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestBackoffJitterBounds(t *testing.T) {
+	p := retryPolicy{Attempts: 5, BaseDelay: time.Second, MaxDelay: 5 * time.Second}
+	for attempt, max := range []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 5 * time.Second} {
+		for i := 0; i < 50; i++ {
+			if d := p.backoff(attempt); d < max/2 || d > max {
+				t.Fatalf("backoff(%d) = %s, want within [%s, %s]", attempt, d, max/2, max)
+			}
+		}
+	}
+}
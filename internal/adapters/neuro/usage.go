@@ -0,0 +1,207 @@
+package neuro
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/larriantoniy/tg_user_bot/internal/config"
+	"github.com/larriantoniy/tg_user_bot/internal/domain"
+	"github.com/larriantoniy/tg_user_bot/internal/ports"
+)
+
+var _ ports.UsageReporter = (*Accounting)(nil)
+
+// Accounting — учёт токенов и денег по всем сессиям процесса и лимиты расходов.
+// Один на процесс, как Breakers: общий лимит должен видеть расход всех сессий.
+type Accounting struct {
+	cfg     config.UsageConfig
+	ledger  ports.UsageLedger
+	logger  *slog.Logger
+	metrics ports.Metrics
+	now     func() time.Time
+
+	mu       sync.Mutex
+	unpriced map[string]bool // модели без цены: предупреждаем один раз
+}
+
+func NewAccounting(cfg config.UsageConfig, ledger ports.UsageLedger, logger *slog.Logger, metrics ports.Metrics) *Accounting {
+	return &Accounting{
+		cfg:      cfg,
+		ledger:   ledger,
+		logger:   logger,
+		metrics:  metrics,
+		now:      time.Now,
+		unpriced: make(map[string]bool),
+	}
+}
+
+// UsageReport — расход за текущий месяц; пустой session — по всем сессиям
+func (a *Accounting) UsageReport(ctx context.Context, session string) (domain.UsageReport, error) {
+	now := a.now()
+	month := monthStart(now)
+	rep := domain.UsageReport{
+		Session:   session,
+		Currency:  a.cfg.Currency,
+		Since:     month,
+		Budget:    budgetOf(a.cfg.Budget),
+		BySession: make(map[string]domain.UsageTotals),
+		ByChannel: make(map[string]domain.UsageTotals),
+		ByModel:   make(map[string]domain.UsageTotals),
+	}
+	if session != "" {
+		rep.Budget = budgetOf(a.cfg.Sessions[session])
+	}
+
+	rows, err := a.ledger.Since(ctx, month.Format(domain.UsageDayLayout))
+	if err != nil {
+		return rep, fmt.Errorf("read usage ledger: %w", err)
+	}
+	today := now.Format(domain.UsageDayLayout)
+	for _, row := range rows {
+		if session != "" && row.Session != session {
+			continue
+		}
+		rep.Month.Add(row.UsageTotals)
+		if row.Day == today {
+			rep.Today.Add(row.UsageTotals)
+		}
+		addTo(rep.BySession, row.Session, row.UsageTotals)
+		addTo(rep.ByChannel, row.Channel, row.UsageTotals)
+		addTo(rep.ByModel, row.Model, row.UsageTotals)
+	}
+	return rep, nil
+}
+
+// check отказывает, если исчерпан лимит сессии или общий; nil-safe
+func (a *Accounting) check(ctx context.Context, session string) error {
+	if a == nil {
+		return nil
+	}
+	own := a.cfg.Sessions[session]
+	if !limited(a.cfg.Budget) && !limited(own) {
+		return nil
+	}
+
+	now := a.now()
+	rows, err := a.ledger.Since(ctx, monthStart(now).Format(domain.UsageDayLayout))
+	if err != nil {
+		// без данных о расходах не останавливаем комментарии, но шумим
+		a.logger.Warn("Usage ledger read failed, budget not checked", "error", err)
+		return nil
+	}
+	today := now.Format(domain.UsageDayLayout)
+	var all, mine domain.UsageTotals // за месяц
+	var allToday, mineToday domain.UsageTotals
+	for _, row := range rows {
+		all.Add(row.UsageTotals)
+		if row.Day == today {
+			allToday.Add(row.UsageTotals)
+		}
+		if row.Session == session {
+			mine.Add(row.UsageTotals)
+			if row.Day == today {
+				mineToday.Add(row.UsageTotals)
+			}
+		}
+	}
+	if err := a.exceeded("session", own, mineToday.Cost, mine.Cost, now); err != nil {
+		return err
+	}
+	return a.exceeded("global", a.cfg.Budget, allToday.Cost, all.Cost, now)
+}
+
+// exceeded проверяет один лимит; месячный первым — по нему пауза дольше
+func (a *Accounting) exceeded(scope string, b config.BudgetConfig, day, month float64, now time.Time) error {
+	switch {
+	case b.Monthly > 0 && month >= b.Monthly:
+		return &ports.NeuroError{
+			RetryAfter: monthStart(now).AddDate(0, 1, 0).Sub(now),
+			Kind:       ports.ErrNeuroBudget,
+			Err:        fmt.Errorf("%s monthly budget %.2f %s spent (%.4f)", scope, b.Monthly, a.cfg.Currency, month),
+		}
+	case b.Daily > 0 && day >= b.Daily:
+		return &ports.NeuroError{
+			RetryAfter: dayStart(now).AddDate(0, 0, 1).Sub(now),
+			Kind:       ports.ErrNeuroBudget,
+			Err:        fmt.Errorf("%s daily budget %.2f %s spent (%.4f)", scope, b.Daily, a.cfg.Currency, day),
+		}
+	}
+	return nil
+}
+
+// record учитывает успешный ответ провайдера; nil-safe.
+// Ошибка ledger не ломает комментарий — только лог.
+func (a *Accounting) record(ctx context.Context, session, channel, provider, model string, u domain.Usage) {
+	if a == nil {
+		return
+	}
+	cost := a.cost(model, u)
+	rec := domain.UsageRecord{
+		Day:      a.now().Format(domain.UsageDayLayout),
+		Session:  session,
+		Channel:  channel,
+		Provider: provider,
+		Model:    model,
+		UsageTotals: domain.UsageTotals{
+			Requests:         1,
+			PromptTokens:     u.PromptTokens,
+			CompletionTokens: u.CompletionTokens,
+			Cost:             cost,
+		},
+	}
+	if err := a.ledger.Add(ctx, rec); err != nil {
+		a.logger.Warn("Usage ledger write failed", "session", session, "model", model, "error", err)
+	}
+	a.metrics.NeuroCost(session, model, cost)
+}
+
+func (a *Accounting) cost(model string, u domain.Usage) float64 {
+	price, ok := a.cfg.Prices[model]
+	if !ok {
+		a.mu.Lock()
+		warned := a.unpriced[model]
+		a.unpriced[model] = true
+		a.mu.Unlock()
+		if !warned {
+			a.logger.Warn("No price for model, spend is counted in tokens only", "model", model)
+		}
+		return 0
+	}
+	return (float64(u.PromptTokens)*price.Prompt + float64(u.CompletionTokens)*price.Completion) / 1e6
+}
+
+// usageChannel — метка канала в учёте: название, а без него id
+func usageChannel(msg *domain.Message) string {
+	if msg.ChatName != "" {
+		return msg.ChatName
+	}
+	return strconv.FormatInt(msg.ChannelID, 10)
+}
+
+func addTo(m map[string]domain.UsageTotals, key string, t domain.UsageTotals) {
+	cur := m[key]
+	cur.Add(t)
+	m[key] = cur
+}
+
+func limited(b config.BudgetConfig) bool {
+	return b.Daily > 0 || b.Monthly > 0
+}
+
+func budgetOf(b config.BudgetConfig) domain.UsageBudget {
+	return domain.UsageBudget{Daily: b.Daily, Monthly: b.Monthly}
+}
+
+func dayStart(t time.Time) time.Time {
+	y, m, d := t.Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, t.Location())
+}
+
+func monthStart(t time.Time) time.Time {
+	y, m, _ := t.Date()
+	return time.Date(y, m, 1, 0, 0, 0, 0, t.Location())
+}
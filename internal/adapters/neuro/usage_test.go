@@ -0,0 +1,185 @@
+package neuro
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/larriantoniy/tg_user_bot/internal/adapters/metrics"
+	"github.com/larriantoniy/tg_user_bot/internal/adapters/neurofake"
+	"github.com/larriantoniy/tg_user_bot/internal/adapters/store"
+	"github.com/larriantoniy/tg_user_bot/internal/config"
+	"github.com/larriantoniy/tg_user_bot/internal/domain"
+	"github.com/larriantoniy/tg_user_bot/internal/ports"
+)
+
+// в фикстуре comment_ok 86 prompt и 14 completion токенов
+const fixtureCost = (86*2.0 + 14*10.0) / 1e6
+
+func newUsageNeuro(t *testing.T, srv *neurofake.Server, session string, acc *Accounting) *Neuro {
+	t.Helper()
+	n, err := NewNeuro(&config.AppConfig{NeuroAddr: srv.URL(), NeuroToken: "test-token"},
+		slog.New(slog.NewTextHandler(io.Discard, nil)), session, nil, metrics.Nop{}, nil, acc)
+	if err != nil {
+		t.Fatalf("NewNeuro() error = %v", err)
+	}
+	n.retry = testRetryPolicy
+	return n
+}
+
+func testAccounting(cfg config.UsageConfig) *Accounting {
+	cfg.Currency = "USD"
+	cfg.Prices = map[string]config.PriceConfig{domain.MistralModel: {Prompt: 2, Completion: 10}}
+	return NewAccounting(cfg, store.NewMemoryUsage(), slog.New(slog.NewTextHandler(io.Discard, nil)), metrics.Nop{})
+}
+
+func TestAccountingRecordsUsage(t *testing.T) {
+	srv := neurofake.New()
+	defer srv.Close()
+	srv.SetDefault(neurofake.ReplyFixture(neurofake.FixtureComment))
+
+	acc := testAccounting(config.UsageConfig{})
+	alpha := newUsageNeuro(t, srv, "alpha", acc)
+	beta := newUsageNeuro(t, srv, "beta", acc)
+	ctx := context.Background()
+
+	for _, call := range []struct {
+		n    *Neuro
+		chat string
+	}{{alpha, "Рынки"}, {alpha, "Рынки"}, {alpha, "Крипта"}, {beta, "Рынки"}} {
+		if _, err := call.n.GetComment(ctx, &domain.Message{ChatName: call.chat, Text: "Пост"}); err != nil {
+			t.Fatalf("GetComment() error = %v", err)
+		}
+	}
+
+	rep, err := acc.UsageReport(ctx, "alpha")
+	if err != nil {
+		t.Fatalf("UsageReport() error = %v", err)
+	}
+	if rep.Month.Requests != 3 || rep.Month.PromptTokens != 3*86 || rep.Month.CompletionTokens != 3*14 {
+		t.Errorf("alpha month = %+v, want 3 requests of 86+14 tokens", rep.Month)
+	}
+	if math.Abs(rep.Today.Cost-3*fixtureCost) > 1e-12 {
+		t.Errorf("alpha cost today = %g, want %g", rep.Today.Cost, 3*fixtureCost)
+	}
+	if got := rep.ByChannel["Рынки"].Requests; got != 2 {
+		t.Errorf("alpha requests in «Рынки» = %d, want 2", got)
+	}
+	if got := rep.ByModel[domain.MistralModel].Requests; got != 3 {
+		t.Errorf("alpha requests by model = %d, want 3", got)
+	}
+
+	all, _ := acc.UsageReport(ctx, "")
+	if all.Month.Requests != 4 || len(all.BySession) != 2 {
+		t.Errorf("report for all sessions = %+v, want 4 requests in 2 sessions", all)
+	}
+}
+
+func TestAccountingRecordsEmptyResponse(t *testing.T) {
+	srv := neurofake.New()
+	defer srv.Close()
+	srv.Enqueue(neurofake.ReplyFixture(neurofake.FixtureEmptyChoices))
+
+	acc := testAccounting(config.UsageConfig{})
+	n := newUsageNeuro(t, srv, "alpha", acc)
+	ctx := context.Background()
+
+	if _, err := n.GetComment(ctx, &domain.Message{ChatName: "Рынки", Text: "Пост"}); !errors.Is(err, ports.ErrNeuroEmpty) {
+		t.Fatalf("GetComment() error = %v, want ErrNeuroEmpty", err)
+	}
+	// пустой ответ без текста, но промпт провайдер посчитал
+	rep, err := acc.UsageReport(ctx, "alpha")
+	if err != nil {
+		t.Fatalf("UsageReport() error = %v", err)
+	}
+	if rep.Month.Requests != 1 || rep.Month.PromptTokens != 86 || rep.Month.CompletionTokens != 0 {
+		t.Errorf("alpha month = %+v, want 1 request of 86 prompt tokens", rep.Month)
+	}
+}
+
+func TestAccountingBudget(t *testing.T) {
+	tests := []struct {
+		name      string
+		cfg       config.UsageConfig
+		session   string
+		wantAllow bool
+	}{
+		{
+			name:      "no limits",
+			session:   "alpha",
+			wantAllow: true,
+		},
+		{
+			name:    "global daily",
+			cfg:     config.UsageConfig{Budget: config.BudgetConfig{Daily: fixtureCost / 2}},
+			session: "beta",
+		},
+		{
+			name:    "session monthly",
+			cfg:     config.UsageConfig{Sessions: map[string]config.BudgetConfig{"alpha": {Monthly: fixtureCost / 2}}},
+			session: "alpha",
+		},
+		{
+			name:      "other session limit",
+			cfg:       config.UsageConfig{Sessions: map[string]config.BudgetConfig{"beta": {Daily: fixtureCost}}},
+			session:   "beta",
+			wantAllow: true,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			srv := neurofake.New()
+			defer srv.Close()
+			srv.SetDefault(neurofake.ReplyFixture(neurofake.FixtureComment))
+
+			acc := testAccounting(tt.cfg)
+			ctx := context.Background()
+			// расход одного запроса на alpha
+			if _, err := newUsageNeuro(t, srv, "alpha", acc).GetComment(ctx, &domain.Message{Text: "Пост"}); err != nil {
+				t.Fatalf("first GetComment() error = %v", err)
+			}
+
+			_, err := newUsageNeuro(t, srv, tt.session, acc).GetComment(ctx, &domain.Message{Text: "Пост"})
+			if tt.wantAllow {
+				if err != nil {
+					t.Errorf("GetComment() error = %v, want allowed", err)
+				}
+				return
+			}
+			if !errors.Is(err, ports.ErrNeuroBudget) {
+				t.Fatalf("GetComment() error = %v, want ErrNeuroBudget", err)
+			}
+			if wait, ok := ports.NeuroRetryAfter(err); !ok || wait <= 0 || wait > 31*24*time.Hour {
+				t.Errorf("RetryAfter = %s, %v; want until next day or month", wait, ok)
+			}
+			if got := len(srv.Requests()); got != 1 {
+				t.Errorf("requests = %d, want 1 (no request over budget)", got)
+			}
+		})
+	}
+}
+
+func TestAccountingBudgetResetsNextDay(t *testing.T) {
+	acc := testAccounting(config.UsageConfig{Budget: config.BudgetConfig{Daily: 0.01, Monthly: 1}})
+	// ledger выбрасывает старые дни по реальным часам, поэтому берём сегодняшний вечер
+	day := dayStart(time.Now()).Add(23 * time.Hour)
+	acc.now = func() time.Time { return day }
+	ctx := context.Background()
+
+	acc.record(ctx, "alpha", "Рынки", "openai", domain.MistralModel, domain.Usage{PromptTokens: 5000})
+	err := acc.check(ctx, "alpha")
+	if wait, _ := ports.NeuroRetryAfter(err); !errors.Is(err, ports.ErrNeuroBudget) || wait != time.Hour {
+		t.Fatalf("check() = %v (retry after %s), want daily budget until midnight", err, wait)
+	}
+
+	acc.now = func() time.Time { return day.Add(2 * time.Hour) }
+	if err := acc.check(ctx, "alpha"); err != nil {
+		t.Errorf("check() next day = %v, want nil", err)
+	}
+}
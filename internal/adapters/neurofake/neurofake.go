@@ -0,0 +1,209 @@
+package neurofake
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+
+	"github.com/larriantoniy/tg_user_bot/internal/domain"
+)
+
+// Записанные ответы chat-completions API (OpenRouter), см. fixtures/*.json.
+//
+//go:embed fixtures/*.json
+var fixtures embed.FS
+
+const (
+	FixtureComment      = "comment_ok"
+	FixtureCommentImage = "comment_image"
+	FixtureEmptyChoices = "empty_choices"
+	FixtureRateLimited  = "rate_limited"
+	FixtureServerError  = "server_error"
+)
+
+// Reply — один заскриптованный ответ сервера.
+type Reply struct {
+	Status int
+	Header http.Header
+	Body   []byte
+}
+
+// Request — записанный запрос к серверу.
+type Request struct {
+	Method string
+	Path   string
+	Header http.Header
+	Raw    []byte
+	// Body — Raw, разобранный в domain.DefaultNeuroBody (нулевой, если не удалось)
+	Body domain.DefaultNeuroBody
+}
+
+// Server — локальная замена chat-completions endpoint на базе httptest.
+// Ответы выдаются из очереди по одному на запрос; когда очередь пуста,
+// отдаётся ответ по умолчанию (500, если он не задан).
+type Server struct {
+	srv *httptest.Server
+
+	mu       sync.Mutex
+	replies  []Reply
+	fallback *Reply
+	requests []Request
+}
+
+// New поднимает сервер. Адрес для neuro — URL().
+func New() *Server {
+	s := &Server{}
+	s.srv = httptest.NewServer(http.HandlerFunc(s.handle))
+	return s
+}
+
+// URL возвращает адрес endpoint, который подставляется в NeuroAddr.
+func (s *Server) URL() string {
+	return s.srv.URL + "/api/v1/chat/completions"
+}
+
+func (s *Server) Close() {
+	s.srv.Close()
+}
+
+// Enqueue добавляет ответы в очередь.
+func (s *Server) Enqueue(replies ...Reply) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.replies = append(s.replies, replies...)
+}
+
+// SetDefault задаёт ответ, который отдаётся при пустой очереди.
+func (s *Server) SetDefault(r Reply) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.fallback = &r
+}
+
+// Requests возвращает копию всех полученных запросов.
+func (s *Server) Requests() []Request {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Request(nil), s.requests...)
+}
+
+func (s *Server) handle(w http.ResponseWriter, r *http.Request) {
+	raw, _ := io.ReadAll(r.Body)
+
+	req := Request{
+		Method: r.Method,
+		Path:   r.URL.Path,
+		Header: r.Header.Clone(),
+		Raw:    raw,
+	}
+	_ = json.Unmarshal(raw, &req.Body)
+
+	s.mu.Lock()
+	s.requests = append(s.requests, req)
+	reply := Reply{Status: http.StatusInternalServerError, Body: []byte(`{"error":{"code":500,"message":"neurofake: no reply scripted"}}`)}
+	if len(s.replies) > 0 {
+		reply = s.replies[0]
+		s.replies = s.replies[1:]
+	} else if s.fallback != nil {
+		reply = *s.fallback
+	}
+	s.mu.Unlock()
+
+	for k, vs := range reply.Header {
+		for _, v := range vs {
+			w.Header().Add(k, v)
+		}
+	}
+	if w.Header().Get("Content-Type") == "" {
+		w.Header().Set("Content-Type", "application/json")
+	}
+	status := reply.Status
+	if status == 0 {
+		status = http.StatusOK
+	}
+	w.WriteHeader(status)
+	_, _ = w.Write(reply.Body)
+}
+
+// --- готовые ответы ---
+
+// Fixture загружает записанный ответ по имени (без .json).
+func Fixture(name string) ([]byte, error) {
+	data, err := fixtures.ReadFile("fixtures/" + name + ".json")
+	if err != nil {
+		return nil, fmt.Errorf("neurofake: fixture %q: %w", name, err)
+	}
+	return data, nil
+}
+
+// FixtureResponse загружает записанный ответ и разбирает его в domain.NeuroResponse.
+func FixtureResponse(name string) (domain.NeuroResponse, error) {
+	var nr domain.NeuroResponse
+	data, err := Fixture(name)
+	if err != nil {
+		return nr, err
+	}
+	if err := json.Unmarshal(data, &nr); err != nil {
+		return nr, fmt.Errorf("neurofake: fixture %q: %w", name, err)
+	}
+	return nr, nil
+}
+
+// ReplyFixture — 200 с телом из фикстуры. Паникует на неизвестном имени:
+// фикстуры вшиты в бинарь, опечатка в имени — ошибка теста.
+func ReplyFixture(name string) Reply {
+	data, err := Fixture(name)
+	if err != nil {
+		panic(err)
+	}
+	return Reply{Status: http.StatusOK, Body: data}
+}
+
+// ReplyText — 200 с одним choice, содержащим text.
+func ReplyText(text string) Reply {
+	return ReplyJSON(http.StatusOK, domain.NeuroResponse{
+		ID:     "gen-neurofake",
+		Object: "chat.completion",
+		Model:  domain.MistralModel,
+		Choices: []domain.Choice{{
+			Message:      domain.MessageResponse{Role: string(domain.RoleAssistant), Content: text},
+			FinishReason: "stop",
+		}},
+	})
+}
+
+// ReplyJSON — ответ с произвольным JSON-телом.
+func ReplyJSON(status int, v any) Reply {
+	data, err := json.Marshal(v)
+	if err != nil {
+		panic(err)
+	}
+	return Reply{Status: status, Body: data}
+}
+
+// ReplyStatus — ответ с кодом status и телом ошибки из фикстуры, если она есть.
+func ReplyStatus(status int) Reply {
+	var name string
+	switch status {
+	case http.StatusTooManyRequests:
+		name = FixtureRateLimited
+	case http.StatusInternalServerError:
+		name = FixtureServerError
+	}
+	body := []byte(fmt.Sprintf(`{"error":{"code":%d,"message":%q}}`, status, http.StatusText(status)))
+	if name != "" {
+		if data, err := Fixture(name); err == nil {
+			body = data
+		}
+	}
+	return Reply{Status: status, Body: body}
+}
+
+// ReplyMalformed — 200 с телом, которое не является валидным JSON.
+func ReplyMalformed() Reply {
+	return Reply{Status: http.StatusOK, Body: []byte(`{"id":"gen-broken","choices":[{"message":`)}
+}
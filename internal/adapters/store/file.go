@@ -0,0 +1,123 @@
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/larriantoniy/tg_user_bot/internal/domain"
+)
+
+// FileStore — хранилище в одном JSON-файле. Состояние держится в памяти
+// и целиком перезаписывается на диск (через tmp + rename) после каждого изменения.
+type FileStore struct {
+	mu   sync.Mutex
+	path string
+	ttl  time.Duration
+	log  *slog.Logger
+	st   *state
+}
+
+func NewFileStore(path string, ttl time.Duration, log *slog.Logger) (*FileStore, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, fmt.Errorf("mkdir store dir: %w", err)
+	}
+
+	st := newState()
+	data, err := os.ReadFile(path)
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+		// первый запуск
+	case err != nil:
+		return nil, fmt.Errorf("read %s: %w", path, err)
+	default:
+		if err := json.Unmarshal(data, st); err != nil {
+			return nil, fmt.Errorf("unmarshal %s: %w", path, err)
+		}
+		if st.Seen == nil {
+			st.Seen = make(map[string]time.Time)
+		}
+	}
+
+	f := &FileStore{path: path, ttl: ttl, log: log, st: st}
+	if st.evict(time.Now(), ttl) {
+		if err := f.save(); err != nil {
+			return nil, err
+		}
+	}
+	log.Info("File comment store loaded", "path", path, "seen", len(st.Seen), "sent", len(st.Sent))
+	return f, nil
+}
+
+func (f *FileStore) MarkSeen(ctx context.Context, key domain.ThreadKey) (bool, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	now := time.Now()
+	f.st.evict(now, f.ttl)
+	if !f.st.markSeen(key, now, f.ttl) {
+		return false, nil
+	}
+	return true, f.save()
+}
+
+func (f *FileStore) UnmarkSeen(ctx context.Context, key domain.ThreadKey) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	k := seenKey(key)
+	if _, ok := f.st.Seen[k]; !ok {
+		return nil
+	}
+	delete(f.st.Seen, k)
+	return f.save()
+}
+
+func (f *FileStore) AddSent(ctx context.Context, c domain.SentComment) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.st.Sent = append(f.st.Sent, c)
+	f.st.evict(time.Now(), f.ttl)
+	return f.save()
+}
+
+func (f *FileStore) RecentSent(ctx context.Context, session string, limit int) ([]domain.SentComment, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.st.recent(session, limit), nil
+}
+
+func (f *FileStore) RecentSentExcept(ctx context.Context, session string, limit int) ([]domain.SentComment, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.st.recentExcept(session, limit), nil
+}
+
+func (f *FileStore) Close() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.save()
+}
+
+// save вызывается под f.mu
+func (f *FileStore) save() error {
+	data, err := json.Marshal(f.st)
+	if err != nil {
+		return fmt.Errorf("marshal store: %w", err)
+	}
+	tmp := f.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return fmt.Errorf("write %s: %w", tmp, err)
+	}
+	if err := os.Rename(tmp, f.path); err != nil {
+		return fmt.Errorf("rename %s: %w", tmp, err)
+	}
+	return nil
+}
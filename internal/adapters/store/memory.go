@@ -0,0 +1,135 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/larriantoniy/tg_user_bot/internal/domain"
+)
+
+// maxHistory — сколько отправленных комментариев держим всего (по всем сессиям)
+const maxHistory = 1000
+
+// state — общее для memory и file хранилищ состояние, сериализуемое в JSON
+type state struct {
+	Seen map[string]time.Time `json:"seen"` // ключ треда -> момент истечения
+	Sent []domain.SentComment `json:"sent"` // от старых к новым
+}
+
+func newState() *state {
+	return &state{Seen: make(map[string]time.Time)}
+}
+
+func seenKey(k domain.ThreadKey) string {
+	return fmt.Sprintf("%s:%d:%d", k.Session, k.ChatID, k.ThreadID)
+}
+
+// evict выбрасывает истёкшие записи, возвращает true, если что-то удалено
+func (s *state) evict(now time.Time, ttl time.Duration) bool {
+	changed := false
+	for k, exp := range s.Seen {
+		if now.After(exp) {
+			delete(s.Seen, k)
+			changed = true
+		}
+	}
+
+	cut := 0
+	for cut < len(s.Sent) && now.Sub(s.Sent[cut].SentAt) > ttl {
+		cut++
+	}
+	if over := len(s.Sent) - cut - maxHistory; over > 0 {
+		cut += over
+	}
+	if cut > 0 {
+		s.Sent = append([]domain.SentComment(nil), s.Sent[cut:]...)
+		changed = true
+	}
+	return changed
+}
+
+func (s *state) markSeen(key domain.ThreadKey, now time.Time, ttl time.Duration) bool {
+	k := seenKey(key)
+	if exp, ok := s.Seen[k]; ok && !now.After(exp) {
+		return false
+	}
+	s.Seen[k] = now.Add(ttl)
+	return true
+}
+
+func (s *state) recent(session string, limit int) []domain.SentComment {
+	return s.recentFunc(limit, func(c domain.SentComment) bool {
+		return session == "" || c.Session == session
+	})
+}
+
+func (s *state) recentExcept(session string, limit int) []domain.SentComment {
+	return s.recentFunc(limit, func(c domain.SentComment) bool {
+		return c.Session != session
+	})
+}
+
+func (s *state) recentFunc(limit int, keep func(domain.SentComment) bool) []domain.SentComment {
+	if limit <= 0 {
+		return nil
+	}
+	out := make([]domain.SentComment, 0, limit)
+	for i := len(s.Sent) - 1; i >= 0 && len(out) < limit; i-- {
+		if keep(s.Sent[i]) {
+			out = append(out, s.Sent[i])
+		}
+	}
+	return out
+}
+
+// MemoryStore — хранилище в памяти процесса, теряется при рестарте
+type MemoryStore struct {
+	mu  sync.Mutex
+	ttl time.Duration
+	st  *state
+}
+
+func NewMemoryStore(ttl time.Duration) *MemoryStore {
+	return &MemoryStore{ttl: ttl, st: newState()}
+}
+
+func (m *MemoryStore) MarkSeen(ctx context.Context, key domain.ThreadKey) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	now := time.Now()
+	m.st.evict(now, m.ttl)
+	return m.st.markSeen(key, now, m.ttl), nil
+}
+
+func (m *MemoryStore) UnmarkSeen(ctx context.Context, key domain.ThreadKey) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.st.Seen, seenKey(key))
+	return nil
+}
+
+func (m *MemoryStore) AddSent(ctx context.Context, c domain.SentComment) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.st.Sent = append(m.st.Sent, c)
+	m.st.evict(time.Now(), m.ttl)
+	return nil
+}
+
+func (m *MemoryStore) RecentSent(ctx context.Context, session string, limit int) ([]domain.SentComment, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.st.recent(session, limit), nil
+}
+
+func (m *MemoryStore) RecentSentExcept(ctx context.Context, session string, limit int) ([]domain.SentComment, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.st.recentExcept(session, limit), nil
+}
+
+func (m *MemoryStore) Close() error {
+	return nil
+}
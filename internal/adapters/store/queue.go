@@ -0,0 +1,199 @@
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/larriantoniy/tg_user_bot/internal/domain"
+)
+
+// jobs — общее для memory и file очередей состояние
+type jobs map[string]domain.CommentJob
+
+func (j jobs) due(session string, now time.Time, limit int) []domain.CommentJob {
+	return j.filter(limit, func(job domain.CommentJob) bool {
+		return job.Session == session && !job.DueAt.After(now)
+	})
+}
+
+// pending — все задания сессии независимо от DueAt; пустой session — всех сессий
+func (j jobs) pending(session string, limit int) []domain.CommentJob {
+	return j.filter(limit, func(job domain.CommentJob) bool {
+		return session == "" || job.Session == session
+	})
+}
+
+// filter — подходящие задания, самые ранние первыми
+func (j jobs) filter(limit int, keep func(domain.CommentJob) bool) []domain.CommentJob {
+	out := make([]domain.CommentJob, 0)
+	for _, job := range j {
+		if keep(job) {
+			out = append(out, job)
+		}
+	}
+	sort.Slice(out, func(a, b int) bool { return out[a].DueAt.Before(out[b].DueAt) })
+	if limit > 0 && len(out) > limit {
+		out = out[:limit]
+	}
+	return out
+}
+
+func (j jobs) count(session string) int {
+	n := 0
+	for _, job := range j {
+		if job.Session == session {
+			n++
+		}
+	}
+	return n
+}
+
+// MemoryQueue — очередь в памяти процесса, теряется при рестарте
+type MemoryQueue struct {
+	mu   sync.Mutex
+	jobs jobs
+}
+
+func NewMemoryQueue() *MemoryQueue {
+	return &MemoryQueue{jobs: make(jobs)}
+}
+
+func (q *MemoryQueue) Enqueue(ctx context.Context, job domain.CommentJob) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.jobs[job.ID] = job
+	return nil
+}
+
+func (q *MemoryQueue) Due(ctx context.Context, session string, now time.Time, limit int) ([]domain.CommentJob, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.jobs.due(session, now, limit), nil
+}
+
+func (q *MemoryQueue) Remove(ctx context.Context, job domain.CommentJob) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	delete(q.jobs, job.ID)
+	return nil
+}
+
+func (q *MemoryQueue) Pending(ctx context.Context, session string, limit int) ([]domain.CommentJob, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.jobs.pending(session, limit), nil
+}
+
+func (q *MemoryQueue) Len(ctx context.Context, session string) (int, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.jobs.count(session), nil
+}
+
+func (q *MemoryQueue) Close() error {
+	return nil
+}
+
+// FileQueue — очередь в JSON-файле, перезаписывается после каждого изменения
+type FileQueue struct {
+	mu   sync.Mutex
+	path string
+	jobs jobs
+}
+
+func NewFileQueue(path string, log *slog.Logger) (*FileQueue, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, fmt.Errorf("mkdir queue dir: %w", err)
+	}
+
+	var list []domain.CommentJob
+	data, err := os.ReadFile(path)
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+		// первый запуск
+	case err != nil:
+		return nil, fmt.Errorf("read %s: %w", path, err)
+	default:
+		if err := json.Unmarshal(data, &list); err != nil {
+			return nil, fmt.Errorf("unmarshal %s: %w", path, err)
+		}
+	}
+
+	q := &FileQueue{path: path, jobs: make(jobs, len(list))}
+	for _, job := range list {
+		q.jobs[job.ID] = job
+	}
+	log.Info("File comment queue loaded", "path", path, "pending", len(q.jobs))
+	return q, nil
+}
+
+func (q *FileQueue) Enqueue(ctx context.Context, job domain.CommentJob) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.jobs[job.ID] = job
+	return q.save()
+}
+
+func (q *FileQueue) Due(ctx context.Context, session string, now time.Time, limit int) ([]domain.CommentJob, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.jobs.due(session, now, limit), nil
+}
+
+func (q *FileQueue) Remove(ctx context.Context, job domain.CommentJob) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if _, ok := q.jobs[job.ID]; !ok {
+		return nil
+	}
+	delete(q.jobs, job.ID)
+	return q.save()
+}
+
+func (q *FileQueue) Pending(ctx context.Context, session string, limit int) ([]domain.CommentJob, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.jobs.pending(session, limit), nil
+}
+
+func (q *FileQueue) Len(ctx context.Context, session string) (int, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.jobs.count(session), nil
+}
+
+func (q *FileQueue) Close() error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.save()
+}
+
+// save вызывается под q.mu
+func (q *FileQueue) save() error {
+	list := make([]domain.CommentJob, 0, len(q.jobs))
+	for _, job := range q.jobs {
+		list = append(list, job)
+	}
+	sort.Slice(list, func(a, b int) bool { return list[a].DueAt.Before(list[b].DueAt) })
+
+	data, err := json.Marshal(list)
+	if err != nil {
+		return fmt.Errorf("marshal queue: %w", err)
+	}
+	tmp := q.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return fmt.Errorf("write %s: %w", tmp, err)
+	}
+	if err := os.Rename(tmp, q.path); err != nil {
+		return fmt.Errorf("rename %s: %w", tmp, err)
+	}
+	return nil
+}
@@ -0,0 +1,137 @@
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/larriantoniy/tg_user_bot/internal/domain"
+	"github.com/redis/go-redis/v9"
+)
+
+// RedisStore — хранилище в Redis. Отметки тредов — ключи с TTL (SET NX),
+// история — sorted set по времени отправки: общий и по каждой сессии.
+type RedisStore struct {
+	rdb    *redis.Client
+	prefix string
+	ttl    time.Duration
+}
+
+func NewRedisStore(ctx context.Context, addr, password string, db int, prefix string, ttl time.Duration) (*RedisStore, error) {
+	rdb := redis.NewClient(&redis.Options{
+		Addr:     addr,
+		Password: password,
+		DB:       db,
+	})
+	if err := rdb.Ping(ctx).Err(); err != nil {
+		_ = rdb.Close()
+		return nil, fmt.Errorf("redis ping %s: %w", addr, err)
+	}
+	if prefix == "" {
+		prefix = "tg_warm_bot"
+	}
+	return &RedisStore{rdb: rdb, prefix: prefix, ttl: ttl}, nil
+}
+
+func (r *RedisStore) seenKey(k domain.ThreadKey) string {
+	return fmt.Sprintf("%s:seen:%s", r.prefix, seenKey(k))
+}
+
+func (r *RedisStore) sentKey(session string) string {
+	if session == "" {
+		return r.prefix + ":sent"
+	}
+	return r.prefix + ":sent:" + session
+}
+
+func (r *RedisStore) MarkSeen(ctx context.Context, key domain.ThreadKey) (bool, error) {
+	ok, err := r.rdb.SetNX(ctx, r.seenKey(key), 1, r.ttl).Result()
+	if err != nil {
+		return false, fmt.Errorf("redis setnx: %w", err)
+	}
+	return ok, nil
+}
+
+func (r *RedisStore) UnmarkSeen(ctx context.Context, key domain.ThreadKey) error {
+	if err := r.rdb.Del(ctx, r.seenKey(key)).Err(); err != nil {
+		return fmt.Errorf("redis del: %w", err)
+	}
+	return nil
+}
+
+func (r *RedisStore) AddSent(ctx context.Context, c domain.SentComment) error {
+	data, err := json.Marshal(c)
+	if err != nil {
+		return fmt.Errorf("marshal comment: %w", err)
+	}
+	member := redis.Z{Score: float64(c.SentAt.UnixMilli()), Member: data}
+	minScore := strconv.FormatInt(time.Now().Add(-r.ttl).UnixMilli(), 10)
+
+	_, err = r.rdb.TxPipelined(ctx, func(p redis.Pipeliner) error {
+		for _, key := range []string{r.sentKey(""), r.sentKey(c.Session)} {
+			p.ZAdd(ctx, key, member)
+			p.ZRemRangeByScore(ctx, key, "-inf", "("+minScore)
+			p.ZRemRangeByRank(ctx, key, 0, -maxHistory-1)
+			p.Expire(ctx, key, r.ttl)
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("redis add sent: %w", err)
+	}
+	return nil
+}
+
+func (r *RedisStore) RecentSent(ctx context.Context, session string, limit int) ([]domain.SentComment, error) {
+	if limit <= 0 {
+		return nil, nil
+	}
+	raw, err := r.rdb.ZRevRange(ctx, r.sentKey(session), 0, int64(limit-1)).Result()
+	if err != nil {
+		return nil, fmt.Errorf("redis recent sent: %w", err)
+	}
+	return decodeSent(raw, nil, limit, nil), nil
+}
+
+// RecentSentExcept листает общую историю страницами по limit, пока не наберёт limit чужих
+func (r *RedisStore) RecentSentExcept(ctx context.Context, session string, limit int) ([]domain.SentComment, error) {
+	if limit <= 0 {
+		return nil, nil
+	}
+	out := make([]domain.SentComment, 0, limit)
+	for start := int64(0); len(out) < limit; start += int64(limit) {
+		raw, err := r.rdb.ZRevRange(ctx, r.sentKey(""), start, start+int64(limit)-1).Result()
+		if err != nil {
+			return nil, fmt.Errorf("redis recent sent: %w", err)
+		}
+		out = decodeSent(raw, out, limit, func(c domain.SentComment) bool { return c.Session != session })
+		if len(raw) < limit {
+			break
+		}
+	}
+	return out, nil
+}
+
+// decodeSent дописывает в out комментарии, прошедшие keep (nil — все), пока их меньше limit
+func decodeSent(raw []string, out []domain.SentComment, limit int, keep func(domain.SentComment) bool) []domain.SentComment {
+	for _, item := range raw {
+		if len(out) >= limit {
+			break
+		}
+		var c domain.SentComment
+		if err := json.Unmarshal([]byte(item), &c); err != nil {
+			continue
+		}
+		if keep != nil && !keep(c) {
+			continue
+		}
+		out = append(out, c)
+	}
+	return out
+}
+
+func (r *RedisStore) Close() error {
+	return r.rdb.Close()
+}
@@ -0,0 +1,159 @@
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/larriantoniy/tg_user_bot/internal/domain"
+	"github.com/redis/go-redis/v9"
+)
+
+// RedisQueue — очередь в Redis: sorted set id по DueAt на каждую сессию
+// и общий hash id -> задание в JSON
+type RedisQueue struct {
+	rdb    *redis.Client
+	prefix string
+}
+
+func NewRedisQueue(ctx context.Context, addr, password string, db int, prefix string) (*RedisQueue, error) {
+	rdb := redis.NewClient(&redis.Options{
+		Addr:     addr,
+		Password: password,
+		DB:       db,
+	})
+	if err := rdb.Ping(ctx).Err(); err != nil {
+		_ = rdb.Close()
+		return nil, fmt.Errorf("redis ping %s: %w", addr, err)
+	}
+	if prefix == "" {
+		prefix = "tg_warm_bot"
+	}
+	return &RedisQueue{rdb: rdb, prefix: prefix}, nil
+}
+
+func (r *RedisQueue) indexKey(session string) string {
+	return r.prefix + ":queue:" + session
+}
+
+func (r *RedisQueue) jobsKey() string {
+	return r.prefix + ":queue_jobs"
+}
+
+func (r *RedisQueue) Enqueue(ctx context.Context, job domain.CommentJob) error {
+	data, err := json.Marshal(job)
+	if err != nil {
+		return fmt.Errorf("marshal job: %w", err)
+	}
+	_, err = r.rdb.TxPipelined(ctx, func(p redis.Pipeliner) error {
+		p.HSet(ctx, r.jobsKey(), job.ID, data)
+		p.ZAdd(ctx, r.indexKey(job.Session), redis.Z{Score: float64(job.DueAt.UnixMilli()), Member: job.ID})
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("redis enqueue: %w", err)
+	}
+	return nil
+}
+
+func (r *RedisQueue) Due(ctx context.Context, session string, now time.Time, limit int) ([]domain.CommentJob, error) {
+	count := int64(limit)
+	if count <= 0 {
+		count = -1
+	}
+	ids, err := r.rdb.ZRangeByScore(ctx, r.indexKey(session), &redis.ZRangeBy{
+		Min:   "-inf",
+		Max:   strconv.FormatInt(now.UnixMilli(), 10),
+		Count: count,
+	}).Result()
+	if err != nil {
+		return nil, fmt.Errorf("redis due: %w", err)
+	}
+	return r.load(ctx, session, ids)
+}
+
+// Pending по сессии идёт по её индексу, а по всем сессиям читает весь hash заданий
+func (r *RedisQueue) Pending(ctx context.Context, session string, limit int) ([]domain.CommentJob, error) {
+	if session != "" {
+		stop := int64(limit - 1)
+		if limit <= 0 {
+			stop = -1
+		}
+		ids, err := r.rdb.ZRange(ctx, r.indexKey(session), 0, stop).Result()
+		if err != nil {
+			return nil, fmt.Errorf("redis pending: %w", err)
+		}
+		return r.load(ctx, session, ids)
+	}
+
+	raw, err := r.rdb.HVals(ctx, r.jobsKey()).Result()
+	if err != nil {
+		return nil, fmt.Errorf("redis pending hvals: %w", err)
+	}
+	out := make([]domain.CommentJob, 0, len(raw))
+	for _, s := range raw {
+		var job domain.CommentJob
+		if err := json.Unmarshal([]byte(s), &job); err != nil {
+			continue
+		}
+		out = append(out, job)
+	}
+	sort.Slice(out, func(a, b int) bool { return out[a].DueAt.Before(out[b].DueAt) })
+	if limit > 0 && len(out) > limit {
+		out = out[:limit]
+	}
+	return out, nil
+}
+
+// load читает задания по id из индекса сессии, сохраняя порядок
+func (r *RedisQueue) load(ctx context.Context, session string, ids []string) ([]domain.CommentJob, error) {
+	if len(ids) == 0 {
+		return nil, nil
+	}
+	raw, err := r.rdb.HMGet(ctx, r.jobsKey(), ids...).Result()
+	if err != nil {
+		return nil, fmt.Errorf("redis hmget jobs: %w", err)
+	}
+	out := make([]domain.CommentJob, 0, len(raw))
+	for i, v := range raw {
+		s, ok := v.(string)
+		if !ok {
+			// индекс без тела — подчищаем
+			r.rdb.ZRem(ctx, r.indexKey(session), ids[i])
+			continue
+		}
+		var job domain.CommentJob
+		if err := json.Unmarshal([]byte(s), &job); err != nil {
+			continue
+		}
+		out = append(out, job)
+	}
+	return out, nil
+}
+
+func (r *RedisQueue) Remove(ctx context.Context, job domain.CommentJob) error {
+	_, err := r.rdb.TxPipelined(ctx, func(p redis.Pipeliner) error {
+		p.ZRem(ctx, r.indexKey(job.Session), job.ID)
+		p.HDel(ctx, r.jobsKey(), job.ID)
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("redis remove: %w", err)
+	}
+	return nil
+}
+
+func (r *RedisQueue) Len(ctx context.Context, session string) (int, error) {
+	n, err := r.rdb.ZCard(ctx, r.indexKey(session)).Result()
+	if err != nil {
+		return 0, fmt.Errorf("redis queue len: %w", err)
+	}
+	return int(n), nil
+}
+
+func (r *RedisQueue) Close() error {
+	return r.rdb.Close()
+}
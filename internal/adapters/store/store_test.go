@@ -0,0 +1,185 @@
+package store
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/larriantoniy/tg_user_bot/internal/domain"
+	"github.com/larriantoniy/tg_user_bot/internal/ports"
+)
+
+func discardLogger() *slog.Logger {
+	return slog.New(slog.NewTextHandler(io.Discard, nil))
+}
+
+func TestFileStorePersistsAcrossReopen(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "comment_store.json")
+	key := domain.ThreadKey{Session: "s1", ChatID: -100, ThreadID: 7}
+
+	fs, err := NewFileStore(path, time.Hour, discardLogger())
+	if err != nil {
+		t.Fatalf("NewFileStore() error = %v", err)
+	}
+	if ok, err := fs.MarkSeen(ctx, key); err != nil || !ok {
+		t.Fatalf("MarkSeen() = %v, %v; want true, nil", ok, err)
+	}
+	if err := fs.AddSent(ctx, domain.SentComment{Session: "s1", ChatID: -100, ThreadID: 7, Text: "first", SentAt: time.Now()}); err != nil {
+		t.Fatalf("AddSent() error = %v", err)
+	}
+	if err := fs.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+
+	reopened, err := NewFileStore(path, time.Hour, discardLogger())
+	if err != nil {
+		t.Fatalf("NewFileStore() reopen error = %v", err)
+	}
+	if ok, _ := reopened.MarkSeen(ctx, key); ok {
+		t.Error("MarkSeen() after reopen = true, want false")
+	}
+	// другая сессия в том же треде — это другой ключ
+	if ok, _ := reopened.MarkSeen(ctx, domain.ThreadKey{Session: "s2", ChatID: -100, ThreadID: 7}); !ok {
+		t.Error("MarkSeen() for another session = false, want true")
+	}
+	if err := reopened.UnmarkSeen(ctx, key); err != nil {
+		t.Fatalf("UnmarkSeen() error = %v", err)
+	}
+	if ok, _ := reopened.MarkSeen(ctx, key); !ok {
+		t.Error("MarkSeen() after UnmarkSeen = false, want true")
+	}
+	sent, _ := reopened.RecentSent(ctx, "s1", 10)
+	if len(sent) != 1 || sent[0].Text != "first" {
+		t.Errorf("RecentSent() after reopen = %+v, want one comment", sent)
+	}
+}
+
+func TestMemoryStoreTTLEviction(t *testing.T) {
+	ctx := context.Background()
+	m := NewMemoryStore(20 * time.Millisecond)
+	key := domain.ThreadKey{Session: "s1", ChatID: -100, ThreadID: 7}
+
+	if ok, _ := m.MarkSeen(ctx, key); !ok {
+		t.Fatal("first MarkSeen() = false, want true")
+	}
+	if ok, _ := m.MarkSeen(ctx, key); ok {
+		t.Fatal("second MarkSeen() = true, want false")
+	}
+	_ = m.AddSent(ctx, domain.SentComment{Session: "s1", Text: "old", SentAt: time.Now()})
+
+	time.Sleep(30 * time.Millisecond)
+
+	if ok, _ := m.MarkSeen(ctx, key); !ok {
+		t.Error("MarkSeen() after TTL = false, want true")
+	}
+	if len(m.st.Seen) != 1 {
+		t.Errorf("seen entries = %d, want 1 (expired ones evicted)", len(m.st.Seen))
+	}
+	if sent, _ := m.RecentSent(ctx, "", 10); len(sent) != 0 {
+		t.Errorf("RecentSent() after TTL = %+v, want empty", sent)
+	}
+}
+
+func TestRecentSentOrderAndFilter(t *testing.T) {
+	ctx := context.Background()
+	m := NewMemoryStore(time.Hour)
+	now := time.Now()
+	for i, c := range []domain.SentComment{
+		{Session: "a", Text: "a1"},
+		{Session: "b", Text: "b1"},
+		{Session: "a", Text: "a2"},
+	} {
+		c.SentAt = now.Add(time.Duration(i) * time.Second)
+		_ = m.AddSent(ctx, c)
+	}
+
+	got, _ := m.RecentSent(ctx, "a", 10)
+	if len(got) != 2 || got[0].Text != "a2" || got[1].Text != "a1" {
+		t.Errorf("RecentSent(a) = %+v, want [a2 a1]", got)
+	}
+	all, _ := m.RecentSent(ctx, "", 2)
+	if len(all) != 2 || all[0].Text != "a2" || all[1].Text != "b1" {
+		t.Errorf("RecentSent(all, 2) = %+v, want [a2 b1]", all)
+	}
+	// свежие комментарии a не занимают место в выборке чужих
+	others, _ := m.RecentSentExcept(ctx, "a", 1)
+	if len(others) != 1 || others[0].Text != "b1" {
+		t.Errorf("RecentSentExcept(a, 1) = %+v, want [b1]", others)
+	}
+}
+
+func TestQueuePending(t *testing.T) {
+	ctx := context.Background()
+	fq, err := NewFileQueue(filepath.Join(t.TempDir(), "queue.json"), discardLogger())
+	if err != nil {
+		t.Fatalf("NewFileQueue() error = %v", err)
+	}
+	now := time.Now()
+	for name, q := range map[string]ports.CommentQueue{"memory": NewMemoryQueue(), "file": fq} {
+		for _, job := range []domain.CommentJob{
+			{ID: "a2", Session: "a", DueAt: now.Add(2 * time.Hour)},
+			{ID: "b1", Session: "b", DueAt: now.Add(time.Hour)},
+			{ID: "a1", Session: "a", DueAt: now.Add(-time.Minute)},
+		} {
+			if err := q.Enqueue(ctx, job); err != nil {
+				t.Fatalf("%s: Enqueue() error = %v", name, err)
+			}
+		}
+
+		// в отличие от Due — и те, до которых ещё далеко
+		got, _ := q.Pending(ctx, "a", 0)
+		if len(got) != 2 || got[0].ID != "a1" || got[1].ID != "a2" {
+			t.Errorf("%s: Pending(a) = %+v, want [a1 a2]", name, got)
+		}
+		all, _ := q.Pending(ctx, "", 2)
+		if len(all) != 2 || all[0].ID != "a1" || all[1].ID != "b1" {
+			t.Errorf("%s: Pending(all, 2) = %+v, want [a1 b1]", name, all)
+		}
+		if n, _ := q.Len(ctx, "a"); n != 2 {
+			t.Errorf("%s: Len(a) = %d, want 2", name, n)
+		}
+	}
+}
+
+func TestFileUsageAggregatesAndPersists(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "neuro_usage.json")
+	today := time.Now().Format(domain.UsageDayLayout)
+	old := time.Now().Add(-100 * 24 * time.Hour).Format(domain.UsageDayLayout)
+	row := func(day, channel string, cost float64) domain.UsageRecord {
+		return domain.UsageRecord{Day: day, Session: "s1", Channel: channel, Provider: "openai", Model: "m",
+			UsageTotals: domain.UsageTotals{Requests: 1, PromptTokens: 10, CompletionTokens: 2, Cost: cost}}
+	}
+
+	fu, err := NewFileUsage(path, discardLogger())
+	if err != nil {
+		t.Fatalf("NewFileUsage() error = %v", err)
+	}
+	for _, rec := range []domain.UsageRecord{row(today, "a", 0.5), row(today, "a", 0.25), row(today, "b", 1), row(old, "a", 9)} {
+		if err := fu.Add(ctx, rec); err != nil {
+			t.Fatalf("Add() error = %v", err)
+		}
+	}
+	if err := fu.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+
+	reopened, err := NewFileUsage(path, discardLogger())
+	if err != nil {
+		t.Fatalf("NewFileUsage() reopen error = %v", err)
+	}
+	rows, _ := reopened.Since(ctx, "")
+	if len(rows) != 2 {
+		t.Fatalf("rows after reopen = %+v, want 2 (same key merged, old day evicted)", rows)
+	}
+	if a := rows[0]; a.Channel != "a" || a.Requests != 2 || a.PromptTokens != 20 || a.Cost != 0.75 {
+		t.Errorf("merged row = %+v", a)
+	}
+	if rows, _ := reopened.Since(ctx, "9999-01-01"); len(rows) != 0 {
+		t.Errorf("Since(future) = %+v, want empty", rows)
+	}
+}
@@ -0,0 +1,164 @@
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/larriantoniy/tg_user_bot/internal/domain"
+	"github.com/larriantoniy/tg_user_bot/internal/ports"
+)
+
+var (
+	_ ports.UsageLedger = (*MemoryUsage)(nil)
+	_ ports.UsageLedger = (*FileUsage)(nil)
+)
+
+// usageRetention — дневные строки старше выбрасываются: хватает на текущий и прошлый месяц
+const usageRetention = 62 * 24 * time.Hour
+
+// usageRows — общее для memory и file ledger состояние: ключ строки -> строка
+type usageRows map[string]domain.UsageRecord
+
+func (u usageRows) add(rec domain.UsageRecord) {
+	k := rec.Key()
+	row, ok := u[k]
+	if !ok {
+		u[k] = rec
+		return
+	}
+	row.Add(rec.UsageTotals)
+	u[k] = row
+}
+
+func (u usageRows) since(day string) []domain.UsageRecord {
+	out := make([]domain.UsageRecord, 0, len(u))
+	for _, row := range u {
+		if row.Day >= day {
+			out = append(out, row)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Key() < out[j].Key() })
+	return out
+}
+
+// evict выбрасывает строки старше usageRetention, возвращает true, если что-то удалено
+func (u usageRows) evict(now time.Time) bool {
+	oldest := now.Add(-usageRetention).Format(domain.UsageDayLayout)
+	changed := false
+	for k, row := range u {
+		if row.Day < oldest {
+			delete(u, k)
+			changed = true
+		}
+	}
+	return changed
+}
+
+// MemoryUsage — ledger в памяти процесса; после рестарта расход считается с нуля
+type MemoryUsage struct {
+	mu   sync.Mutex
+	rows usageRows
+}
+
+func NewMemoryUsage() *MemoryUsage {
+	return &MemoryUsage{rows: make(usageRows)}
+}
+
+func (m *MemoryUsage) Add(ctx context.Context, rec domain.UsageRecord) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.rows.add(rec)
+	m.rows.evict(time.Now())
+	return nil
+}
+
+func (m *MemoryUsage) Since(ctx context.Context, day string) ([]domain.UsageRecord, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.rows.since(day), nil
+}
+
+func (m *MemoryUsage) Close() error { return nil }
+
+// FileUsage — ledger в JSON-файле, перезаписывается целиком после каждого запроса к LLM,
+// чтобы лимиты расходов переживали рестарт
+type FileUsage struct {
+	mu   sync.Mutex
+	path string
+	rows usageRows
+}
+
+func NewFileUsage(path string, log *slog.Logger) (*FileUsage, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, fmt.Errorf("mkdir usage dir: %w", err)
+	}
+
+	var list []domain.UsageRecord
+	data, err := os.ReadFile(path)
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+		// первый запуск
+	case err != nil:
+		return nil, fmt.Errorf("read %s: %w", path, err)
+	default:
+		if err := json.Unmarshal(data, &list); err != nil {
+			return nil, fmt.Errorf("unmarshal %s: %w", path, err)
+		}
+	}
+
+	f := &FileUsage{path: path, rows: make(usageRows, len(list))}
+	for _, rec := range list {
+		f.rows.add(rec)
+	}
+	if f.rows.evict(time.Now()) {
+		if err := f.save(); err != nil {
+			return nil, err
+		}
+	}
+	log.Info("File usage ledger loaded", "path", path, "rows", len(f.rows))
+	return f, nil
+}
+
+func (f *FileUsage) Add(ctx context.Context, rec domain.UsageRecord) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.rows.add(rec)
+	f.rows.evict(time.Now())
+	return f.save()
+}
+
+func (f *FileUsage) Since(ctx context.Context, day string) ([]domain.UsageRecord, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.rows.since(day), nil
+}
+
+func (f *FileUsage) Close() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.save()
+}
+
+// save вызывается под f.mu
+func (f *FileUsage) save() error {
+	data, err := json.Marshal(f.rows.since(""))
+	if err != nil {
+		return fmt.Errorf("marshal usage: %w", err)
+	}
+	tmp := f.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return fmt.Errorf("write %s: %w", tmp, err)
+	}
+	if err := os.Rename(tmp, f.path); err != nil {
+		return fmt.Errorf("rename %s: %w", tmp, err)
+	}
+	return nil
+}
@@ -0,0 +1,82 @@
+package tg
+
+import (
+	"sort"
+	"time"
+
+	"github.com/larriantoniy/tg_user_bot/internal/domain"
+	"github.com/zelenin/go-tdlib/client"
+)
+
+// albumWindow — сколько ждать следующее сообщение альбома после предыдущего.
+// TDLib присылает части альбома почти одновременно, секунды хватает с запасом.
+const albumWindow = 1500 * time.Millisecond
+
+type albumKey struct {
+	chatID  int64
+	albumID client.JsonInt64
+}
+
+type album struct {
+	chatID int64
+	msgIDs []int64
+	due    time.Time
+}
+
+// albumBuffer копит сообщения альбомов каналов, пока не кончится окно ожидания
+type albumBuffer struct {
+	window  time.Duration
+	pending map[albumKey]*album
+}
+
+func newAlbumBuffer(window time.Duration) *albumBuffer {
+	return &albumBuffer{window: window, pending: make(map[albumKey]*album)}
+}
+
+// add добавляет сообщение; окно отсчитывается от последнего пришедшего
+func (b *albumBuffer) add(m *client.Message, now time.Time) {
+	key := albumKey{chatID: m.ChatId, albumID: m.MediaAlbumId}
+	a, ok := b.pending[key]
+	if !ok {
+		a = &album{chatID: m.ChatId}
+		b.pending[key] = a
+	}
+	a.msgIDs = append(a.msgIDs, m.Id)
+	a.due = now.Add(b.window)
+}
+
+// next — когда истекает ближайшее окно; false — ждать нечего
+func (b *albumBuffer) next() (time.Time, bool) {
+	var next time.Time
+	for _, a := range b.pending {
+		if next.IsZero() || a.due.Before(next) {
+			next = a.due
+		}
+	}
+	return next, !next.IsZero()
+}
+
+// due забирает собранные альбомы; сообщения в каждом по порядку id
+func (b *albumBuffer) due(now time.Time) []album {
+	var out []album
+	for key, a := range b.pending {
+		if now.Before(a.due) {
+			continue
+		}
+		delete(b.pending, key)
+		sort.Slice(a.msgIDs, func(i, j int) bool { return a.msgIDs[i] < a.msgIDs[j] })
+		out = append(out, *a)
+	}
+	return out
+}
+
+// processAlbum обрабатывает альбом как один пост: тред ищется один раз, по первому сообщению
+func (t *TelegramClient) processAlbum(out chan domain.Message, a album) {
+	if _, err := t.processChannelPost(out, a.chatID, a.msgIDs...); err != nil {
+		t.logger.Error("Error process media album",
+			"chat_id", a.chatID,
+			"size", len(a.msgIDs),
+			"error", err,
+		)
+	}
+}
@@ -0,0 +1,43 @@
+package tg
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/zelenin/go-tdlib/client"
+)
+
+func TestAlbumBuffer(t *testing.T) {
+	b := newAlbumBuffer(time.Second)
+	start := time.Now()
+	msg := func(chatID, id int64, album client.JsonInt64) *client.Message {
+		return &client.Message{ChatId: chatID, Id: id, MediaAlbumId: album}
+	}
+
+	if _, ok := b.next(); ok {
+		t.Fatal("next() on empty buffer = true")
+	}
+	b.add(msg(-100, 12, 7), start)
+	b.add(msg(-100, 11, 7), start.Add(300*time.Millisecond))
+	b.add(msg(-200, 5, 7), start.Add(500*time.Millisecond)) // тот же id альбома в другом канале
+
+	if next, ok := b.next(); !ok || !next.Equal(start.Add(1300*time.Millisecond)) {
+		t.Errorf("next() = %v, %v; want window from the last album item", next, ok)
+	}
+	if got := b.due(start.Add(1200 * time.Millisecond)); len(got) != 0 {
+		t.Errorf("due() before window end = %+v, want nothing", got)
+	}
+
+	got := b.due(start.Add(1300 * time.Millisecond))
+	if len(got) != 1 || got[0].chatID != -100 || !reflect.DeepEqual(got[0].msgIDs, []int64{11, 12}) {
+		t.Errorf("due() = %+v, want album of -100 with messages 11, 12", got)
+	}
+	got = b.due(start.Add(2 * time.Second))
+	if len(got) != 1 || got[0].chatID != -200 {
+		t.Errorf("due() = %+v, want album of -200", got)
+	}
+	if _, ok := b.next(); ok {
+		t.Error("next() after all albums are due = true")
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"log/slog"
 	"net"
+	"strconv"
 	"time"
 
 	"github.com/larriantoniy/tg_user_bot/internal/ports"
@@ -73,7 +74,7 @@ func checkProxy(logger *slog.Logger, proxyCfg *ports.ProxyConfig) {
 
 	// IPv4 literal
 	if isIPv4Literal(host) {
-		addr4 := fmt.Sprintf("%s:%d", host, port)
+		addr4 := net.JoinHostPort(host, strconv.Itoa(int(port)))
 		logger.Info("checking IPv4 proxy...", "addr", addr4)
 
 		conn4, err4 := net.DialTimeout("tcp4", addr4, 10*time.Second)
@@ -98,7 +99,7 @@ func checkProxy(logger *slog.Logger, proxyCfg *ports.ProxyConfig) {
 		logger.Warn("proxy IPv6 via hostname failed, trying IPv4", "error_v6", err6)
 	}
 
-	addr4 := fmt.Sprintf("%s:%d", host, port)
+	addr4 := net.JoinHostPort(host, strconv.Itoa(int(port)))
 	if conn4, err4 := net.DialTimeout("tcp4", addr4, 10*time.Second); err4 == nil {
 		_ = conn4.Close()
 		logger.Info("proxy reachable on IPv4 via hostname", "addr_v4", addr4)
@@ -0,0 +1,158 @@
+package tg
+
+import (
+	"time"
+	"unicode/utf16"
+
+	"github.com/larriantoniy/tg_user_bot/internal/domain"
+	"github.com/zelenin/go-tdlib/client"
+)
+
+// postMetadata заполняет метаданные поста, которые не требуют запросов к TDLib.
+// post — сообщение с подписью (или первое сообщение альбома), albumSize — сообщений в посте.
+func postMetadata(msg *domain.Message, post *client.Message, first *client.Message, albumSize int) {
+	msg.PostID = first.Id
+	msg.PostDate = time.Unix(int64(post.Date), 0)
+	msg.ContentType = contentType(post.Content)
+	if post.MediaAlbumId != 0 {
+		msg.AlbumID = int64(post.MediaAlbumId)
+		msg.AlbumSize = albumSize
+		if albumSize > 1 {
+			msg.ContentType = domain.ContentAlbum
+		}
+	}
+	msg.Entities = textEntities(formattedText(post.Content))
+	if info := post.InteractionInfo; info != nil {
+		msg.Views = int(info.ViewCount)
+		msg.Forwards = int(info.ForwardCount)
+		msg.Reactions = reactionCount(info.Reactions)
+	}
+	msg.ForwardOrigin = forwardOrigin(post.ForwardInfo)
+}
+
+// contentType — вид содержимого сообщения для Message.ContentType
+func contentType(content client.MessageContent) string {
+	switch content.(type) {
+	case *client.MessageText:
+		return domain.ContentText
+	case *client.MessagePhoto:
+		return domain.ContentPhoto
+	case *client.MessageVideo:
+		return domain.ContentVideo
+	case *client.MessageAnimation:
+		return domain.ContentAnimation
+	case *client.MessageDocument:
+		return domain.ContentDocument
+	case *client.MessageAudio:
+		return domain.ContentAudio
+	case *client.MessageVoiceNote:
+		return domain.ContentVoice
+	case *client.MessagePoll:
+		return domain.ContentPoll
+	}
+	return domain.ContentOther
+}
+
+// textEntities достаёт из разметки ссылки, хэштеги и упоминания; остальная разметка (жирный и т.п.) не нужна.
+// Смещения TDLib считаются в UTF-16, поэтому текст режем по ним, а не по байтам.
+func textEntities(ft *client.FormattedText) []domain.TextEntity {
+	if ft == nil || len(ft.Entities) == 0 {
+		return nil
+	}
+	units := utf16.Encode([]rune(ft.Text))
+	var out []domain.TextEntity
+	for _, e := range ft.Entities {
+		if e == nil {
+			continue
+		}
+		var fragment string
+		if start, end := int(e.Offset), int(e.Offset+e.Length); start >= 0 && start <= end && end <= len(units) {
+			fragment = string(utf16.Decode(units[start:end]))
+		}
+		te := domain.TextEntity{Text: fragment}
+		switch et := e.Type.(type) {
+		case *client.TextEntityTypeUrl:
+			te.Type, te.URL = domain.EntityURL, fragment
+		case *client.TextEntityTypeTextUrl:
+			te.Type, te.URL = domain.EntityTextURL, et.Url
+		case *client.TextEntityTypeEmailAddress:
+			te.Type = domain.EntityEmail
+		case *client.TextEntityTypeHashtag:
+			te.Type = domain.EntityHashtag
+		case *client.TextEntityTypeCashtag:
+			te.Type = domain.EntityCashtag
+		case *client.TextEntityTypeMention:
+			te.Type = domain.EntityMention
+		case *client.TextEntityTypeMentionName:
+			te.Type, te.UserID = domain.EntityMentionName, et.UserId
+		default:
+			continue
+		}
+		out = append(out, te)
+	}
+	return out
+}
+
+// reactionCount — сколько всего реакций под постом, всех видов вместе
+func reactionCount(r *client.MessageReactions) int {
+	if r == nil {
+		return 0
+	}
+	total := 0
+	for _, reaction := range r.Reactions {
+		if reaction != nil {
+			total += int(reaction.TotalCount)
+		}
+	}
+	return total
+}
+
+// forwardOrigin — откуда репост; название канала или чата дописывает вызывающий, тут нужен запрос к TDLib
+func forwardOrigin(fi *client.MessageForwardInfo) *domain.ForwardOrigin {
+	if fi == nil {
+		return nil
+	}
+	origin := &domain.ForwardOrigin{Date: time.Unix(int64(fi.Date), 0)}
+	switch o := fi.Origin.(type) {
+	case *client.MessageOriginChannel:
+		origin.Kind, origin.ChatID, origin.MessageID = domain.ForwardFromChannel, o.ChatId, o.MessageId
+	case *client.MessageOriginChat:
+		origin.Kind, origin.ChatID = domain.ForwardFromChat, o.SenderChatId
+	case *client.MessageOriginUser:
+		origin.Kind, origin.ChatID = domain.ForwardFromUser, o.SenderUserId
+	case *client.MessageOriginHiddenUser:
+		origin.Kind, origin.Name = domain.ForwardFromHidden, o.SenderName
+	}
+	return origin
+}
+
+// channelUsername — основной username канала без @; пусто у закрытых каналов и при ошибке
+func (t *TelegramClient) channelUsername(chat *client.Chat) string {
+	sg, ok := chat.Type.(*client.ChatTypeSupergroup)
+	if !ok {
+		return ""
+	}
+	info, err := t.client.GetSupergroup(&client.GetSupergroupRequest{SupergroupId: sg.SupergroupId})
+	if err != nil {
+		t.logger.Debug("GetSupergroup failed", "chat_id", chat.Id, "error", err)
+		return ""
+	}
+	if info.Usernames == nil || len(info.Usernames.ActiveUsernames) == 0 {
+		return ""
+	}
+	return info.Usernames.ActiveUsernames[0]
+}
+
+// postLink — ссылка на пост канала; у альбома — на весь альбом
+func (t *TelegramClient) postLink(chatID, msgID int64, album bool) string {
+	link, err := t.client.GetMessageLink(&client.GetMessageLinkRequest{
+		ChatId:    chatID,
+		MessageId: msgID,
+		ForAlbum:  album,
+	})
+	if err != nil {
+		t.logger.Debug("GetMessageLink failed", "chat_id", chatID, "msg_id", msgID, "error", err)
+		return ""
+	}
+	return link.Link
+}
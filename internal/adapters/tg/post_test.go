@@ -0,0 +1,89 @@
+package tg
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/larriantoniy/tg_user_bot/internal/domain"
+	"github.com/zelenin/go-tdlib/client"
+)
+
+func TestTextEntities(t *testing.T) {
+	// 📈 занимает две единицы UTF-16: смещения после него съехали бы при подсчёте в рунах
+	ft := &client.FormattedText{
+		Text: "📈 Рынок растёт #нефть @analyst подробнее example.com",
+		Entities: []*client.TextEntity{
+			{Offset: 3, Length: 5, Type: &client.TextEntityTypeBold{}},
+			{Offset: 16, Length: 6, Type: &client.TextEntityTypeHashtag{}},
+			{Offset: 23, Length: 8, Type: &client.TextEntityTypeMention{}},
+			{Offset: 32, Length: 9, Type: &client.TextEntityTypeTextUrl{Url: "https://example.com/a"}},
+			{Offset: 42, Length: 11, Type: &client.TextEntityTypeUrl{}},
+			{Offset: 40, Length: 100, Type: &client.TextEntityTypeCashtag{}}, // битое смещение
+		},
+	}
+	want := []domain.TextEntity{
+		{Type: domain.EntityHashtag, Text: "#нефть"},
+		{Type: domain.EntityMention, Text: "@analyst"},
+		{Type: domain.EntityTextURL, Text: "подробнее", URL: "https://example.com/a"},
+		{Type: domain.EntityURL, Text: "example.com", URL: "example.com"},
+		{Type: domain.EntityCashtag},
+	}
+	if got := textEntities(ft); !reflect.DeepEqual(got, want) {
+		t.Errorf("textEntities() = %+v, want %+v", got, want)
+	}
+	if got := textEntities(nil); got != nil {
+		t.Errorf("textEntities(nil) = %+v, want nil", got)
+	}
+}
+
+func TestPostMetadata(t *testing.T) {
+	date := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	first := &client.Message{Id: 10 << 20, MediaAlbumId: 77, Content: &client.MessagePhoto{}}
+	captioned := &client.Message{
+		Id:           11 << 20,
+		Date:         int32(date.Unix()),
+		MediaAlbumId: 77,
+		Content:      &client.MessagePhoto{Caption: &client.FormattedText{Text: "Подпись"}},
+		InteractionInfo: &client.MessageInteractionInfo{
+			ViewCount:    1500,
+			ForwardCount: 12,
+			Reactions: &client.MessageReactions{Reactions: []*client.MessageReaction{
+				{TotalCount: 30}, {TotalCount: 5}, nil,
+			}},
+		},
+		ForwardInfo: &client.MessageForwardInfo{
+			Date:   int32(date.Add(-time.Hour).Unix()),
+			Origin: &client.MessageOriginChannel{ChatId: -100500, MessageId: 3 << 20},
+		},
+	}
+
+	var msg domain.Message
+	postMetadata(&msg, captioned, first, 3)
+	want := domain.Message{
+		PostID:      10 << 20,
+		PostDate:    date.Local(),
+		ContentType: domain.ContentAlbum,
+		Views:       1500,
+		Forwards:    12,
+		Reactions:   35,
+		AlbumID:     77,
+		AlbumSize:   3,
+		ForwardOrigin: &domain.ForwardOrigin{
+			Kind:      domain.ForwardFromChannel,
+			ChatID:    -100500,
+			MessageID: 3 << 20,
+			Date:      date.Add(-time.Hour).Local(),
+		},
+	}
+	if !reflect.DeepEqual(msg, want) {
+		t.Errorf("postMetadata() = %+v, want %+v", msg, want)
+	}
+
+	var single domain.Message
+	text := &client.Message{Id: 5 << 20, Content: &client.MessageText{Text: &client.FormattedText{Text: "Пост"}}}
+	postMetadata(&single, text, text, 1)
+	if single.ContentType != domain.ContentText || single.AlbumSize != 0 || single.ForwardOrigin != nil {
+		t.Errorf("postMetadata() for text post = %+v", single)
+	}
+}
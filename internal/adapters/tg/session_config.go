@@ -24,10 +24,30 @@ type RawSessionConfig struct {
 
 	Proxy    []any    `json:"proxy"` // [type, host, port, useAuth, user, pass]
 	Channels []string `json:"channels"`
+	Vision   *bool    `json:"vision"` // отправлять ли картинки постов в нейросеть; nil — как в общем конфиге
+
+	Prompt RawPromptConfig `json:"prompt"`
 
 	// остальное можно добавить по мере необходимости
 }
 
+// RawPromptConfig — какой шаблон промпта использует сессия
+type RawPromptConfig struct {
+	Template string            `json:"template"` // имя шаблона из prompts.templates
+	Language string            `json:"language"`
+	Persona  string            `json:"persona"`
+	Channels map[string]string `json:"channels"` // chat id или название канала → имя шаблона
+}
+
+func (c *RawSessionConfig) ToPromptSettings() ports.PromptSettings {
+	return ports.PromptSettings{
+		Template: c.Prompt.Template,
+		Language: c.Prompt.Language,
+		Persona:  c.Prompt.Persona,
+		Channels: c.Prompt.Channels,
+	}
+}
+
 func (c *RawSessionConfig) ToProxyConfig() (*ports.ProxyConfig, error) {
 	if len(c.Proxy) == 0 {
 		return nil, nil
@@ -8,12 +8,15 @@ import (
 	"math/rand"
 	"os"
 	"path/filepath"
+	"regexp"
 	"slices"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
 
 	"github.com/larriantoniy/tg_user_bot/internal/domain"
+	"github.com/larriantoniy/tg_user_bot/internal/ports"
 	"github.com/zelenin/go-tdlib/client"
 )
 
@@ -27,6 +30,16 @@ type TelegramClient struct {
 	mu          sync.Mutex
 	joinedChats map[int64]struct{}
 	blockedTill map[int64]time.Time
+	stopReason  error // выставляется, когда TDLib сам завершил сессию
+	closed      bool
+	listener    *client.Listener // go-tdlib сам его не закрывает: Updates закрываем в closeListener
+
+	vision        bool  // скачивать картинки постов для vision-модели
+	maxImageBytes int64 // размеры фото крупнее пропускаем
+	threadReplies int   // сколько последних комментариев обсуждения прикладывать к посту; 0 — не читать
+	conversation  bool  // режим переписки: отдавать наверх ответы на наши комментарии
+
+	albums *albumBuffer // только из горутины Listen, без mu
 }
 type ClientMode int
 
@@ -97,7 +110,7 @@ func NewClientFromJSON(
 	tdCli, err := client.NewClient(authorizer, opts...)
 	if err != nil {
 		log.Error("TDLib NewClient error", "session", rawCfg.SessionFile, "error", err)
-		return nil, err
+		return nil, classifySessionError(err)
 	}
 
 	// === Режим AUTH: просто возвращаем клиента, без GetMe ===
@@ -121,7 +134,8 @@ func NewClientFromJSON(
 	me, err := tdCli.GetMe()
 	if err != nil {
 		log.Error("GetMe failed", "session", rawCfg.SessionFile, "error", err)
-		return nil, err
+		tdCli.Close()
+		return nil, classifySessionError(err)
 	}
 
 	log.Info("TDLib client initialized and authorized",
@@ -139,7 +153,8 @@ func NewClientFromJSON(
 	}, nil
 }
 
-var ErrRateLimited = errors.New("tdlib: too many requests")
+// ErrRateLimited оставлен для совместимости, сама ошибка живёт в ports.
+var ErrRateLimited = ports.ErrRateLimited
 
 // Реализация ports.TelegramClient:
 
@@ -151,10 +166,37 @@ func (t *TelegramClient) GetMe() (int64, error) {
 	return me.Id, nil
 }
 
+// Close закрывает TDLib-клиент; повторный вызов ничего не делает
 func (t *TelegramClient) Close() {
+	t.mu.Lock()
+	if t.closed {
+		t.mu.Unlock()
+		return
+	}
+	t.closed = true
+	t.mu.Unlock()
+	// сначала отписываемся: иначе TDLib упрётся в запись в Updates, которые уже никто не читает
+	t.closeListener()
 	t.client.Close()
 }
 
+// closeListener закрывает Updates слушателя, из-за чего Listen закрывает свой канал; повторный вызов ничего не делает
+func (t *TelegramClient) closeListener() {
+	t.mu.Lock()
+	l := t.listener
+	t.listener = nil
+	t.mu.Unlock()
+	if l != nil {
+		l.Close()
+	}
+}
+
+func (t *TelegramClient) StopReason() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.stopReason
+}
+
 // JoinChannel подписывается на публичный канал по его username, если ещё не подписан
 func (t *TelegramClient) JoinChannel(username string) error {
 	// Ищем чат по username
@@ -179,6 +221,45 @@ func (t *TelegramClient) JoinChannel(username string) error {
 	t.logger.Info("Joined channel", "channel", username)
 	return nil
 }
+// LeaveChannel отписывается от канала по username ("@name") или invite-ссылке
+func (t *TelegramClient) LeaveChannel(ch string) error {
+	var chatID int64
+	if strings.HasPrefix(ch, "@") {
+		chat, err := t.client.SearchPublicChat(&client.SearchPublicChatRequest{
+			Username: ch,
+		})
+		if err != nil {
+			t.logger.Error("SearchPublicChat failed", "username", ch, "error", err)
+			return err
+		}
+		chatID = chat.Id
+	} else {
+		info, err := t.client.CheckChatInviteLink(&client.CheckChatInviteLinkRequest{
+			InviteLink: ch,
+		})
+		if err != nil {
+			t.logger.Error("CheckChatInviteLink failed", "link", ch, "error", err)
+			return err
+		}
+		chatID = info.ChatId
+	}
+	if chatID == 0 {
+		return fmt.Errorf("leave %s: not a member", ch)
+	}
+
+	if _, err := t.client.LeaveChat(&client.LeaveChatRequest{ChatId: chatID}); err != nil {
+		t.logger.Error("LeaveChat failed", "chat_id", chatID, "error", err)
+		return err
+	}
+
+	t.mu.Lock()
+	delete(t.joinedChats, chatID)
+	t.mu.Unlock()
+
+	t.logger.Info("Left channel", "channel", ch)
+	return nil
+}
+
 func (t *TelegramClient) JoinChannels(chs []string) {
 	//  Логируем входные данные
 	t.logger.Info("JoinChannels called", "channels", chs)
@@ -230,32 +311,98 @@ func (t *TelegramClient) JoinChannels(chs []string) {
 	}
 }
 
-// Listen возвращает канал доменных сообщений из TDLib и запускает обработку обновлений
+// Listen возвращает канал доменных сообщений из TDLib и запускает обработку обновлений.
+// Канал закрывается после Close или когда TDLib сам завершил сессию (см. StopReason).
 func (t *TelegramClient) Listen() (<-chan domain.Message, error) {
 	out := make(chan domain.Message)
 
 	// Получаем слушатель обновлений
 	listener := t.client.GetListener()
-	go func() {
-		defer close(out)
-		for update := range listener.Updates {
-
-			if upd, ok := update.(*client.UpdateNewMessage); ok {
-				t.logger.Debug("UpdateNewMessage received",
-					"chat_id", upd.Message.ChatId,
-					"is_channel_post", upd.Message.IsChannelPost,
-					"message_id", upd.Message.Id,
+	t.mu.Lock()
+	t.listener = listener
+	t.mu.Unlock()
+	t.albums = newAlbumBuffer(albumWindow)
+	go t.listen(listener.Updates, out)
+
+	return out, nil
+}
+
+// listen разбирает обновления TDLib до закрытия updates или конца авторизации
+func (t *TelegramClient) listen(updates <-chan client.Type, out chan domain.Message) {
+	defer close(out)
+	defer t.closeListener()
+	for {
+		// альбомы отдаём из этой же горутины: out закрывается только здесь
+		var albumDue <-chan time.Time
+		if next, ok := t.albums.next(); ok {
+			albumDue = time.After(time.Until(next))
+		}
+		var update client.Type
+		select {
+		case u, ok := <-updates:
+			if !ok {
+				// клиент закрыт: недособранные альбомы уже некуда комментировать
+				return
+			}
+			update = u
+		case <-albumDue:
+			for _, a := range t.albums.due(time.Now()) {
+				t.processAlbum(out, a)
+			}
+			continue
+		}
+
+		if upd, ok := update.(*client.UpdateAuthorizationState); ok {
+			if t.handleAuthorizationState(upd.AuthorizationState) {
+				return
+			}
+			continue
+		}
+
+		if upd, ok := update.(*client.UpdateNewMessage); ok {
+			t.logger.Debug("UpdateNewMessage received",
+				"chat_id", upd.Message.ChatId,
+				"is_channel_post", upd.Message.IsChannelPost,
+				"message_id", upd.Message.Id,
+			)
+			_, err := t.processUpdateNewMessage(out, upd)
+			if err != nil {
+				t.logger.Error("Error process UpdateNewMessage",
+					"content_type", upd.Message.Content.MessageContentType(),
+					"error", err,
 				)
-				_, err := t.processUpdateNewMessage(out, upd)
-				if err != nil {
-					t.logger.Error("Error process UpdateNewMessage msg content type", upd.Message.Content.MessageContentType())
-				}
 			}
 		}
-	}()
+	}
+}
 
-	return out, nil
+// errTDLibClosed — TDLib закрыл клиента без разлогина; сессию можно перезапустить
+var errTDLibClosed = errors.New("tdlib: client closed")
+
+// handleAuthorizationState запоминает причину, если TDLib завершает сессию.
+// true — обновлений больше не будет и Listen пора выходить.
+func (t *TelegramClient) handleAuthorizationState(state client.AuthorizationState) bool {
+	var reason error
+	switch state.AuthorizationStateType() {
+	case client.TypeAuthorizationStateLoggingOut:
+		t.logger.Error("TDLib session is logging out (revoked or deleted)")
+		reason = ports.ErrUnauthorized
+	case client.TypeAuthorizationStateClosed:
+		t.logger.Warn("TDLib client closed")
+		reason = errTDLibClosed
+	default:
+		return false
+	}
+
+	t.mu.Lock()
+	// после нашего Close это штатная остановка, а не сбой сессии
+	if t.stopReason == nil && !t.closed {
+		t.stopReason = reason
+	}
+	t.mu.Unlock()
+	return true
 }
+
 func (t *TelegramClient) isMember(chatID int64) bool {
 	member, err := t.client.GetChatMember(&client.GetChatMemberRequest{
 		ChatId:   chatID,
@@ -448,35 +595,77 @@ func (t *TelegramClient) processUpdateNewMessage(out chan domain.Message, upd *c
 	if upd.Message.IsOutgoing {
 		return out, nil
 	}
+	// у личных чатов ChatId положительный и совпадает с user id
+	if upd.Message.ChatId > 0 {
+		return t.processPrivateMessage(out, upd.Message)
+	}
 	if !upd.Message.IsChannelPost {
-		return out, nil
+		return t.processReplyToOwn(out, upd.Message)
 	}
 
 	channelMsgID := upd.Message.Id
 	if channelMsgID == 0 {
 		return out, nil
 	}
+	if upd.Message.MediaAlbumId != 0 && t.albums != nil {
+		// альбом приходит отдельными сообщениями: копим их и обрабатываем одним постом
+		t.albums.add(upd.Message, time.Now())
+		return out, nil
+	}
+	return t.processChannelPost(out, upd.Message.ChatId, channelMsgID)
+}
 
-	discussionChatID, discussionThreadID, replyToID, ok := t.resolveDiscussionThread(upd.Message.ChatId, channelMsgID)
+// processChannelPost находит тред обсуждения по первому сообщению поста и отдаёт пост наверх.
+// channelMsgIDs — одно сообщение или весь альбом.
+func (t *TelegramClient) processChannelPost(out chan domain.Message, channelChatID int64, channelMsgIDs ...int64) (<-chan domain.Message, error) {
+	channelMsgID := channelMsgIDs[0]
+	discussionChatID, discussionThreadID, replyToID, ok := t.resolveDiscussionThread(channelChatID, channelMsgID)
 	if !ok {
 		t.logger.Info("Resolve thread failed",
-			"channel_chat_id", upd.Message.ChatId,
+			"channel_chat_id", channelChatID,
 			"channel_msg_id", channelMsgID,
 		)
 		return out, nil
 	}
 	t.logger.Info("Resolved discussion thread",
-		"channel_chat_id", upd.Message.ChatId,
+		"channel_chat_id", channelChatID,
 		"channel_msg_id", channelMsgID,
+		"album_size", len(channelMsgIDs),
 		"discussion_chat_id", discussionChatID,
 		"discussion_thread_id", discussionThreadID,
 		"reply_to_message_id", replyToID,
 	)
 	t.ensureJoinedChat(discussionChatID)
-	return t.processChannelPostThread(out, upd.Message.ChatId, discussionChatID, discussionThreadID, replyToID, channelMsgID)
+	return t.processChannelPostThread(out, channelChatID, discussionChatID, discussionThreadID, replyToID, channelMsgIDs...)
 }
 
-func (t *TelegramClient) processChannelPostThread(out chan domain.Message, channelChatID int64, discussionChatID int64, discussionThreadID int64, replyToID int64, channelMsgID int64) (<-chan domain.Message, error) {
+// processPrivateMessage пропускает дальше текст из лички: Sender сам решит, команда ли это владельца
+func (t *TelegramClient) processPrivateMessage(out chan domain.Message, m *client.Message) (<-chan domain.Message, error) {
+	sender, ok := m.SenderId.(*client.MessageSenderUser)
+	if !ok {
+		return out, nil
+	}
+	msg, ok := m.Content.(*client.MessageText)
+	if !ok {
+		return out, nil
+	}
+	text := strings.TrimSpace(msg.Text.Text)
+	if text == "" {
+		return out, nil
+	}
+
+	out <- domain.Message{
+		ChatID:    m.ChatId,
+		Text:      text,
+		IsPrivate: true,
+		SenderID:  sender.UserId,
+	}
+	return out, nil
+}
+
+// processChannelPostThread отдаёт наверх пост канала; channelMsgIDs — одно сообщение
+// или все сообщения альбома: подпись берётся у того, где она есть, картинки — у всех
+func (t *TelegramClient) processChannelPostThread(out chan domain.Message, channelChatID int64, discussionChatID int64, discussionThreadID int64, replyToID int64, channelMsgIDs ...int64) (<-chan domain.Message, error) {
 	if t.isChatBlocked(discussionChatID) {
 		t.logger.Info("Skip post thread: invite required for discussion chat", "chat_id", discussionChatID)
 		return out, nil
@@ -492,78 +681,403 @@ func (t *TelegramClient) processChannelPostThread(out chan domain.Message, chann
 		"thread_id", discussionThreadID,
 	)
 
-	chatName, err := t.getChatTitle(channelChatID)
+	var chatName, username string
+	chat, err := t.client.GetChat(&client.GetChatRequest{ChatId: channelChatID})
 	if err != nil {
 		t.logger.Info("Error getting chat title", "err", err)
-		chatName = ""
+	} else {
+		chatName, username = chat.Title, t.channelUsername(chat)
 	}
 
-	threadMsg, err := t.client.GetMessage(&client.GetMessageRequest{
-		ChatId:    channelChatID,
-		MessageId: channelMsgID,
-	})
-	if err != nil {
-		t.logger.Error("GetMessage for thread root failed",
-			"chat_id", channelChatID,
-			"thread_id", channelMsgID,
-			"error", err,
-		)
-		return out, err
+	var (
+		text      string
+		first     *client.Message
+		captioned *client.Message // сообщение с подписью; у альбома подпись обычно у одного
+		photoIDs  []int32
+	)
+	for _, id := range channelMsgIDs {
+		m, err := t.client.GetMessage(&client.GetMessageRequest{
+			ChatId:    channelChatID,
+			MessageId: id,
+		})
+		if err != nil {
+			t.logger.Error("GetMessage for thread root failed",
+				"chat_id", channelChatID,
+				"thread_id", id,
+				"error", err,
+			)
+			continue
+		}
+		if first == nil {
+			first = m
+		}
+		if captioned == nil {
+			if caption, _ := extractTextFromContent(m.Content); strings.TrimSpace(caption) != "" {
+				text, captioned = strings.TrimSpace(caption), m
+			}
+		}
+		if id, ok := t.mediaFileID(m.Content); ok {
+			photoIDs = append(photoIDs, id)
+		}
 	}
-
-	text, ok := extractTextFromContent(threadMsg.Content)
-	if !ok {
-		t.logger.Debug("Post has no text, skipping", "content_type", threadMsg.Content.MessageContentType())
-		return out, nil
+	if first == nil {
+		return out, fmt.Errorf("GetMessage for post %d/%v failed", channelChatID, channelMsgIDs)
 	}
-
-	text = strings.TrimSpace(text)
-	if text == "" {
-		t.logger.Debug("Post text is empty, skipping")
+	if text == "" && len(photoIDs) == 0 {
+		t.logger.Debug("Post has no text or image, skipping", "chat_id", channelChatID, "messages", len(channelMsgIDs))
 		return out, nil
 	}
 
-	out <- domain.Message{
+	msg := domain.Message{
 		ChannelID:       channelChatID,
 		ChatID:          discussionChatID,
 		Text:            text,
 		ChatName:        chatName,
 		MessageThreadId: discussionThreadID,
 		ReplyToMessageID: replyToID,
+		PhotoFileIDs:    photoIDs,
+		Thread:          t.fetchThread(discussionChatID, discussionThreadID, replyToID),
+	}
+	// ссылки и метаданные (в том числе ForwardOrigin) — по сообщению с подписью, без неё — по первому
+	if captioned == nil {
+		captioned = first
+	}
+	msg.HasLinks = hasLinks(captioned.Content)
+	postMetadata(&msg, captioned, first, len(channelMsgIDs))
+	msg.ChannelUsername = username
+	msg.Link = t.postLink(channelChatID, first.Id, msg.AlbumSize > 1)
+	if o := msg.ForwardOrigin; o != nil && o.Name == "" && (o.Kind == domain.ForwardFromChannel || o.Kind == domain.ForwardFromChat) {
+		// источник может быть незнаком TDLib — тогда остаётся только id
+		o.Name, _ = t.getChatTitle(o.ChatID)
+	}
+	out <- msg
+	return out, nil
+}
+
+// maxReplyChain — сколько сообщений цепочки ответов поднимаем вверх от реплики собеседника
+const maxReplyChain = 10
+
+// SetConversation включает режим переписки: ответы на наши комментарии уходят в Sender
+func (t *TelegramClient) SetConversation(enabled bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.conversation = enabled
+}
+
+// processReplyToOwn пропускает дальше реплику собеседника на наш комментарий в обсуждении
+// вместе с цепочкой переписки и текстом поста. Лимиты переписки проверяет Sender.
+func (t *TelegramClient) processReplyToOwn(out chan domain.Message, m *client.Message) (<-chan domain.Message, error) {
+	t.mu.Lock()
+	enabled := t.conversation
+	t.mu.Unlock()
+	if !enabled || m.MessageThreadId == 0 {
+		return out, nil
+	}
+	reply, ok := m.ReplyTo.(*client.MessageReplyToMessage)
+	if !ok || reply.MessageId == 0 || (reply.ChatId != 0 && reply.ChatId != m.ChatId) {
+		return out, nil
+	}
+	author, ok := m.SenderId.(*client.MessageSenderUser)
+	if !ok || author.UserId == t.selfId {
+		return out, nil
+	}
+	text, _ := extractTextFromContent(m.Content)
+	if text = strings.TrimSpace(text); text == "" {
+		return out, nil
+	}
+
+	get := func(id int64) (*client.Message, error) {
+		return t.client.GetMessage(&client.GetMessageRequest{ChatId: m.ChatId, MessageId: id})
+	}
+	parent, err := get(reply.MessageId)
+	if err != nil {
+		t.logger.Debug("GetMessage for replied message failed", "chat_id", m.ChatId, "msg_id", reply.MessageId, "error", err)
+		return out, nil
+	}
+	if !parent.IsOutgoing {
+		return out, nil
+	}
+
+	chain, root := replyChain(parent, get, maxReplyChain)
+	truncated := root == nil
+	if truncated {
+		// цепочка длиннее maxReplyChain — пост читаем отдельно
+		if root, err = get(m.MessageThreadId); err != nil {
+			t.logger.Warn("GetMessage for thread root failed", "chat_id", m.ChatId, "thread_id", m.MessageThreadId, "error", err)
+			return out, nil
+		}
+	}
+	postText, _ := extractTextFromContent(root.Content)
+	chain = append(chain, domain.ThreadReply{MessageID: m.Id, Text: text})
+
+	channelID := int64(0)
+	if root.ForwardInfo != nil {
+		if origin, ok := root.ForwardInfo.Origin.(*client.MessageOriginChannel); ok {
+			channelID = origin.ChatId
+		}
+	}
+	chatName := ""
+	if channelID != 0 {
+		chatName, _ = t.getChatTitle(channelID)
+	}
+
+	t.logger.Info("Reply to own comment",
+		"chat_id", m.ChatId,
+		"thread_id", m.MessageThreadId,
+		"msg_id", m.Id,
+		"user_id", author.UserId,
+		"chain", len(chain),
+		"truncated", truncated,
+	)
+	out <- domain.Message{
+		ChannelID:           channelID,
+		ChatID:              m.ChatId,
+		ChatName:            chatName,
+		Text:                strings.TrimSpace(postText),
+		MessageThreadId:     m.MessageThreadId,
+		ReplyToMessageID:    m.Id,
+		SenderID:            author.UserId,
+		IsReply:             true,
+		ReplyChain:          chain,
+		ReplyChainTruncated: truncated,
 	}
 	return out, nil
 }
 
-func extractTextFromContent(content client.MessageContent) (string, bool) {
+// replyChain поднимается по ответам от from до корня треда (не дальше limit сообщений)
+// и возвращает текстовые сообщения цепочки в хронологическом порядке и сам корень;
+// nil root — до корня не дошли
+func replyChain(from *client.Message, get func(id int64) (*client.Message, error), limit int) ([]domain.ThreadReply, *client.Message) {
+	var chain []domain.ThreadReply
+	var root *client.Message
+	for m, n := from, 0; m != nil && n < limit; n++ {
+		if m.Id == m.MessageThreadId {
+			root = m
+			break
+		}
+		if text, _ := extractTextFromContent(m.Content); strings.TrimSpace(text) != "" {
+			chain = append(chain, domain.ThreadReply{MessageID: m.Id, Text: strings.TrimSpace(text), Own: m.IsOutgoing})
+		}
+		reply, ok := m.ReplyTo.(*client.MessageReplyToMessage)
+		if !ok || reply.MessageId == 0 {
+			break
+		}
+		next, err := get(reply.MessageId)
+		if err != nil {
+			break
+		}
+		m = next
+	}
+	slices.Reverse(chain)
+	return chain, root
+}
+
+// SetThreadContext задаёт, сколько последних комментариев обсуждения читать вместе с постом; 0 — не читать
+func (t *TelegramClient) SetThreadContext(replies int) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.threadReplies = replies
+}
+
+// fetchThread читает последние комментарии треда; ошибка не мешает комментировать пост без них
+func (t *TelegramClient) fetchThread(chatID, threadID, rootID int64) []domain.ThreadReply {
+	t.mu.Lock()
+	limit := t.threadReplies
+	t.mu.Unlock()
+	if limit <= 0 {
+		return nil
+	}
+
+	hist, err := t.client.GetMessageThreadHistory(&client.GetMessageThreadHistoryRequest{
+		ChatId:        chatID,
+		MessageId:     threadID,
+		FromMessageId: 0, // с самого нового
+		Limit:         int32(limit + 1), // +1 на корневой пост, если он попадёт в выборку
+	})
+	if err != nil {
+		t.logger.Warn("GetMessageThreadHistory for context failed", "chat_id", chatID, "thread_id", threadID, "error", err)
+		return nil
+	}
+	return threadReplies(hist.Messages, rootID, limit)
+}
+
+// threadReplies берёт до limit текстовых комментариев из истории TDLib (новые первыми)
+// без корневого поста и возвращает их в хронологическом порядке
+func threadReplies(msgs []*client.Message, rootID int64, limit int) []domain.ThreadReply {
+	var out []domain.ThreadReply
+	for _, m := range msgs {
+		if len(out) == limit {
+			break
+		}
+		if m == nil || m.Id == rootID {
+			continue
+		}
+		text, _ := extractTextFromContent(m.Content)
+		if text = strings.TrimSpace(text); text == "" {
+			continue
+		}
+		out = append(out, domain.ThreadReply{MessageID: m.Id, Text: text, Own: m.IsOutgoing})
+	}
+	slices.Reverse(out)
+	return out
+}
+
+// SetVision включает картинки постов (фото, превью видео и анимаций) для vision-модели
+func (t *TelegramClient) SetVision(enabled bool, maxImageBytes int64) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.vision = enabled
+	t.maxImageBytes = maxImageBytes
+}
+
+// mediaFileID выбирает картинку поста для vision-модели, но не скачивает её:
+// до DownloadPhotos пост ещё может отсеяться фильтрами. false — vision выключен или картинки нет.
+func (t *TelegramClient) mediaFileID(content client.MessageContent) (int32, bool) {
+	t.mu.Lock()
+	enabled, limit := t.vision, t.maxImageBytes
+	t.mu.Unlock()
+	if !enabled {
+		return 0, false
+	}
+	f := pickMediaFile(content, limit)
+	if f == nil {
+		return 0, false
+	}
+	return f.Id, true
+}
+
+// DownloadPhotos скачивает картинки поста по PhotoFileIDs и заполняет PhotoFiles и PhotoFile.
+// Не скачавшиеся пропускает: пост уйдёт в нейросеть без них.
+func (t *TelegramClient) DownloadPhotos(msg *domain.Message) {
+	if len(msg.PhotoFileIDs) == 0 || len(msg.PhotoFiles) > 0 {
+		return
+	}
+	for _, id := range msg.PhotoFileIDs {
+		if path := t.downloadFile(id); path != "" {
+			msg.PhotoFiles = append(msg.PhotoFiles, path)
+		}
+	}
+	if len(msg.PhotoFiles) > 0 {
+		msg.PhotoFile = msg.PhotoFiles[0]
+	}
+}
+
+// downloadFile скачивает файл TDLib и возвращает локальный путь; "" — не скачался
+func (t *TelegramClient) downloadFile(id int32) string {
+	file, err := t.client.DownloadFile(&client.DownloadFileRequest{
+		FileId:      id,
+		Priority:    16,
+		Synchronous: true,
+	})
+	if err != nil {
+		t.logger.Warn("DownloadFile failed, post goes without image", "file_id", id, "error", err)
+		return ""
+	}
+	if file.Local == nil || !file.Local.IsDownloadingCompleted {
+		t.logger.Warn("DownloadFile incomplete, post goes without image", "file_id", id)
+		return ""
+	}
+	return file.Local.Path
+}
+
+// pickMediaFile выбирает картинку для vision-модели: самый крупный размер фото,
+// который влезает в maxBytes, или jpeg/png/webp-превью видео и анимации
+func pickMediaFile(content client.MessageContent, maxBytes int64) *client.File {
+	fits := func(f *client.File) bool {
+		if f == nil {
+			return false
+		}
+		size := f.Size
+		if size == 0 {
+			size = f.ExpectedSize
+		}
+		return maxBytes <= 0 || size <= maxBytes
+	}
+
+	var thumb *client.Thumbnail
 	switch c := content.(type) {
-	case *client.MessageText:
-		return c.Text.Text, true
 	case *client.MessagePhoto:
-		if c.Caption != nil {
-			return c.Caption.Text, true
+		if c.Photo == nil {
+			return nil
+		}
+		var best *client.PhotoSize
+		for _, ps := range c.Photo.Sizes {
+			if ps == nil || !fits(ps.Photo) {
+				continue
+			}
+			if best == nil || int64(ps.Width)*int64(ps.Height) > int64(best.Width)*int64(best.Height) {
+				best = ps
+			}
 		}
+		if best == nil {
+			return nil
+		}
+		return best.Photo
 	case *client.MessageVideo:
-		if c.Caption != nil {
-			return c.Caption.Text, true
+		if c.Video != nil {
+			thumb = c.Video.Thumbnail
 		}
 	case *client.MessageAnimation:
-		if c.Caption != nil {
-			return c.Caption.Text, true
+		if c.Animation != nil {
+			thumb = c.Animation.Thumbnail
 		}
+	}
+
+	if thumb == nil || thumb.Format == nil || !fits(thumb.File) {
+		return nil
+	}
+	switch thumb.Format.ThumbnailFormatType() {
+	case client.TypeThumbnailFormatJpeg, client.TypeThumbnailFormatPng, client.TypeThumbnailFormatWebp:
+		return thumb.File
+	}
+	return nil
+}
+
+func extractTextFromContent(content client.MessageContent) (string, bool) {
+	ft := formattedText(content)
+	if ft == nil {
+		return "", false
+	}
+	return ft.Text, true
+}
+
+// formattedText — текст или подпись сообщения вместе с разметкой; nil — текста нет
+func formattedText(content client.MessageContent) *client.FormattedText {
+	switch c := content.(type) {
+	case *client.MessageText:
+		return c.Text
+	case *client.MessagePhoto:
+		return c.Caption
+	case *client.MessageVideo:
+		return c.Caption
+	case *client.MessageAnimation:
+		return c.Caption
 	case *client.MessageDocument:
-		if c.Caption != nil {
-			return c.Caption.Text, true
-		}
+		return c.Caption
 	case *client.MessageAudio:
-		if c.Caption != nil {
-			return c.Caption.Text, true
-		}
+		return c.Caption
 	case *client.MessageVoiceNote:
-		if c.Caption != nil {
-			return c.Caption.Text, true
+		return c.Caption
+	}
+	return nil
+}
+
+// hasLinks — в тексте есть ссылка: явная или спрятанная под словами
+func hasLinks(content client.MessageContent) bool {
+	ft := formattedText(content)
+	if ft == nil {
+		return false
+	}
+	for _, e := range ft.Entities {
+		if e == nil {
+			continue
+		}
+		switch e.Type.(type) {
+		case *client.TextEntityTypeUrl, *client.TextEntityTypeTextUrl:
+			return true
 		}
 	}
-	return "", false
+	return false
 }
 func (t *TelegramClient) SendMessage(
 	chatID int64,
@@ -606,14 +1120,15 @@ func (t *TelegramClient) SendMessage(
 	if err != nil {
 		// 🔍 проверяем, не словили ли лимит
 		if isTooManyRequests(err) {
-			t.logger.Error("SendMessage rate-limited: too many requests, stopping client",
+			wait := retryAfter(err)
+			t.logger.Error("SendMessage rate-limited: FLOOD_WAIT",
 				"chat_id", chatID,
 				"thread_id", threadID,
+				"retry_after", wait,
 				"error", err,
 			)
-			// Останавливаем конкретный TDLib-клиент
-			t.Close()
-			return ErrRateLimited
+			// клиент не закрываем: Sender сам переждёт retry_after
+			return &ports.RateLimitError{RetryAfter: wait}
 		}
 
 		t.logger.Error("SendMessage failed",
@@ -769,10 +1284,50 @@ func isTooManyRequests(err error) bool {
 		if strings.Contains(strings.ToLower(respErr.Err.Message), "too many requests") {
 			return true
 		}
+		// FLOOD_WAIT_N приходит с кодом 420
+		if strings.HasPrefix(respErr.Err.Message, "FLOOD_WAIT_") {
+			return true
+		}
 	}
 	return false
 }
 
+// defaultFloodWait — сколько ждать, если TDLib не сказал, сколько
+const defaultFloodWait = 5 * time.Minute
+
+var retryAfterRe = regexp.MustCompile(`(?i)(?:retry after|FLOOD_WAIT_)\s*(\d+)`)
+
+// retryAfter достаёт секунды из "Too Many Requests: retry after 123" или "FLOOD_WAIT_123"
+func retryAfter(err error) time.Duration {
+	m := retryAfterRe.FindStringSubmatch(err.Error())
+	if m == nil {
+		return defaultFloodWait
+	}
+	sec, convErr := strconv.Atoi(m[1])
+	if convErr != nil || sec <= 0 {
+		return defaultFloodWait
+	}
+	return time.Duration(sec) * time.Second
+}
+
+// classifySessionError оборачивает ошибки TDLib, после которых перезапуск не поможет
+func classifySessionError(err error) error {
+	msg := strings.ToUpper(err.Error())
+	switch {
+	case strings.Contains(msg, "USER_DEACTIVATED_BAN"),
+		strings.Contains(msg, "PHONE_NUMBER_BANNED"):
+		return fmt.Errorf("%w: %v", ports.ErrBanned, err)
+	case strings.Contains(msg, "USER_DEACTIVATED"):
+		return fmt.Errorf("%w: %v", ports.ErrDeactivated, err)
+	case strings.Contains(msg, "UNAUTHORIZED"),
+		strings.Contains(msg, "AUTH_KEY_UNREGISTERED"),
+		strings.Contains(msg, "SESSION_REVOKED"),
+		strings.Contains(msg, "SESSION_EXPIRED"):
+		return fmt.Errorf("%w: %v", ports.ErrUnauthorized, err)
+	}
+	return err
+}
+
 func isInviteRequestSent(err error) bool {
 	var respErr client.ResponseError
 	if errors.As(err, &respErr) && respErr.Err != nil {
@@ -0,0 +1,266 @@
+package tg
+
+import (
+	"errors"
+	"io"
+	"log/slog"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/larriantoniy/tg_user_bot/internal/domain"
+	"github.com/larriantoniy/tg_user_bot/internal/ports"
+	"github.com/zelenin/go-tdlib/client"
+)
+
+func TestRetryAfter(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want time.Duration
+	}{
+		{
+			name: "too many requests",
+			err:  client.ResponseError{Err: &client.Error{Code: 429, Message: "Too Many Requests: retry after 17"}},
+			want: 17 * time.Second,
+		},
+		{
+			name: "flood wait",
+			err:  client.ResponseError{Err: &client.Error{Code: 420, Message: "FLOOD_WAIT_300"}},
+			want: 300 * time.Second,
+		},
+		{
+			name: "no retry after",
+			err:  client.ResponseError{Err: &client.Error{Code: 429, Message: "Too Many Requests"}},
+			want: defaultFloodWait,
+		},
+		{
+			name: "plain error",
+			err:  errors.New("retry after 0"),
+			want: defaultFloodWait,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			if got := retryAfter(tt.err); got != tt.want {
+				t.Errorf("retryAfter() = %v, want %v", got, tt.want)
+			}
+			if !isTooManyRequests(tt.err) && tt.name != "plain error" {
+				t.Errorf("isTooManyRequests() = false, want true")
+			}
+		})
+	}
+}
+
+func TestClassifySessionError(t *testing.T) {
+	tests := []struct {
+		msg  string
+		want error
+	}{
+		{msg: "USER_DEACTIVATED_BAN", want: ports.ErrBanned},
+		{msg: "PHONE_NUMBER_BANNED", want: ports.ErrBanned},
+		{msg: "USER_DEACTIVATED", want: ports.ErrDeactivated},
+		{msg: "Unauthorized", want: ports.ErrUnauthorized},
+		{msg: "AUTH_KEY_UNREGISTERED", want: ports.ErrUnauthorized},
+		{msg: "Connection timeout", want: nil},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.msg, func(t *testing.T) {
+			err := classifySessionError(client.ResponseError{Err: &client.Error{Code: 401, Message: tt.msg}})
+			if tt.want == nil {
+				if ports.IsPermanent(err) {
+					t.Errorf("classifySessionError() = %v, want transient", err)
+				}
+				return
+			}
+			if !errors.Is(err, tt.want) {
+				t.Errorf("classifySessionError() = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestPickMediaFile(t *testing.T) {
+	file := func(id int32, size int64) *client.File { return &client.File{Id: id, Size: size} }
+	photo := &client.MessagePhoto{Photo: &client.Photo{Sizes: []*client.PhotoSize{
+		{Type: "s", Width: 90, Height: 90, Photo: file(1, 1_000)},
+		{Type: "y", Width: 1280, Height: 1280, Photo: file(3, 300_000)},
+		{Type: "m", Width: 320, Height: 320, Photo: file(2, 20_000)},
+	}}}
+
+	tests := []struct {
+		name     string
+		content  client.MessageContent
+		maxBytes int64
+		wantID   int32 // 0 — картинки нет
+	}{
+		{name: "largest photo size", content: photo, wantID: 3},
+		{name: "largest size under limit", content: photo, maxBytes: 50_000, wantID: 2},
+		{name: "every size over limit", content: photo, maxBytes: 10, wantID: 0},
+		{
+			name: "video jpeg thumbnail",
+			content: &client.MessageVideo{Video: &client.Video{
+				Thumbnail: &client.Thumbnail{Format: &client.ThumbnailFormatJpeg{}, File: file(7, 5_000)},
+			}},
+			wantID: 7,
+		},
+		{
+			name: "animation mpeg4 thumbnail is skipped",
+			content: &client.MessageAnimation{Animation: &client.Animation{
+				Thumbnail: &client.Thumbnail{Format: &client.ThumbnailFormatMpeg4{}, File: file(8, 5_000)},
+			}},
+			wantID: 0,
+		},
+		{name: "text post", content: &client.MessageText{Text: &client.FormattedText{Text: "hi"}}, wantID: 0},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			got := pickMediaFile(tt.content, tt.maxBytes)
+			var gotID int32
+			if got != nil {
+				gotID = got.Id
+			}
+			if gotID != tt.wantID {
+				t.Errorf("pickMediaFile() file id = %d, want %d", gotID, tt.wantID)
+			}
+		})
+	}
+}
+
+func TestThreadReplies(t *testing.T) {
+	text := func(id int64, s string, own bool) *client.Message {
+		return &client.Message{Id: id, IsOutgoing: own, Content: &client.MessageText{Text: &client.FormattedText{Text: s}}}
+	}
+	// TDLib отдаёт историю треда от новых к старым, корневой пост — последним
+	history := []*client.Message{
+		text(50, "Согласен с автором", false),
+		{Id: 40, Content: &client.MessageSticker{}},
+		text(30, " Отличный разбор 👍 ", true),
+		text(20, "Первый!", false),
+		text(10, "Пост канала", false),
+	}
+
+	got := threadReplies(history, 10, 10)
+	want := []domain.ThreadReply{
+		{MessageID: 20, Text: "Первый!"},
+		{MessageID: 30, Text: "Отличный разбор 👍", Own: true},
+		{MessageID: 50, Text: "Согласен с автором"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("threadReplies() = %+v, want %+v", got, want)
+	}
+
+	if got := threadReplies(history, 10, 2); len(got) != 2 || got[0].MessageID != 30 || got[1].MessageID != 50 {
+		t.Errorf("threadReplies() with limit 2 = %+v, want the 2 newest", got)
+	}
+}
+
+func TestReplyChain(t *testing.T) {
+	reply := func(id, to int64, s string, own bool) *client.Message {
+		m := &client.Message{Id: id, MessageThreadId: 10, IsOutgoing: own, Content: &client.MessageText{Text: &client.FormattedText{Text: s}}}
+		if to != 0 {
+			m.ReplyTo = &client.MessageReplyToMessage{MessageId: to}
+		}
+		return m
+	}
+	msgs := map[int64]*client.Message{
+		10: reply(10, 0, "Пост канала", false),
+		20: reply(20, 10, "Отличный разбор 👍", true),
+		30: reply(30, 20, "А почему?", false),
+		40: reply(40, 30, "Рынок так решил 📈", true),
+	}
+	get := func(id int64) (*client.Message, error) {
+		if m, ok := msgs[id]; ok {
+			return m, nil
+		}
+		return nil, errors.New("not found")
+	}
+
+	chain, root := replyChain(msgs[40], get, 10)
+	want := []domain.ThreadReply{
+		{MessageID: 20, Text: "Отличный разбор 👍", Own: true},
+		{MessageID: 30, Text: "А почему?"},
+		{MessageID: 40, Text: "Рынок так решил 📈", Own: true},
+	}
+	if !reflect.DeepEqual(chain, want) || root == nil || root.Id != 10 {
+		t.Errorf("replyChain() = %+v, root %+v; want %+v and root 10", chain, root, want)
+	}
+
+	chain, root = replyChain(msgs[40], get, 2)
+	if len(chain) != 2 || chain[0].MessageID != 30 || root != nil {
+		t.Errorf("replyChain() with limit 2 = %+v, root %+v; want 2 newest without root", chain, root)
+	}
+}
+
+func TestHasLinks(t *testing.T) {
+	text := func(entities ...client.TextEntityType) client.MessageContent {
+		ft := &client.FormattedText{Text: "Подробнее здесь"}
+		for _, e := range entities {
+			ft.Entities = append(ft.Entities, &client.TextEntity{Type: e})
+		}
+		return &client.MessageText{Text: ft}
+	}
+	tests := []struct {
+		name    string
+		content client.MessageContent
+		want    bool
+	}{
+		{name: "plain text", content: text(&client.TextEntityTypeBold{}), want: false},
+		{name: "url", content: text(&client.TextEntityTypeUrl{}), want: true},
+		{name: "hidden link", content: text(&client.TextEntityTypeTextUrl{Url: "https://example.com"}), want: true},
+		{name: "photo without caption", content: &client.MessagePhoto{}, want: false},
+	}
+	for _, tt := range tests {
+		if got := hasLinks(tt.content); got != tt.want {
+			t.Errorf("%s: hasLinks() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestListenStopsOnAuthorizationEnd(t *testing.T) {
+	tests := []struct {
+		name       string
+		state      client.AuthorizationState
+		closed     bool // Close уже вызван нами
+		wantReason error
+	}{
+		{name: "closed by TDLib", state: &client.AuthorizationStateClosed{}, wantReason: errTDLibClosed},
+		{name: "logging out", state: &client.AuthorizationStateLoggingOut{}, wantReason: ports.ErrUnauthorized},
+		{name: "closed after own Close", state: &client.AuthorizationStateClosed{}, closed: true},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			c := &TelegramClient{
+				logger: slog.New(slog.NewTextHandler(io.Discard, nil)),
+				albums: newAlbumBuffer(albumWindow),
+				closed: tt.closed,
+			}
+			// канал обновлений не закрываем: go-tdlib этого тоже не делает
+			updates := make(chan client.Type, 2)
+			updates <- &client.UpdateAuthorizationState{AuthorizationState: &client.AuthorizationStateReady{}}
+			updates <- &client.UpdateAuthorizationState{AuthorizationState: tt.state}
+			out := make(chan domain.Message)
+			go c.listen(updates, out)
+
+			select {
+			case _, ok := <-out:
+				if ok {
+					t.Fatal("listen() sent a message, want closed channel")
+				}
+			case <-time.After(time.Second):
+				t.Fatal("listen() did not close out after authorization ended")
+			}
+			if got := c.StopReason(); !errors.Is(got, tt.wantReason) {
+				t.Errorf("StopReason() = %v, want %v", got, tt.wantReason)
+			}
+		})
+	}
+}
@@ -0,0 +1,344 @@
+package tgfake
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/larriantoniy/tg_user_bot/internal/domain"
+	"github.com/larriantoniy/tg_user_bot/internal/ports"
+)
+
+var _ ports.TelegramClient = (*Client)(nil)
+
+// Client — управляемая из тестов in-memory реализация ports.TelegramClient.
+// Позволяет подкладывать посты в Listen(), задавать ответы CanSendToChat/IsMember,
+// программировать ошибки SendMessage (в том числе ports.ErrRateLimited)
+// и записывает все исходящие вызовы.
+type Client struct {
+	mu sync.Mutex
+
+	selfID     int64
+	updates    chan domain.Message
+	closed     bool
+	stopReason error
+
+	// ответы по умолчанию для чатов, которых нет в canSend/member
+	defaultCanSend bool
+	defaultMember  bool
+	canSend        map[int64]bool
+	member         map[int64]bool
+	usernames      map[string]int64
+	channelMember  map[string]bool
+
+	// очередь ошибок для следующих вызовов SendMessage
+	sendErrs []error
+
+	sent      []SentMessage
+	typing    []TypingCall
+	reads     []int64
+	joined    []string
+	left      []string
+	resolve   []string
+	downloads []int32
+}
+
+// SentMessage — записанный вызов SendMessage.
+type SentMessage struct {
+	ChatID           int64
+	ThreadID         int64
+	ReplyToMessageID int64
+	Text             string
+}
+
+// TypingCall — записанный вызов SimulateTyping.
+type TypingCall struct {
+	ChatID   int64
+	ThreadID int64
+	Text     string
+}
+
+// New создаёт фейковый клиент: по умолчанию во все чаты можно писать и мы в них состоим.
+// buffer — ёмкость канала Listen(), чтобы Push не блокировался без читателя.
+func New(selfID int64, buffer int) *Client {
+	return &Client{
+		selfID:         selfID,
+		updates:        make(chan domain.Message, buffer),
+		defaultCanSend: true,
+		defaultMember:  true,
+		canSend:        make(map[int64]bool),
+		member:         make(map[int64]bool),
+		usernames:      make(map[string]int64),
+		channelMember:  make(map[string]bool),
+	}
+}
+
+// --- сценарий ---
+
+// Push подкладывает пост в канал, который вернул Listen().
+func (c *Client) Push(msg domain.Message) {
+	c.updates <- msg
+}
+
+// SetCanSend задаёт ответ CanSendToChat для конкретного чата.
+func (c *Client) SetCanSend(chatID int64, ok bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.canSend[chatID] = ok
+}
+
+// SetDefaultCanSend задаёт ответ CanSendToChat для всех остальных чатов.
+func (c *Client) SetDefaultCanSend(ok bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.defaultCanSend = ok
+}
+
+// SetMember задаёт ответ IsMember для конкретного чата.
+func (c *Client) SetMember(chatID int64, ok bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.member[chatID] = ok
+}
+
+// SetDefaultMember задаёт ответ IsMember для всех остальных чатов.
+func (c *Client) SetDefaultMember(ok bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.defaultMember = ok
+}
+
+// SetChannelMember задаёт ответ IsChannelMember для username.
+func (c *Client) SetChannelMember(username string, ok bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.channelMember[username] = ok
+}
+
+// AddUsername регистрирует username для ResolveUsername (с "@" или без).
+func (c *Client) AddUsername(username string, id int64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.usernames[strings.TrimPrefix(username, "@")] = id
+}
+
+// FailNextSend ставит ошибки в очередь: каждый следующий SendMessage
+// забирает по одной. nil в очереди означает успешную отправку.
+func (c *Client) FailNextSend(errs ...error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.sendErrs = append(c.sendErrs, errs...)
+}
+
+// Disconnect имитирует обрыв сессии со стороны Telegram: закрывает Listen()
+// и запоминает причину для StopReason.
+func (c *Client) Disconnect(reason error) {
+	c.mu.Lock()
+	c.stopReason = reason
+	c.mu.Unlock()
+	c.Close()
+}
+
+// --- записанные вызовы ---
+
+// Sent возвращает копию всех успешных вызовов SendMessage.
+func (c *Client) Sent() []SentMessage {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]SentMessage(nil), c.sent...)
+}
+
+// SentTo возвращает успешные отправки в конкретный чат.
+func (c *Client) SentTo(chatID int64) []SentMessage {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var out []SentMessage
+	for _, m := range c.sent {
+		if m.ChatID == chatID {
+			out = append(out, m)
+		}
+	}
+	return out
+}
+
+// Typing возвращает копию всех вызовов SimulateTyping.
+func (c *Client) Typing() []TypingCall {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]TypingCall(nil), c.typing...)
+}
+
+// Reads возвращает chatID всех вызовов ImitateReading.
+func (c *Client) Reads() []int64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]int64(nil), c.reads...)
+}
+
+// Joined возвращает все каналы, переданные в JoinChannel/JoinChannels.
+func (c *Client) Joined() []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]string(nil), c.joined...)
+}
+
+// Left возвращает все каналы, переданные в LeaveChannel.
+func (c *Client) Left() []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]string(nil), c.left...)
+}
+
+// Resolved возвращает все username, запрошенные через ResolveUsername.
+func (c *Client) Resolved() []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]string(nil), c.resolve...)
+}
+
+// Downloads возвращает id всех картинок, скачанных через DownloadPhotos.
+func (c *Client) Downloads() []int32 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]int32(nil), c.downloads...)
+}
+
+// Closed сообщает, вызывался ли Close.
+func (c *Client) Closed() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.closed
+}
+
+// --- реализация ports.TelegramClient ---
+
+func (c *Client) GetMe() (int64, error) {
+	return c.selfID, nil
+}
+
+func (c *Client) JoinChannel(ch string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.joined = append(c.joined, ch)
+	return nil
+}
+
+func (c *Client) JoinChannels(chs []string) {
+	for _, ch := range chs {
+		_ = c.JoinChannel(ch)
+	}
+}
+
+func (c *Client) LeaveChannel(ch string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.left = append(c.left, ch)
+	return nil
+}
+
+func (c *Client) Listen() (<-chan domain.Message, error) {
+	return c.updates, nil
+}
+
+func (c *Client) IsChannelMember(username string) (bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.channelMember[username], nil
+}
+
+func (c *Client) IsMember(chatID int64) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if ok, set := c.member[chatID]; set {
+		return ok
+	}
+	return c.defaultMember
+}
+
+// Close закрывает канал Listen(); повторный вызов безопасен.
+func (c *Client) Close() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed {
+		return
+	}
+	c.closed = true
+	close(c.updates)
+}
+
+func (c *Client) StopReason() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.stopReason
+}
+
+func (c *Client) SendMessage(chatID int64, threadID int64, replyToMessageID int64, text string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.closed {
+		return fmt.Errorf("tgfake: client closed")
+	}
+	if len(c.sendErrs) > 0 {
+		err := c.sendErrs[0]
+		c.sendErrs = c.sendErrs[1:]
+		if err != nil {
+			return err
+		}
+	}
+
+	c.sent = append(c.sent, SentMessage{
+		ChatID:           chatID,
+		ThreadID:         threadID,
+		ReplyToMessageID: replyToMessageID,
+		Text:             text,
+	})
+	return nil
+}
+
+func (c *Client) SimulateTyping(chatID, threadID int64, text string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.typing = append(c.typing, TypingCall{ChatID: chatID, ThreadID: threadID, Text: text})
+}
+
+func (c *Client) ImitateReading(ctx context.Context, chatID int64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.reads = append(c.reads, chatID)
+}
+
+func (c *Client) ResolveUsername(username string) (int64, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.resolve = append(c.resolve, username)
+	id, ok := c.usernames[strings.TrimPrefix(username, "@")]
+	if !ok {
+		return 0, fmt.Errorf("tgfake: username %q not found", username)
+	}
+	return id, nil
+}
+
+// DownloadPhotos «скачивает» картинки в пути вида tgfake://photo/<id>.
+func (c *Client) DownloadPhotos(msg *domain.Message) {
+	if len(msg.PhotoFileIDs) == 0 || len(msg.PhotoFiles) > 0 {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, id := range msg.PhotoFileIDs {
+		c.downloads = append(c.downloads, id)
+		msg.PhotoFiles = append(msg.PhotoFiles, fmt.Sprintf("tgfake://photo/%d", id))
+	}
+	msg.PhotoFile = msg.PhotoFiles[0]
+}
+
+func (c *Client) CanSendToChat(chatID int64) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if ok, set := c.canSend[chatID]; set {
+		return ok
+	}
+	return c.defaultCanSend
+}
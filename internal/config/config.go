@@ -4,8 +4,10 @@ import (
 	"flag"
 	"fmt"
 	"os"
+	"path/filepath"
 	"strconv"
 	"strings"
+	"time"
 
 	"gopkg.in/yaml.v3"
 )
@@ -18,11 +20,226 @@ type AppConfig struct {
 	NeuroAddr  string `yaml:"neuro_addr"`
 	NeuroToken string `yaml:"neuro_token"`
 	Owner      string `yaml:"owner"`
+	Approval   bool   `yaml:"approval"`   // черновики сначала уходят Owner на одобрение
+	AdminAddr  string `yaml:"admin_addr"` // адрес админ-API, по умолчанию 127.0.0.1:7231
+	AdminToken string `yaml:"-"`          // только из ENV ADMIN_TOKEN
+
+	Store StoreConfig `yaml:"store"`
+	Queue QueueConfig `yaml:"queue"`
+
+	Supervisor SupervisorConfig `yaml:"supervisor"`
+	Vision     VisionConfig     `yaml:"vision"`
+	Thread     ThreadConfig     `yaml:"thread"`
+	Prompts    PromptsConfig    `yaml:"prompts"`
+
+	// Providers — LLM-провайдеры в порядке fallback; пусто — один openai по NEURO_ADDR/NEURO_TOKEN
+	Providers []ProviderConfig `yaml:"providers"`
+	// NeuroBreaker — circuit breaker провайдеров, общий для всех сессий
+	NeuroBreaker BreakerConfig `yaml:"neuro_breaker"`
+	// Usage — учёт токенов, цены моделей и лимиты расходов на LLM
+	Usage UsageConfig `yaml:"usage"`
+	// Validation — проверка ответа нейросети перед отправкой
+	Validation ValidationConfig `yaml:"validation"`
+	// Filter — какие посты не показывать нейросети вовсе
+	Filter FilterConfig `yaml:"filter"`
+	// Dedup — отсев ответов, повторяющих недавние комментарии
+	Dedup DedupConfig `yaml:"dedup"`
+	// Conversation — ответы на реплики к нашим комментариям (по умолчанию выключено)
+	Conversation ConversationConfig `yaml:"conversation"`
+	// Moderation — какие посты не комментировать и какие ответы не отправлять
+	Moderation ModerationConfig `yaml:"moderation"`
 
 	Session string `yaml:"session"` // имя сессии по умолчанию (может переопределяться флагом/ENV)
 	Auth    bool   `yaml:"-"`       // режим авторизации, управляется флагом/ENV, из yaml не читаем
 }
 
+// StoreConfig — где хранить отметки прокомментированных тредов и историю комментариев
+type StoreConfig struct {
+	Driver        string        `yaml:"driver"` // memory | file | redis
+	Path          string        `yaml:"path"`   // для file, по умолчанию <base_dir>/comment_store.json
+	RedisAddr     string        `yaml:"redis_addr"`
+	RedisPassword string        `yaml:"-"` // только из ENV REDIS_PASSWORD
+	RedisDB       int           `yaml:"redis_db"`
+	RedisPrefix   string        `yaml:"redis_prefix"`
+	TTL           time.Duration `yaml:"ttl"` // сколько помним тред/комментарий
+}
+
+// QueueConfig — очередь запланированных комментариев. Бэкенд тот же, что у store.driver.
+type QueueConfig struct {
+	Path         string        `yaml:"path"`          // для file, по умолчанию <base_dir>/comment_queue.json
+	PollInterval time.Duration `yaml:"poll_interval"` // как часто воркер смотрит в очередь
+	StaleAfter   time.Duration `yaml:"stale_after"`   // просроченные дольше этого задания выбрасываются
+}
+
+// PromptsConfig — шаблоны system-инструкций (Go text/template).
+// Переменные: {{.Channel}} (название канала), {{.Language}}, {{.Persona}};
+// текст поста в шаблон не подставляется — он уходит отдельным user-сообщением.
+// Сессия выбирает шаблон в <session>.json ("prompt": {"template": ..., "channels": {...}}).
+type PromptsConfig struct {
+	Default   string            `yaml:"default"` // имя шаблона по умолчанию; "" — встроенный
+	Language  string            `yaml:"language"`
+	Persona   string            `yaml:"persona"`
+	Templates map[string]string `yaml:"templates"`
+}
+
+// ProviderConfig — один LLM-провайдер в цепочке. Пустые model, temperature, max_tokens
+// и timeout берутся по умолчанию адаптера; temperature: 0 — именно ноль.
+type ProviderConfig struct {
+	Name        string        `yaml:"name"`      // метка в логах и метриках; по умолчанию type
+	Type        string        `yaml:"type"`      // openai | ollama | anthropic
+	Addr        string        `yaml:"addr"`      // полный URL endpoint
+	TokenEnv    string        `yaml:"token_env"` // переменная окружения с ключом API
+	Token       string        `yaml:"-"`         // только из ENV TokenEnv
+	Model       string        `yaml:"model"`
+	Temperature *float64      `yaml:"temperature"` // nil — по умолчанию адаптера
+	MaxTokens   int           `yaml:"max_tokens"`
+	Timeout     time.Duration `yaml:"timeout"` // на одну попытку
+}
+
+// BreakerConfig — когда перестать ходить к лежащему провайдеру и когда пробовать снова.
+// Пустые поля берутся по умолчанию адаптера.
+type BreakerConfig struct {
+	Failures       int           `yaml:"failures"`         // ошибок подряд до открытия; -1 — breaker выключен
+	OpenFor        time.Duration `yaml:"open_for"`         // сколько держать открытым до пробного запроса
+	HalfOpenProbes int           `yaml:"half_open_probes"` // одновременных пробных запросов
+}
+
+// ValidationConfig — правила для ответа нейросети. Пустые числа берутся по умолчанию
+// (как во встроенном промпте: до 12 слов, ровно одно эмодзи), -1 — без ограничения.
+type ValidationConfig struct {
+	Enabled        *bool    `yaml:"enabled"` // nil — включена
+	MaxWords       int      `yaml:"max_words"`
+	MaxChars       int      `yaml:"max_chars"`
+	MinEmoji       int      `yaml:"min_emoji"`
+	MaxEmoji       int      `yaml:"max_emoji"`
+	AllowLinks     bool     `yaml:"allow_links"`
+	AllowMentions  bool     `yaml:"allow_mentions"`
+	AllowQuestions bool     `yaml:"allow_questions"`
+	EchoWords      int      `yaml:"echo_words"`     // слов подряд из поста, чтобы считать ответ цитатой
+	BannedPhrases  []string `yaml:"banned_phrases"` // добавляются к встроенным
+	Regenerate     int      `yaml:"regenerate"`     // перегенераций до отказа от комментария
+}
+
+// FilterConfig — правила отбора постов до запроса к нейросети. Пустые значения берутся
+// по умолчанию, -1 в длине — без ограничения. Channels переопределяют правила для
+// канала (chat id или название): заданные поля заменяют общие, списки дописываются к общим.
+type FilterConfig struct {
+	Enabled           *bool `yaml:"enabled"` // nil — включен
+	FilterRulesConfig `yaml:",inline"`
+	Channels          map[string]FilterRulesConfig `yaml:"channels"`
+}
+
+// FilterRulesConfig — правила фильтра; строки ищутся как подстроки без учёта регистра, /.../ — регулярка
+type FilterRulesConfig struct {
+	MinChars      int      `yaml:"min_chars"`
+	MaxChars      int      `yaml:"max_chars"`
+	Include       []string `yaml:"include"` // хотя бы одно должно встретиться в посте
+	Exclude       []string `yaml:"exclude"`
+	SkipForwarded *bool    `yaml:"skip_forwarded"`
+	SkipLinks     *bool    `yaml:"skip_links"`
+	SkipAds       *bool    `yaml:"skip_ads"`
+	AdMarkers     []string `yaml:"ad_markers"` // добавляются к встроенным (#реклама, erid, розыгрыш…)
+}
+
+// DedupConfig — поиск повторов среди недавних комментариев. Пустые значения берутся
+// по умолчанию, -1 в размере истории — эту историю не смотреть.
+type DedupConfig struct {
+	Enabled        *bool   `yaml:"enabled"`         // nil — включен
+	Threshold      float64 `yaml:"threshold"`       // похожесть 0..1, с которой ответ считается повтором
+	SessionHistory int     `yaml:"session_history"` // последних комментариев своей сессии
+	GlobalHistory  int     `yaml:"global_history"`  // последних комментариев всех сессий
+}
+
+// ConversationConfig — режим переписки: отвечаем тем, кто ответил на наш комментарий.
+// Пустые лимиты берутся по умолчанию, -1 — без ограничения.
+type ConversationConfig struct {
+	Enabled      bool          `yaml:"enabled"`
+	MaxDepth     int           `yaml:"max_depth"`      // наших ответов в одной цепочке после исходного комментария
+	MaxPerUser   int           `yaml:"max_per_user"`   // ответов одному человеку за window
+	MaxPerThread int           `yaml:"max_per_thread"` // ответов в одном треде за window
+	Window       time.Duration `yaml:"window"`
+}
+
+// ModerationConfig — фильтр чувствительных тем для постов и ответов нейросети.
+// Выключен по умолчанию; при enabled: true локальные правила работают всегда,
+// LLM-классификатор — только при llm: true.
+type ModerationConfig struct {
+	Enabled    bool                `yaml:"enabled"`
+	Categories []string            `yaml:"categories"` // встроенные категории; пусто — все
+	Rules      map[string][]string `yaml:"rules"`      // своя категория -> слова, начала слов* или /регулярки/
+	LLM        bool                `yaml:"llm"`        // дополнительно спрашивать нейросеть
+	FailOpen   bool                `yaml:"fail_open"`  // классификатор не ответил — пропускать, а не блокировать
+}
+
+// UsageConfig — учёт расходов на LLM. Цены задаются за 1M токенов в одной валюте;
+// модель без цены учитывается только токенами. Лимиты 0 — без ограничения.
+type UsageConfig struct {
+	Path     string                  `yaml:"path"`     // для store.driver file/redis, по умолчанию <base_dir>/neuro_usage.json
+	Currency string                  `yaml:"currency"` // только для отчётов, по умолчанию USD
+	Prices   map[string]PriceConfig  `yaml:"prices"`   // имя модели -> цена
+	Budget   BudgetConfig            `yaml:"budget"`   // на все сессии вместе
+	Sessions map[string]BudgetConfig `yaml:"sessions"` // имя сессии -> её собственный лимит
+}
+
+// PriceConfig — цена модели за 1M токенов
+type PriceConfig struct {
+	Prompt     float64 `yaml:"prompt"`
+	Completion float64 `yaml:"completion"`
+}
+
+// BudgetConfig — лимит расходов; исчерпан — GetComment отказывает до следующего дня/месяца
+type BudgetConfig struct {
+	Daily   float64 `yaml:"daily"`
+	Monthly float64 `yaml:"monthly"`
+}
+
+// VisionConfig — отправка картинок постов в vision-модель.
+// В <session>.json можно переопределить Enabled полем "vision".
+type VisionConfig struct {
+	Enabled       bool  `yaml:"enabled"`
+	MaxImageBytes int64 `yaml:"max_image_bytes"` // картинки крупнее не скачиваем и не отправляем
+}
+
+// ThreadConfig — сколько уже написанных комментариев обсуждения показывать нейросети вместе с постом.
+// После загрузки 0 значит «выключено»: в yaml пустые поля берутся по умолчанию, -1 выключает.
+type ThreadConfig struct {
+	Replies   int `yaml:"replies"`    // последних комментариев из треда
+	MaxTokens int `yaml:"max_tokens"` // примерный бюджет токенов на них в промпте; старые отбрасываются первыми
+}
+
+// SupervisorConfig — политика перезапуска упавших сессий; пустые поля берутся по умолчанию
+type SupervisorConfig struct {
+	InitialBackoff time.Duration `yaml:"initial_backoff"`
+	MaxBackoff     time.Duration `yaml:"max_backoff"`
+	MaxRestarts    int           `yaml:"max_restarts"` // подряд; -1 — без ограничения
+	ResetAfter     time.Duration `yaml:"reset_after"`  // проработала дольше — счётчик рестартов обнуляется
+
+	// WatchInterval — как часто перечитывать base_dir в поисках новых/удалённых сессий; <0 — не следить
+	WatchInterval time.Duration `yaml:"watch_interval"`
+}
+
+const (
+	StoreDriverMemory = "memory"
+	StoreDriverFile   = "file"
+	StoreDriverRedis  = "redis"
+
+	defaultAdminAddr = "127.0.0.1:7231"
+
+	defaultStoreTTL = 30 * 24 * time.Hour
+
+	defaultQueuePollInterval = 30 * time.Second
+	defaultQueueStaleAfter   = 2 * time.Hour
+
+	defaultSessionsWatchInterval = 30 * time.Second
+
+	defaultMaxImageBytes = 4 << 20
+
+	defaultThreadReplies   = 10
+	defaultThreadMaxTokens = 300
+
+	defaultUsageCurrency = "USD"
+)
+
 // Load читает настройки из переменных окружения
 func Load() (*AppConfig, error) {
 	parseFlagsOnce()
@@ -38,11 +255,31 @@ func Load() (*AppConfig, error) {
 	neuroAddr := os.Getenv("NEURO_ADDR")
 	neuroToken := os.Getenv("NEURO_TOKEN")
 	owner := os.Getenv("OWNER")
+	approval := cfgFromFile.Approval
+	if v := os.Getenv("APPROVAL_MODE"); v != "" {
+		approval = v == "1" || strings.EqualFold(v, "true") || strings.EqualFold(v, "yes")
+	}
+	adminAddr := os.Getenv("ADMIN_ADDR")
+	if adminAddr == "" {
+		adminAddr = cfgFromFile.AdminAddr
+	}
+	if adminAddr == "" {
+		adminAddr = defaultAdminAddr
+	}
 	sessionFromEnv := os.Getenv("SESSION_NAME")
 	authEnv := os.Getenv("AUTH_MODE") // например "true"/"1"
 
-	if apiIDStr == "" || apiHash == "" || cfgFromFile.BaseDir == "" || neuroAddr == "" || neuroToken == "" {
-		return nil, fmt.Errorf("TELEGRAM_API_ID, TELEGRAM_API_HASH , BaseDir , NEURO_ADDR , NEURO_TOKEN должны быть заданы")
+	if apiIDStr == "" || apiHash == "" || cfgFromFile.BaseDir == "" {
+		return nil, fmt.Errorf("TELEGRAM_API_ID, TELEGRAM_API_HASH , BaseDir должны быть заданы")
+	}
+	// без списка провайдеров работаем по-старому: один endpoint из ENV
+	if len(cfgFromFile.Providers) == 0 && (neuroAddr == "" || neuroToken == "") {
+		return nil, fmt.Errorf("NEURO_ADDR , NEURO_TOKEN должны быть заданы, если в конфиге нет providers")
+	}
+
+	providers, err := loadProviders(cfgFromFile.Providers)
+	if err != nil {
+		return nil, err
 	}
 
 	apiID, err := strconv.Atoi(apiIDStr)
@@ -50,6 +287,45 @@ func Load() (*AppConfig, error) {
 		return nil, fmt.Errorf("invalid TELEGRAM_API_ID: %w", err)
 	}
 
+	storeCfg, err := loadStoreConfig(cfgFromFile.Store, cfgFromFile.BaseDir)
+	if err != nil {
+		return nil, err
+	}
+
+	queueCfg := loadQueueConfig(cfgFromFile.Queue, cfgFromFile.BaseDir)
+
+	visionCfg := cfgFromFile.Vision
+	if visionCfg.MaxImageBytes <= 0 {
+		visionCfg.MaxImageBytes = defaultMaxImageBytes
+	}
+
+	threadCfg := cfgFromFile.Thread
+	switch {
+	case threadCfg.Replies < 0:
+		threadCfg.Replies = 0
+	case threadCfg.Replies == 0:
+		threadCfg.Replies = defaultThreadReplies
+	}
+	switch {
+	case threadCfg.MaxTokens < 0:
+		threadCfg.Replies, threadCfg.MaxTokens = 0, 0
+	case threadCfg.MaxTokens == 0:
+		threadCfg.MaxTokens = defaultThreadMaxTokens
+	}
+
+	usageCfg := cfgFromFile.Usage
+	if usageCfg.Path == "" {
+		usageCfg.Path = filepath.Join(cfgFromFile.BaseDir, "neuro_usage.json")
+	}
+	if usageCfg.Currency == "" {
+		usageCfg.Currency = defaultUsageCurrency
+	}
+
+	supervisorCfg := cfgFromFile.Supervisor
+	if supervisorCfg.WatchInterval == 0 {
+		supervisorCfg.WatchInterval = defaultSessionsWatchInterval
+	}
+
 	// --- выбираем session: приоритет flag > ENV > yaml ---
 	sessionName := sessionFlag
 	if sessionName == "" {
@@ -67,18 +343,102 @@ func Load() (*AppConfig, error) {
 	}
 
 	return &AppConfig{
-		ApiID:      int32(apiID),
-		ApiHash:    apiHash,
-		Env:        cfgFromFile.Env,
-		BaseDir:    cfgFromFile.BaseDir,
-		NeuroAddr:  neuroAddr,
-		NeuroToken: neuroToken,
-		Owner:      owner,
-		Session:    sessionName,
-		Auth:       auth,
+		ApiID:        int32(apiID),
+		ApiHash:      apiHash,
+		Env:          cfgFromFile.Env,
+		BaseDir:      cfgFromFile.BaseDir,
+		NeuroAddr:    neuroAddr,
+		NeuroToken:   neuroToken,
+		Owner:        owner,
+		Approval:     approval,
+		AdminAddr:    adminAddr,
+		AdminToken:   os.Getenv("ADMIN_TOKEN"),
+		Store:        storeCfg,
+		Queue:        queueCfg,
+		Supervisor:   supervisorCfg,
+		Vision:       visionCfg,
+		Thread:       threadCfg,
+		Prompts:      cfgFromFile.Prompts,
+		Providers:    providers,
+		NeuroBreaker: cfgFromFile.NeuroBreaker,
+		Usage:        usageCfg,
+		Validation:   cfgFromFile.Validation,
+		Filter:       cfgFromFile.Filter,
+		Dedup:        cfgFromFile.Dedup,
+		Conversation: cfgFromFile.Conversation,
+		Moderation:   cfgFromFile.Moderation,
+		Session:      sessionName,
+		Auth:         auth,
 	}, nil
 }
 
+// loadProviders проверяет цепочку провайдеров и подтягивает ключи из ENV
+func loadProviders(pcs []ProviderConfig) ([]ProviderConfig, error) {
+	out := make([]ProviderConfig, 0, len(pcs))
+	seen := make(map[string]bool, len(pcs))
+	for i, pc := range pcs {
+		if pc.Type == "" || pc.Addr == "" {
+			return nil, fmt.Errorf("providers[%d]: type и addr должны быть заданы", i)
+		}
+		if pc.Name == "" {
+			pc.Name = pc.Type
+		}
+		if seen[pc.Name] {
+			return nil, fmt.Errorf("providers[%d]: имя %q уже занято", i, pc.Name)
+		}
+		seen[pc.Name] = true
+		if pc.TokenEnv != "" {
+			pc.Token = os.Getenv(pc.TokenEnv)
+		}
+		out = append(out, pc)
+	}
+	return out, nil
+}
+
+// loadStoreConfig проставляет значения по умолчанию и переопределения из ENV
+func loadStoreConfig(sc StoreConfig, baseDir string) (StoreConfig, error) {
+	if addr := os.Getenv("REDIS_ADDR"); addr != "" {
+		sc.RedisAddr = addr
+	}
+	sc.RedisPassword = os.Getenv("REDIS_PASSWORD")
+
+	if sc.Driver == "" {
+		sc.Driver = StoreDriverFile
+	}
+	if sc.TTL <= 0 {
+		sc.TTL = defaultStoreTTL
+	}
+
+	switch sc.Driver {
+	case StoreDriverMemory:
+	case StoreDriverFile:
+		if sc.Path == "" {
+			// файл, а не папка: каждая папка в base_dir считается сессией
+			sc.Path = filepath.Join(baseDir, "comment_store.json")
+		}
+	case StoreDriverRedis:
+		if sc.RedisAddr == "" {
+			return sc, fmt.Errorf("store.driver=redis требует store.redis_addr или REDIS_ADDR")
+		}
+	default:
+		return sc, fmt.Errorf("unknown store.driver %q", sc.Driver)
+	}
+	return sc, nil
+}
+
+func loadQueueConfig(qc QueueConfig, baseDir string) QueueConfig {
+	if qc.Path == "" {
+		qc.Path = filepath.Join(baseDir, "comment_queue.json")
+	}
+	if qc.PollInterval <= 0 {
+		qc.PollInterval = defaultQueuePollInterval
+	}
+	if qc.StaleAfter <= 0 {
+		qc.StaleAfter = defaultQueueStaleAfter
+	}
+	return qc
+}
+
 func MustLoadPath(path string) (*AppConfig, error) {
 	data, err := os.ReadFile(path)
 	if err != nil {
@@ -19,6 +19,8 @@ func NewJSONSessionConfigRepo(baseDir string) *JSONSessionConfigRepo {
 	return &JSONSessionConfigRepo{baseDir: baseDir}
 }
 
+// ListSessions возвращает папки base_dir, в которых лежит <name>/<name>.json.
+// Папка без конфига считается недописанной и пропускается — Runner подхватит её позже.
 func (r *JSONSessionConfigRepo) ListSessions(ctx context.Context) ([]string, error) {
 	entries, err := os.ReadDir(r.baseDir)
 	if err != nil {
@@ -26,10 +28,13 @@ func (r *JSONSessionConfigRepo) ListSessions(ctx context.Context) ([]string, err
 	}
 	out := make([]string, 0, len(entries))
 	for _, e := range entries {
-		if e.IsDir() {
-			fmt.Println("e.Name()", e.Name())
-			out = append(out, e.Name())
+		if !e.IsDir() {
+			continue
 		}
+		if _, err := os.Stat(filepath.Join(r.baseDir, e.Name(), e.Name()+".json")); err != nil {
+			continue
+		}
+		out = append(out, e.Name())
 	}
 	return out, nil
 }
@@ -69,5 +74,7 @@ func (r *JSONSessionConfigRepo) GetSessionConfig(ctx context.Context, sessionNam
 		LangCode:           raw.LangCode,
 		Proxy:              proxyCfg,
 		Channels:           raw.Channels,
+		Vision:             raw.Vision,
+		Prompt:             raw.ToPromptSettings(),
 	}, nil
 }
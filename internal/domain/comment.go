@@ -0,0 +1,60 @@
+package domain
+
+import "time"
+
+// ThreadKey идентифицирует тред обсуждения в рамках сессии
+type ThreadKey struct {
+	Session  string
+	ChatID   int64
+	ThreadID int64
+}
+
+// SentComment — комментарий, который сессия уже отправила
+type SentComment struct {
+	Session  string    `json:"session"`
+	ChatID   int64     `json:"chat_id"`
+	ThreadID int64     `json:"thread_id"`
+	ChatName string    `json:"chat_name,omitempty"`
+	Text     string    `json:"text"`
+	SentAt   time.Time `json:"sent_at"`
+	// кому ответили в режиме переписки; 0 — обычный комментарий к посту
+	ReplyToUser int64 `json:"reply_to_user,omitempty"`
+}
+
+// CommentJob — запланированный комментарий: текст уже сгенерирован,
+// отправка откладывается до DueAt
+type CommentJob struct {
+	ID        string    `json:"id"`
+	Session   string    `json:"session"`
+	Post      Message   `json:"post"`
+	Text      string    `json:"text"`
+	DueAt     time.Time `json:"due_at"`
+	CreatedAt time.Time `json:"created_at"`
+	Approved  bool      `json:"approved,omitempty"` // владелец уже видел текст, уведомление после отправки не нужно
+}
+
+// SkipReason — почему пост остался без комментария (метки метрик и логов)
+type SkipReason string
+
+const (
+	SkipAlreadySeen   SkipReason = "already_seen"
+	SkipFiltered      SkipReason = "filtered" // пост отсеян правилами фильтра до нейросети
+	SkipPaused        SkipReason = "paused"
+	SkipRateLimited   SkipReason = "rate_limited"
+	SkipCannotSend    SkipReason = "cannot_send"
+	SkipNotMember     SkipReason = "not_member"
+	SkipNeuroError    SkipReason = "neuro_error"
+	SkipNeuroQuota    SkipReason = "neuro_quota"        // у всех LLM-провайдеров кончилась квота
+	SkipNeuroCircuit  SkipReason = "neuro_circuit_open" // breaker не пустил к провайдерам
+	SkipNeuroBudget   SkipReason = "neuro_budget"       // исчерпан лимит расходов на LLM
+	SkipEmptyLLM      SkipReason = "empty_llm"
+	SkipInvalidLLM    SkipReason = "invalid_llm"        // ответ не прошёл валидатор и после перегенераций
+	SkipSensitivePost SkipReason = "sensitive_post"     // пост на чувствительную тему (модерация)
+	SkipUnsafeReply   SkipReason = "unsafe_reply"       // ответ не прошёл модерацию и после перегенераций
+	SkipModeration    SkipReason = "moderation_error"   // модерация не смогла решить
+	SkipDuplicate     SkipReason = "duplicate"          // ответ повторяет недавний комментарий и после перегенераций
+	SkipConversation  SkipReason = "conversation_limit" // исчерпаны лимиты режима переписки
+	SkipStale         SkipReason = "stale"
+	SkipRejected      SkipReason = "rejected"      // владелец отклонил черновик
+	SkipDraftExpired  SkipReason = "draft_expired" // владелец не ответил на черновик вовремя
+)
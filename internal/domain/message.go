@@ -1,5 +1,7 @@
 package domain
 
+import "time"
+
 // Message описывает входящее сообщение из Telegram
 
 type Message struct {
@@ -8,6 +10,94 @@ type Message struct {
 	ChatName        string
 	Text            string
 	PhotoFile       string
+	PhotoFiles      []string // все картинки альбома по порядку; PhotoFile — первая из них
+	PhotoFileIDs    []int32  // картинки в Telegram; скачиваются в PhotoFiles через TelegramClient.DownloadPhotos
 	MessageThreadId int64
 	ReplyToMessageID int64
+	HasLinks        bool // в тексте есть ссылки, в том числе скрытые под словами
+
+	// метаданные поста канала; у альбома — по сообщению с подписью, а без неё — по первому
+	PostID          int64 // id сообщения в канале; у альбома — первого
+	PostDate        time.Time
+	ChannelUsername string       // без @; пусто у закрытого канала
+	Link            string       // ссылка на пост, у альбома — на весь альбом
+	ContentType     string       // ContentText, ContentPhoto, ..., ContentAlbum
+	Entities        []TextEntity // ссылки, хэштеги и упоминания из текста
+	Views           int
+	Forwards        int
+	Reactions       int            // всего реакций, всех видов
+	ForwardOrigin   *ForwardOrigin // откуда репост; nil — собственный пост канала
+	AlbumID         int64
+	AlbumSize       int // сообщений в альбоме; 0 — не альбом
+
+	// последние комментарии обсуждения, старые первыми; пусто — тред пуст или контекст выключен
+	Thread []ThreadReply
+
+	// личные сообщения (команды владельца в режиме одобрения)
+	IsPrivate bool
+	SenderID  int64 // автор: владелец в личке или собеседник в режиме переписки
+
+	// ответ на наш комментарий (режим переписки): Text — пост канала,
+	// ReplyChain — цепочка от нашего комментария до реплики собеседника, старые первыми
+	IsReply    bool
+	ReplyChain []ThreadReply
+	// ReplyChainTruncated — до корня треда не дошли, начало переписки в ReplyChain не попало
+	ReplyChainTruncated bool
+}
+
+// ThreadReply — уже написанный комментарий в обсуждении поста
+type ThreadReply struct {
+	MessageID int64
+	Text      string
+	Own       bool // наш собственный комментарий
 }
+
+// Типы содержимого поста (Message.ContentType)
+const (
+	ContentText      = "text"
+	ContentPhoto     = "photo"
+	ContentVideo     = "video"
+	ContentAnimation = "animation"
+	ContentDocument  = "document"
+	ContentAudio     = "audio"
+	ContentVoice     = "voice"
+	ContentPoll      = "poll"
+	ContentAlbum     = "album"
+	ContentOther     = "other"
+)
+
+// TextEntity — размеченный фрагмент текста поста
+type TextEntity struct {
+	Type   string // EntityURL, EntityTextURL, ...
+	Text   string // фрагмент как в тексте: ссылка, #хэштег, @упоминание
+	URL    string // адрес ссылки: у EntityURL совпадает с Text, у EntityTextURL спрятан под словами
+	UserID int64  // у EntityMentionName — кого упомянули
+}
+
+// Типы TextEntity
+const (
+	EntityURL         = "url"
+	EntityTextURL     = "text_url" // ссылка, спрятанная под словами
+	EntityEmail       = "email"
+	EntityHashtag     = "hashtag"
+	EntityCashtag     = "cashtag"
+	EntityMention     = "mention"      // @username
+	EntityMentionName = "mention_name" // упоминание пользователя без username
+)
+
+// ForwardOrigin — источник репоста
+type ForwardOrigin struct {
+	Kind      string // ForwardFromChannel, ForwardFromChat, ForwardFromUser, ForwardFromHidden
+	ChatID    int64  // канал или чат; у ForwardFromUser — id пользователя
+	MessageID int64  // id исходного поста канала
+	Name      string // название канала или чата, имя скрытого отправителя; может быть пустым
+	Date      time.Time
+}
+
+// Виды ForwardOrigin
+const (
+	ForwardFromChannel = "channel"
+	ForwardFromChat    = "chat"
+	ForwardFromUser    = "user"
+	ForwardFromHidden  = "hidden_user"
+)
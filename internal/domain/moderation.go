@@ -0,0 +1,28 @@
+package domain
+
+// ModerationTarget — что проверяем: входящий пост или сгенерированный ответ
+type ModerationTarget string
+
+const (
+	ModeratePost  ModerationTarget = "post"
+	ModerateReply ModerationTarget = "reply"
+)
+
+// Встроенные категории модерации; свои задаются в конфиге
+const (
+	ModerationTragedy   = "tragedy"   // катастрофы, гибель людей, теракты
+	ModerationPolitics  = "politics"  // выборы, война, политики
+	ModerationAdult     = "adult"     // 18+
+	ModerationOffensive = "offensive" // мат и оскорбления
+)
+
+// ModerationCategories — все встроенные категории
+var ModerationCategories = []string{ModerationAdult, ModerationOffensive, ModerationPolitics, ModerationTragedy}
+
+// ModerationVerdict — решение модерации и что именно его вызвало (для логов)
+type ModerationVerdict struct {
+	Flagged  bool
+	Category string
+	Rule     string // ключевое слово, регулярка или ответ классификатора
+	Source   string // "rules" или "llm"
+}
@@ -1,13 +1,18 @@
 package domain
 
-import "encoding/json"
+import (
+	"encoding/json"
+	"time"
+)
 
 type NeuroModel string
 type MessageRole string
 
 const (
-	MistralModel string      = "mistralai/mistral-small-3.2-24b-instruct"
-	RoleUser     MessageRole = "user"
+	MistralModel  string      = "mistralai/mistral-small-3.2-24b-instruct"
+	RoleSystem    MessageRole = "system"    // инструкции модели
+	RoleUser      MessageRole = "user"      // данные: текст поста, картинки
+	RoleAssistant MessageRole = "assistant" // прошлые ответы модели
 )
 
 type ImageUrl struct {
@@ -28,13 +33,31 @@ type NeuroMessage struct {
 type DefaultNeuroBody struct {
 	Model            string         `json:"model"`
 	Messages         []NeuroMessage `json:"messages"`
-	Temperature      float64        `json:"temperature,omitempty"`
+	Temperature      float64        `json:"temperature"`
 	TopP             float64        `json:"top_p,omitempty"`
 	PresencePenalty  float64        `json:"presence_penalty,omitempty"`
 	FrequencyPenalty float64        `json:"frequency_penalty,omitempty"`
 	MaxTokens        int            `json:"max_tokens,omitempty"`
 }
 
+// BreakerState — состояние circuit breaker LLM-провайдера
+type BreakerState string
+
+const (
+	BreakerClosed   BreakerState = "closed"    // запросы идут как обычно
+	BreakerOpen     BreakerState = "open"      // провайдер лежит, запросы отбиваются сразу
+	BreakerHalfOpen BreakerState = "half_open" // пробные запросы: удача закрывает, ошибка снова открывает
+)
+
+// BreakerStatus — снимок circuit breaker для админки
+type BreakerStatus struct {
+	Provider string       `json:"provider"`
+	State    BreakerState `json:"state"`
+	Failures int          `json:"failures"` // ошибок подряд
+	OpenedAt *time.Time   `json:"opened_at,omitempty"`
+	RetryAt  *time.Time   `json:"retry_at,omitempty"` // когда пойдёт пробный запрос
+}
+
 // NeuroResponse соответствует корневому JSON-объекту.
 type NeuroResponse struct {
 	ID      string   `json:"id"`
@@ -66,3 +89,72 @@ type MessageResponse struct {
 	ToolCalls []json.RawMessage `json:"tool_calls,omitempty"`
 	Prefix    bool              `json:"prefix"`
 }
+
+// --- Ollama: POST /api/chat ---
+
+type OllamaMessage struct {
+	Role    MessageRole `json:"role"`
+	Content string      `json:"content"`
+	Images  []string    `json:"images,omitempty"` // base64 без префикса data:
+}
+
+type OllamaOptions struct {
+	Temperature float64 `json:"temperature"`
+	NumPredict  int     `json:"num_predict,omitempty"` // аналог max_tokens
+}
+
+type OllamaChatBody struct {
+	Model    string          `json:"model"`
+	Messages []OllamaMessage `json:"messages"`
+	Stream   bool            `json:"stream"`
+	Options  OllamaOptions   `json:"options"`
+}
+
+type OllamaChatResponse struct {
+	Model           string        `json:"model"`
+	Message         OllamaMessage `json:"message"`
+	Done            bool          `json:"done"`
+	PromptEvalCount int           `json:"prompt_eval_count"`
+	EvalCount       int           `json:"eval_count"`
+}
+
+// --- Anthropic: POST /v1/messages ---
+
+type AnthropicImageSource struct {
+	Type      string `json:"type"` // "base64" или "url"
+	MediaType string `json:"media_type,omitempty"`
+	Data      string `json:"data,omitempty"`
+	URL       string `json:"url,omitempty"`
+}
+
+type AnthropicContent struct {
+	Type   string                `json:"type"` // "text" или "image"
+	Text   string                `json:"text,omitempty"`
+	Source *AnthropicImageSource `json:"source,omitempty"`
+}
+
+type AnthropicMessage struct {
+	Role    MessageRole        `json:"role"`
+	Content []AnthropicContent `json:"content"`
+}
+
+type AnthropicMessagesBody struct {
+	Model       string             `json:"model"`
+	System      string             `json:"system,omitempty"`
+	Messages    []AnthropicMessage `json:"messages"`
+	MaxTokens   int                `json:"max_tokens"`
+	Temperature float64            `json:"temperature"`
+}
+
+type AnthropicUsage struct {
+	InputTokens  int `json:"input_tokens"`
+	OutputTokens int `json:"output_tokens"`
+}
+
+type AnthropicResponse struct {
+	ID         string             `json:"id"`
+	Model      string             `json:"model"`
+	Content    []AnthropicContent `json:"content"`
+	StopReason string             `json:"stop_reason"`
+	Usage      AnthropicUsage     `json:"usage"`
+}
@@ -1,5 +1,13 @@
 package domain
 
+import (
+	"errors"
+	"time"
+)
+
+// ErrSessionNotFound — сессия с таким именем не запущена
+var ErrSessionNotFound = errors.New("session not found")
+
 type Session struct {
 	SessionName string
 	Phone       string
@@ -12,3 +20,58 @@ type Session struct {
 
 	// можно добавить флаги "активен/забанен" и т.п.
 }
+
+// SessionState — состояние запущенной сессии
+type SessionState string
+
+const (
+	SessionStarting    SessionState = "starting"
+	SessionAuthorized  SessionState = "authorized"
+	SessionRateLimited SessionState = "rate_limited"
+	SessionRestarting  SessionState = "restarting"
+	SessionStopped     SessionState = "stopped"
+	SessionFailed      SessionState = "failed"
+)
+
+// SessionStatus — снимок состояния сессии для админки
+type SessionStatus struct {
+	Name            string       `json:"name"`
+	State           SessionState `json:"state"`
+	Error           string       `json:"error,omitempty"`
+	Paused          bool         `json:"paused"`
+	PausedChats     []int64      `json:"paused_chats,omitempty"`
+	PendingComments int          `json:"pending_comments"`
+	PendingDrafts   int          `json:"pending_drafts,omitempty"` // черновики, ждущие решения владельца
+	LastCommentAt   *time.Time   `json:"last_comment_at,omitempty"`
+	// RateLimitedUntil — до какого момента сессия ждёт FLOOD_WAIT
+	RateLimitedUntil *time.Time `json:"rate_limited_until,omitempty"`
+	// NeuroQuotaUntil — до какого момента не зовём нейросеть: у провайдеров кончилась квота
+	NeuroQuotaUntil *time.Time `json:"neuro_quota_until,omitempty"`
+	StartedAt       time.Time  `json:"started_at"`
+	Restarts        int        `json:"restarts"` // рестартов подряд с последнего сброса счётчика
+}
+
+// SessionEventType — что произошло с сессией
+type SessionEventType string
+
+const (
+	SessionEventStarted    SessionEventType = "started"    // клиент поднят и авторизован
+	SessionEventStopped    SessionEventType = "stopped"    // клиент закрылся или не поднялся
+	SessionEventRestarting SessionEventType = "restarting" // ждём backoff перед новой попыткой
+	SessionEventFailed     SessionEventType = "failed"     // больше не перезапускаем
+	SessionEventShutdown   SessionEventType = "shutdown"   // остановлена вместе с приложением
+	SessionEventAdded      SessionEventType = "added"      // появилась новая папка сессии
+	SessionEventRemoved    SessionEventType = "removed"    // папка сессии удалена, сессия остановлена
+	SessionEventReloaded   SessionEventType = "reloaded"   // клиент переподписан на новые Channels
+)
+
+// SessionEvent — событие жизненного цикла сессии для подписчиков Runner
+type SessionEvent struct {
+	Session   string           `json:"session"`
+	Type      SessionEventType `json:"type"`
+	Error     string           `json:"error,omitempty"`
+	Permanent bool             `json:"permanent,omitempty"` // ошибка не лечится перезапуском
+	Restarts  int              `json:"restarts"`
+	Backoff   time.Duration    `json:"backoff,omitempty"`
+	At        time.Time        `json:"at"`
+}
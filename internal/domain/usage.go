@@ -0,0 +1,56 @@
+package domain
+
+import "time"
+
+// UsageDayLayout — формат дня в UsageRecord.Day (по локальному времени процесса)
+const UsageDayLayout = "2006-01-02"
+
+// UsageRecord — расход LLM за один день в разрезе сессии, канала, провайдера и модели.
+// Ledger складывает запросы с одинаковым ключом в одну строку.
+type UsageRecord struct {
+	Day      string `json:"day"`
+	Session  string `json:"session"`
+	Channel  string `json:"channel"`
+	Provider string `json:"provider"`
+	Model    string `json:"model"`
+	UsageTotals
+}
+
+// Key — всё, кроме счётчиков
+func (r UsageRecord) Key() string {
+	return r.Day + "|" + r.Session + "|" + r.Channel + "|" + r.Provider + "|" + r.Model
+}
+
+// UsageTotals — сумма запросов, токенов и денег
+type UsageTotals struct {
+	Requests         int     `json:"requests"`
+	PromptTokens     int     `json:"prompt_tokens"`
+	CompletionTokens int     `json:"completion_tokens"`
+	Cost             float64 `json:"cost"` // в валюте таблицы цен
+}
+
+func (t *UsageTotals) Add(o UsageTotals) {
+	t.Requests += o.Requests
+	t.PromptTokens += o.PromptTokens
+	t.CompletionTokens += o.CompletionTokens
+	t.Cost += o.Cost
+}
+
+// UsageBudget — лимит расходов; 0 — без лимита
+type UsageBudget struct {
+	Daily   float64 `json:"daily,omitempty"`
+	Monthly float64 `json:"monthly,omitempty"`
+}
+
+// UsageReport — сводка расходов за текущий календарный месяц
+type UsageReport struct {
+	Session   string                 `json:"session,omitempty"` // пусто — по всем сессиям
+	Currency  string                 `json:"currency"`
+	Since     time.Time              `json:"since"` // начало месяца
+	Today     UsageTotals            `json:"today"`
+	Month     UsageTotals            `json:"month"`
+	Budget    UsageBudget            `json:"budget"` // лимит сессии, а без Session — общий
+	BySession map[string]UsageTotals `json:"by_session"`
+	ByChannel map[string]UsageTotals `json:"by_channel"`
+	ByModel   map[string]UsageTotals `json:"by_model"`
+}
@@ -0,0 +1,23 @@
+package ports
+
+import (
+	"context"
+	"time"
+
+	"github.com/larriantoniy/tg_user_bot/internal/domain"
+)
+
+// CommentQueue — персистентная очередь запланированных комментариев
+type CommentQueue interface {
+	Enqueue(ctx context.Context, job domain.CommentJob) error
+	// Due возвращает задания сессии с DueAt <= now, самые ранние первыми
+	Due(ctx context.Context, session string, now time.Time, limit int) ([]domain.CommentJob, error)
+	// Remove удаляет выполненное или отброшенное задание
+	Remove(ctx context.Context, job domain.CommentJob) error
+	// Pending возвращает ещё не отправленные задания сессии, самые ранние первыми.
+	// Пустой session — по всем сессиям; limit <= 0 — все.
+	Pending(ctx context.Context, session string, limit int) ([]domain.CommentJob, error)
+	// Len возвращает число заданий сессии в очереди
+	Len(ctx context.Context, session string) (int, error)
+	Close() error
+}
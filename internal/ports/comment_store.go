@@ -0,0 +1,24 @@
+package ports
+
+import (
+	"context"
+
+	"github.com/larriantoniy/tg_user_bot/internal/domain"
+)
+
+// CommentStore хранит отметки о прокомментированных тредах и историю
+// отправленных комментариев. Записи живут ограниченное время (TTL задаёт реализация).
+type CommentStore interface {
+	// MarkSeen отмечает тред. Возвращает false, если тред уже был отмечен и запись не истекла.
+	MarkSeen(ctx context.Context, key domain.ThreadKey) (bool, error)
+	// UnmarkSeen снимает отметку, если комментарий к треду так и не запланировали
+	UnmarkSeen(ctx context.Context, key domain.ThreadKey) error
+	// AddSent добавляет комментарий в историю
+	AddSent(ctx context.Context, c domain.SentComment) error
+	// RecentSent возвращает последние комментарии сессии, новые первыми.
+	// Пустой session — по всем сессиям.
+	RecentSent(ctx context.Context, session string, limit int) ([]domain.SentComment, error)
+	// RecentSentExcept возвращает последние комментарии всех сессий, кроме session, новые первыми
+	RecentSentExcept(ctx context.Context, session string, limit int) ([]domain.SentComment, error)
+	Close() error
+}
@@ -0,0 +1,23 @@
+package ports
+
+import (
+	"time"
+
+	"github.com/larriantoniy/tg_user_bot/internal/domain"
+)
+
+// Metrics — счётчики и гистограммы по сессиям
+type Metrics interface {
+	// PostReceived — пришёл пост канала с тредом обсуждения
+	PostReceived(session string)
+	// CommentSkipped — пост или запланированный комментарий отброшен
+	CommentSkipped(session string, reason domain.SkipReason)
+	// CommentSent — комментарий отправлен
+	CommentSent(session string)
+	// NeuroCall — один HTTP-запрос к LLM: длительность, токены (при успехе) и ошибка
+	NeuroCall(session, model string, d time.Duration, usage domain.Usage, err error)
+	// NeuroBreakerState — circuit breaker провайдера перешёл в новое состояние
+	NeuroBreakerState(provider string, state domain.BreakerState)
+	// NeuroCost — деньги за успешный запрос к LLM по таблице цен
+	NeuroCost(session, model string, cost float64)
+}
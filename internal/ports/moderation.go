@@ -0,0 +1,12 @@
+package ports
+
+import (
+	"context"
+
+	"github.com/larriantoniy/tg_user_bot/internal/domain"
+)
+
+// Moderator решает, можно ли комментировать пост и можно ли отправлять ответ
+type Moderator interface {
+	Moderate(ctx context.Context, target domain.ModerationTarget, text string) (domain.ModerationVerdict, error)
+}
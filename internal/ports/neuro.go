@@ -2,10 +2,58 @@ package ports
 
 import (
 	"context"
+	"errors"
+	"time"
 
 	"github.com/larriantoniy/tg_user_bot/internal/domain"
 )
 
+// Классы ошибок нейросети: по ним Sender решает, ждать ли квоту или просто пропустить пост.
+var (
+	// ErrNeuroQuota — у провайдера кончились квота или деньги; повторы до RetryAfter бесполезны
+	ErrNeuroQuota = errors.New("neuro: quota exhausted")
+	// ErrNeuroTransient — 429, 5xx, сеть или таймаут; следующая попытка может пройти
+	ErrNeuroTransient = errors.New("neuro: temporary failure")
+	// ErrNeuroCircuitOpen — провайдер подряд не отвечал, circuit breaker не пускает к нему запросы
+	ErrNeuroCircuitOpen = errors.New("neuro: circuit open")
+	// ErrNeuroBudget — дневной или месячный лимит расходов исчерпан, запросы не отправляются
+	ErrNeuroBudget = errors.New("neuro: budget exceeded")
+	// ErrNeuroEmpty — модель ответила без текста; для Sender это пустой ответ, а не сбой
+	ErrNeuroEmpty = errors.New("neuro: empty response")
+)
+
+// NeuroError — ошибка запроса к LLM-провайдеру.
+// errors.Is(err, Kind) для неё true; Kind == nil — запрос неверен и повтор не поможет.
+// Для ErrNeuroCircuitOpen RetryAfter — сколько breaker ещё будет открыт,
+// для ErrNeuroBudget — сколько до начала следующего дня или месяца.
+type NeuroError struct {
+	Status     int           // HTTP-статус; 0 — до ответа дело не дошло
+	RetryAfter time.Duration // из заголовка Retry-After; 0 — не прислали
+	Kind       error         // ErrNeuroQuota, ErrNeuroTransient, ErrNeuroCircuitOpen, ErrNeuroBudget или nil
+	Err        error
+}
+
+func (e *NeuroError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *NeuroError) Unwrap() error {
+	return e.Err
+}
+
+func (e *NeuroError) Is(target error) bool {
+	return e.Kind != nil && target == e.Kind
+}
+
+// NeuroRetryAfter достаёт Retry-After из ошибки нейросети; ok=false, если провайдер его не прислал
+func NeuroRetryAfter(err error) (time.Duration, bool) {
+	var ne *NeuroError
+	if errors.As(err, &ne) && ne.RetryAfter > 0 {
+		return ne.RetryAfter, true
+	}
+	return 0, false
+}
+
 type NeuroProccesor interface {
 	GetComment(ctx context.Context, msg *domain.Message) (string, error)
 }
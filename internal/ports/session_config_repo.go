@@ -23,6 +23,16 @@ type SessionConfig struct {
 	LangCode           string
 	Proxy              *ProxyConfig
 	Channels           []string
+	Vision             *bool // nil — как в общем конфиге
+	Prompt             PromptSettings
+}
+
+// PromptSettings — выбор шаблона промпта сессией; пустые поля берутся из общего конфига
+type PromptSettings struct {
+	Template string // имя шаблона
+	Language string
+	Persona  string
+	Channels map[string]string // chat id или название канала → имя шаблона
 }
 type SessionConfigRepo interface {
 	// Возвращает список доступных сессий (по именам)
@@ -2,10 +2,51 @@ package ports
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"time"
 
 	"github.com/larriantoniy/tg_user_bot/internal/domain"
 )
 
+// ErrRateLimited возвращается SendMessage, когда Telegram ответил "too many requests".
+var ErrRateLimited = errors.New("tdlib: too many requests")
+
+// RateLimitError — FLOOD_WAIT от Telegram: сколько нужно подождать до следующей отправки.
+// errors.Is(err, ErrRateLimited) для него тоже true.
+type RateLimitError struct {
+	RetryAfter time.Duration
+}
+
+func (e *RateLimitError) Error() string {
+	return fmt.Sprintf("%s: retry after %s", ErrRateLimited, e.RetryAfter)
+}
+
+func (e *RateLimitError) Is(target error) bool {
+	return target == ErrRateLimited
+}
+
+// RetryAfter достаёт время ожидания из ошибки; ok=false, если это не RateLimitError
+func RetryAfter(err error) (time.Duration, bool) {
+	var rl *RateLimitError
+	if errors.As(err, &rl) {
+		return rl.RetryAfter, true
+	}
+	return 0, false
+}
+
+// Постоянные ошибки сессии: перезапуск клиента их не лечит, нужен человек.
+var (
+	ErrUnauthorized = errors.New("tdlib: session is not authorized")
+	ErrBanned       = errors.New("tdlib: account is banned")
+	ErrDeactivated  = errors.New("tdlib: account is deactivated")
+)
+
+// IsPermanent сообщает, что сессию бессмысленно перезапускать
+func IsPermanent(err error) bool {
+	return errors.Is(err, ErrUnauthorized) || errors.Is(err, ErrBanned) || errors.Is(err, ErrDeactivated)
+}
+
 // TelegramClient определяет интерфейс для работы с Telegram
 // Реализуется конкретными адаптерами (TDLib, Bot API и т.д.).
 type TelegramClient interface {
@@ -14,12 +55,16 @@ type TelegramClient interface {
 	JoinChannel(ch string) error
 	// JoinChannels подписывается на список каналов
 	JoinChannels(chs []string)
+	// LeaveChannel отписывается от канала (username или invite-ссылка)
+	LeaveChannel(ch string) error
 	// Listen возвращает канал доменных сообщений
 	Listen() (<-chan domain.Message, error)
 	// IsChannelMember проверяет есть ли username в чате
 	IsChannelMember(username string) (bool, error)
 	IsMember(chatID int64) bool
 	Close()
+	// StopReason — почему закрылся канал Listen (nil — штатный Close)
+	StopReason() error
 	SendMessage(chatID int64,
 		threadID int64, // может быть 0
 		replyToMessageID int64, // может быть 0
@@ -28,4 +73,7 @@ type TelegramClient interface {
 	ImitateReading(ctx context.Context, chatID int64)
 	ResolveUsername(username string) (int64, error)
 	CanSendToChat(chatID int64) bool
+	// DownloadPhotos скачивает картинки поста (PhotoFileIDs) и заполняет PhotoFiles.
+	// Зовётся только для постов, которые дошли до нейросети.
+	DownloadPhotos(msg *domain.Message)
 }
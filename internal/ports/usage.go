@@ -0,0 +1,21 @@
+package ports
+
+import (
+	"context"
+
+	"github.com/larriantoniy/tg_user_bot/internal/domain"
+)
+
+// UsageLedger хранит дневной расход LLM: одна строка на день, сессию, канал, провайдера и модель
+type UsageLedger interface {
+	// Add прибавляет rec к строке с тем же rec.Key()
+	Add(ctx context.Context, rec domain.UsageRecord) error
+	// Since возвращает строки с rec.Day >= day (формат domain.UsageDayLayout)
+	Since(ctx context.Context, day string) ([]domain.UsageRecord, error)
+	Close() error
+}
+
+// UsageReporter отдаёт сводку расходов; пустой session — по всем сессиям
+type UsageReporter interface {
+	UsageReport(ctx context.Context, session string) (domain.UsageReport, error)
+}
@@ -0,0 +1,250 @@
+package useCases
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/larriantoniy/tg_user_bot/internal/domain"
+)
+
+// Режим одобрения: сгенерированный комментарий не ставится в очередь сразу,
+// а уходит владельцу в личку черновиком. Владелец отвечает командой:
+//
+//	/approve <id>        — поставить в очередь как есть
+//	/reject <id>         — выбросить
+//	/edit <id> <текст>   — заменить текст и прислать черновик заново
+//	/regen <id>          — сгенерировать заново
+//
+// /usage (расход на LLM, см. usage.go) работает и без режима одобрения.
+// Черновики живут в памяти сессии: после рестарта неотвеченные пропадают.
+
+// draftTTL — сколько ждём ответа владельца, потом черновик выбрасывается
+const draftTTL = 24 * time.Hour
+
+// draft — комментарий, ждущий решения владельца
+type draft struct {
+	id        string // короткий номер, чтобы удобно набирать в командах
+	post      domain.Message
+	text      string
+	createdAt time.Time
+}
+
+// SetApproval включает или выключает режим одобрения черновиков владельцем
+func (s *Sender) SetApproval(enabled bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.approval = enabled
+	s.log.Info("Approval mode changed", "enabled", enabled)
+}
+
+func (s *Sender) approvalEnabled() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.approval
+}
+
+// proposeDraft отправляет черновик владельцу и запоминает его до решения
+func (s *Sender) proposeDraft(msg *domain.Message, text string) error {
+	s.expireDrafts()
+
+	s.mu.Lock()
+	s.draftSeq++
+	d := &draft{
+		id:        strconv.Itoa(s.draftSeq),
+		post:      *msg,
+		text:      text,
+		createdAt: time.Now(),
+	}
+	s.drafts[d.id] = d
+	s.mu.Unlock()
+
+	if err := s.sendDraft(d); err != nil {
+		s.mu.Lock()
+		delete(s.drafts, d.id)
+		s.mu.Unlock()
+		return err
+	}
+	s.log.Info("Draft sent to owner for approval",
+		"draft_id", d.id,
+		"chat_id", msg.ChatID,
+		"msg_thread_id", msg.MessageThreadId,
+		"comment", text,
+	)
+	return nil
+}
+
+func (s *Sender) sendDraft(d *draft) error {
+	s.mu.Lock()
+	text := d.text
+	s.mu.Unlock()
+
+	body := fmt.Sprintf(
+		"📝 Черновик #%s:\n\n%s\n\nНа сообщение: %s",
+		d.id,
+		text,
+		repliedText(&d.post),
+	)
+	if link := s.buildChatLink(&d.post); link != "" {
+		body = fmt.Sprintf("%s\n\nСсылка: %s", body, link)
+	}
+	body = fmt.Sprintf("%s\n\n/approve %[2]s · /reject %[2]s · /regen %[2]s · /edit %[2]s <текст>", body, d.id)
+	return s.replyOwner(body)
+}
+
+// HandleOwnerMessage разбирает команду владельца из лички.
+// Сообщения не от владельца и не-команды молча игнорируются.
+func (s *Sender) HandleOwnerMessage(ctx context.Context, msg *domain.Message) error {
+	if !msg.IsPrivate || s.ownerUsername == "" || (!s.approvalEnabled() && s.usageReporter() == nil) {
+		return nil
+	}
+	ownerID, err := s.resolveOwner()
+	if err != nil || msg.SenderID != ownerID {
+		return nil
+	}
+
+	fields := strings.Fields(msg.Text)
+	if len(fields) == 0 || !strings.HasPrefix(fields[0], "/") {
+		return nil
+	}
+	cmd := strings.ToLower(fields[0])
+	if r := s.usageReporter(); cmd == "/usage" && r != nil {
+		return s.replyUsage(ctx, r)
+	}
+	if !s.approvalEnabled() {
+		return nil
+	}
+	if len(fields) < 2 {
+		return s.replyOwner("Укажите номер черновика, например: " + cmd + " 1")
+	}
+	id := strings.TrimPrefix(fields[1], "#")
+
+	s.expireDrafts()
+	s.mu.Lock()
+	d, ok := s.drafts[id]
+	s.mu.Unlock()
+	if !ok {
+		return s.replyOwner(fmt.Sprintf("Черновик #%s не найден или уже обработан", id))
+	}
+
+	switch cmd {
+	case "/approve":
+		taken, ok := s.takeDraft(id)
+		if !ok {
+			return nil
+		}
+		// ставим в очередь копию: параллельный /edit или /regen правит d под s.mu
+		job, err := s.schedule(ctx, &taken.post, taken.text, true)
+		if err != nil {
+			return err
+		}
+		s.log.Info("Draft approved", "draft_id", id, "job_id", job.ID)
+		return s.replyOwner(fmt.Sprintf("✅ Черновик #%s одобрен, отправка в %s", id, job.DueAt.Format("15:04")))
+
+	case "/reject":
+		if _, ok := s.takeDraft(id); !ok {
+			return nil
+		}
+		s.skip(domain.SkipRejected)
+		s.log.Info("Draft rejected", "draft_id", id)
+		return s.replyOwner(fmt.Sprintf("❌ Черновик #%s отклонён", id))
+
+	case "/edit":
+		text := skipWords(msg.Text, 2)
+		if text == "" {
+			return s.replyOwner(fmt.Sprintf("Пустой текст: /edit %s <новый текст>", id))
+		}
+		s.mu.Lock()
+		d.text = text
+		s.mu.Unlock()
+		s.log.Info("Draft edited", "draft_id", id)
+		return s.sendDraft(d)
+
+	case "/regen":
+		text, reason, err := s.generate(ctx, &d.post)
+		if err != nil {
+			s.log.Error("GetComment (regen)", "draft_id", id, "error", err)
+			return s.replyOwner(fmt.Sprintf("Не удалось перегенерировать #%s: %v", id, err))
+		}
+		switch reason {
+		case domain.SkipEmptyLLM:
+			return s.replyOwner(fmt.Sprintf("Нейросеть вернула пустой ответ для #%s", id))
+		case domain.SkipInvalidLLM:
+			return s.replyOwner(fmt.Sprintf("Нейросеть не дала ответа, прошедшего проверку, для #%s", id))
+		case domain.SkipUnsafeReply, domain.SkipModeration:
+			return s.replyOwner(fmt.Sprintf("Новый вариант для #%s не прошёл модерацию", id))
+		case domain.SkipDuplicate:
+			return s.replyOwner(fmt.Sprintf("Нейросеть повторяет недавние комментарии, новый вариант для #%s не готов", id))
+		}
+		s.mu.Lock()
+		d.text = text
+		s.mu.Unlock()
+		s.log.Info("Draft regenerated", "draft_id", id)
+		return s.sendDraft(d)
+	}
+
+	help := "Команды: /approve, /reject, /edit, /regen"
+	if s.usageReporter() != nil {
+		help += ", /usage"
+	}
+	return s.replyOwner(help)
+}
+
+// takeDraft забирает черновик из ожидающих и возвращает его копию, снятую под s.mu;
+// false — его уже обработали параллельно
+func (s *Sender) takeDraft(id string) (draft, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	d, ok := s.drafts[id]
+	if !ok {
+		return draft{}, false
+	}
+	delete(s.drafts, id)
+	return *d, true
+}
+
+// expireDrafts выбрасывает черновики, на которые владелец не ответил за draftTTL
+func (s *Sender) expireDrafts() {
+	s.mu.Lock()
+	var expired []string
+	for id, d := range s.drafts {
+		if time.Since(d.createdAt) > draftTTL {
+			expired = append(expired, id)
+			delete(s.drafts, id)
+		}
+	}
+	s.mu.Unlock()
+
+	for _, id := range expired {
+		s.skip(domain.SkipDraftExpired)
+		s.log.Info("Draft expired without owner decision", "draft_id", id)
+	}
+}
+
+// skipWords отрезает первые n слов, сохраняя переносы строк в остатке
+func skipWords(text string, n int) string {
+	rest := strings.TrimSpace(text)
+	for i := 0; i < n; i++ {
+		idx := strings.IndexFunc(rest, unicode.IsSpace)
+		if idx < 0 {
+			return ""
+		}
+		rest = strings.TrimLeftFunc(rest[idx:], unicode.IsSpace)
+	}
+	return rest
+}
+
+func (s *Sender) replyOwner(text string) error {
+	ownerID, err := s.resolveOwner()
+	if err != nil {
+		return err
+	}
+	if err := s.tg.SendMessage(ownerID, 0, 0, text); err != nil {
+		s.log.Warn("Reply to owner failed", "error", err)
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,201 @@
+package useCases
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/larriantoniy/tg_user_bot/internal/adapters/tgfake"
+	"github.com/larriantoniy/tg_user_bot/internal/domain"
+)
+
+func newApprovalSender(t *testing.T, n *stubNeuro) (*Sender, *tgfake.Client) {
+	t.Helper()
+	cli := tgfake.New(1, 0)
+	cli.AddUsername("@owner", testOwnerID)
+	s := newTestSender(cli, n, "@owner")
+	s.SetApproval(true)
+
+	if err := s.SendComment(context.Background(), testMessage()); err != nil {
+		t.Fatalf("SendComment() error = %v", err)
+	}
+	return s, cli
+}
+
+func ownerCommand(text string) *domain.Message {
+	return &domain.Message{ChatID: testOwnerID, SenderID: testOwnerID, IsPrivate: true, Text: text}
+}
+
+func lastOwnerMessage(t *testing.T, cli *tgfake.Client) string {
+	t.Helper()
+	notes := cli.SentTo(testOwnerID)
+	if len(notes) == 0 {
+		t.Fatal("owner got no messages")
+	}
+	return notes[len(notes)-1].Text
+}
+
+func pendingJobs(t *testing.T, s *Sender) []domain.CommentJob {
+	t.Helper()
+	jobs, err := s.queue.Due(context.Background(), testSession, time.Now().Add(time.Hour), 0)
+	if err != nil {
+		t.Fatalf("Due() error = %v", err)
+	}
+	return jobs
+}
+
+func TestApprovalDraftGoesToOwnerFirst(t *testing.T) {
+	s, cli := newApprovalSender(t, &stubNeuro{text: "Отличный разбор 👍"})
+
+	if got := len(pendingJobs(t, s)); got != 0 {
+		t.Fatalf("queued jobs = %d, want 0 before approval", got)
+	}
+	draft := lastOwnerMessage(t, cli)
+	for _, want := range []string{"Черновик #1", "Отличный разбор 👍", "Пост про рынок", "https://t.me/c/1234567890", "/approve 1"} {
+		if !strings.Contains(draft, want) {
+			t.Errorf("draft %q does not contain %q", draft, want)
+		}
+	}
+	if got := len(cli.SentTo(testChatID)); got != 0 {
+		t.Errorf("sent %d comments before approval, want 0", got)
+	}
+}
+
+func TestApprovalApprovePublishesWithoutNotify(t *testing.T) {
+	s, cli := newApprovalSender(t, &stubNeuro{text: "Отличный разбор 👍"})
+	ctx := context.Background()
+
+	if err := s.HandleOwnerMessage(ctx, ownerCommand("/approve 1")); err != nil {
+		t.Fatalf("HandleOwnerMessage() error = %v", err)
+	}
+	jobs := pendingJobs(t, s)
+	if len(jobs) != 1 || !jobs[0].Approved || jobs[0].Text != "Отличный разбор 👍" {
+		t.Fatalf("queued jobs = %+v, want one approved job", jobs)
+	}
+	ownerMessages := len(cli.SentTo(testOwnerID))
+
+	if err := s.Publish(ctx, jobs[0]); err != nil {
+		t.Fatalf("Publish() error = %v", err)
+	}
+	if got := cli.SentTo(testChatID); len(got) != 1 || got[0].Text != "Отличный разбор 👍" {
+		t.Errorf("sent comments = %+v, want the approved text", got)
+	}
+	if got := len(cli.SentTo(testOwnerID)); got != ownerMessages {
+		t.Errorf("owner messages after publish = %d, want %d (no extra notify)", got, ownerMessages)
+	}
+
+	// повторное одобрение того же черновика ничего не ставит в очередь
+	_ = s.HandleOwnerMessage(ctx, ownerCommand("/approve 1"))
+	if !strings.Contains(lastOwnerMessage(t, cli), "не найден") {
+		t.Errorf("second approve reply = %q, want not found", lastOwnerMessage(t, cli))
+	}
+}
+
+func TestApprovalCommands(t *testing.T) {
+	tests := []struct {
+		name      string
+		command   string
+		wantReply string
+		wantNeuro int
+		wantDraft bool
+	}{
+		{
+			name:      "reject drops draft",
+			command:   "/reject 1",
+			wantReply: "отклонён",
+			wantNeuro: 1,
+		},
+		{
+			name:      "edit keeps draft with new text",
+			command:   "/edit #1 Свой текст\nв две строки",
+			wantReply: "Свой текст\nв две строки",
+			wantNeuro: 1,
+			wantDraft: true,
+		},
+		{
+			name:      "regen asks LLM again",
+			command:   "/regen 1",
+			wantReply: "Черновик #1",
+			wantNeuro: 2,
+			wantDraft: true,
+		},
+		{
+			name:      "unknown draft",
+			command:   "/approve 42",
+			wantReply: "не найден",
+			wantNeuro: 1,
+			wantDraft: true,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			n := &stubNeuro{text: "Отличный разбор 👍"}
+			s, cli := newApprovalSender(t, n)
+
+			if err := s.HandleOwnerMessage(context.Background(), ownerCommand(tt.command)); err != nil {
+				t.Fatalf("HandleOwnerMessage() error = %v", err)
+			}
+			if reply := lastOwnerMessage(t, cli); !strings.Contains(reply, tt.wantReply) {
+				t.Errorf("owner reply = %q, want containing %q", reply, tt.wantReply)
+			}
+			if n.calls != tt.wantNeuro {
+				t.Errorf("GetComment calls = %d, want %d", n.calls, tt.wantNeuro)
+			}
+			st := domain.SessionStatus{}
+			s.fillStatus(context.Background(), &st)
+			if (st.PendingDrafts == 1) != tt.wantDraft {
+				t.Errorf("pending drafts = %d, want draft kept = %v", st.PendingDrafts, tt.wantDraft)
+			}
+			if got := len(pendingJobs(t, s)); got != 0 {
+				t.Errorf("queued jobs = %d, want 0", got)
+			}
+		})
+	}
+}
+
+func TestApprovalApproveRacesEdit(t *testing.T) {
+	s, _ := newApprovalSender(t, &stubNeuro{text: "Отличный разбор 👍"})
+	ctx := context.Background()
+
+	// main.go разбирает каждое сообщение владельца в своей горутине
+	var wg sync.WaitGroup
+	for _, cmd := range []string{"/edit 1 Свой текст", "/approve 1"} {
+		wg.Add(1)
+		go func(cmd string) {
+			defer wg.Done()
+			if err := s.HandleOwnerMessage(ctx, ownerCommand(cmd)); err != nil {
+				t.Errorf("HandleOwnerMessage(%q) error = %v", cmd, err)
+			}
+		}(cmd)
+	}
+	wg.Wait()
+
+	jobs := pendingJobs(t, s)
+	if len(jobs) != 1 {
+		t.Fatalf("queued jobs = %+v, want one approved job", jobs)
+	}
+	if text := jobs[0].Text; text != "Отличный разбор 👍" && text != "Свой текст" {
+		t.Errorf("approved text = %q, want original or edited", text)
+	}
+}
+
+func TestApprovalIgnoresStrangers(t *testing.T) {
+	s, cli := newApprovalSender(t, &stubNeuro{text: "Отличный разбор 👍"})
+	before := len(cli.Sent())
+
+	stranger := ownerCommand("/approve 1")
+	stranger.SenderID = 777
+	if err := s.HandleOwnerMessage(context.Background(), stranger); err != nil {
+		t.Fatalf("HandleOwnerMessage() error = %v", err)
+	}
+	if got := len(cli.Sent()); got != before {
+		t.Errorf("sent %d messages after stranger command, want %d", got, before)
+	}
+	if got := len(pendingJobs(t, s)); got != 0 {
+		t.Errorf("queued jobs = %d, want 0", got)
+	}
+}
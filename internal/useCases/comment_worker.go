@@ -0,0 +1,124 @@
+package useCases
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/larriantoniy/tg_user_bot/internal/domain"
+	"github.com/larriantoniy/tg_user_bot/internal/ports"
+)
+
+// CommentWorker забирает из очереди созревшие комментарии своей сессии
+// и отправляет их через Sender. Первый проход делается сразу при старте,
+// так что задания, просроченные за время простоя, подхватываются после рестарта.
+type CommentWorker struct {
+	log     *slog.Logger
+	session string
+	queue   ports.CommentQueue
+	sender  *Sender
+
+	pollInterval time.Duration
+	staleAfter   time.Duration // просроченные дольше этого задания выбрасываются
+}
+
+func NewCommentWorker(
+	log *slog.Logger,
+	session string,
+	queue ports.CommentQueue,
+	sender *Sender,
+	pollInterval time.Duration,
+	staleAfter time.Duration,
+) *CommentWorker {
+	return &CommentWorker{
+		log:          log,
+		session:      session,
+		queue:        queue,
+		sender:       sender,
+		pollInterval: pollInterval,
+		staleAfter:   staleAfter,
+	}
+}
+
+// Run крутится до отмены ctx. Во время FLOOD_WAIT задания копятся в очереди
+// и уходят на первом тике после истечения retry_after.
+func (w *CommentWorker) Run(ctx context.Context) {
+	ticker := time.NewTicker(w.pollInterval)
+	defer ticker.Stop()
+
+	for {
+		if err := w.processDue(ctx, time.Now()); err != nil {
+			if !errors.Is(err, ports.ErrRateLimited) {
+				return
+			}
+			retryAfter, _ := ports.RetryAfter(err)
+			w.log.Info("CommentWorker waits for FLOOD_WAIT", "session", w.session, "retry_after", retryAfter)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// processDue отправляет все созревшие к now задания. Ошибку возвращает только
+// когда продолжать бессмысленно (shutdown или FLOOD_WAIT) — задание тогда остаётся в очереди.
+func (w *CommentWorker) processDue(ctx context.Context, now time.Time) error {
+	jobs, err := w.queue.Due(ctx, w.session, now, 0)
+	if err != nil {
+		w.log.Error("CommentQueue.Due failed", "session", w.session, "error", err)
+		return nil
+	}
+
+	for _, job := range jobs {
+		if w.staleAfter > 0 && now.Sub(job.DueAt) > w.staleAfter {
+			w.log.Warn("Discard stale comment job",
+				"session", w.session,
+				"job_id", job.ID,
+				"chat_id", job.Post.ChatID,
+				"due_at", job.DueAt,
+				"overdue", now.Sub(job.DueAt),
+			)
+			w.sender.skip(domain.SkipStale)
+			if !w.remove(ctx, job) {
+				return nil
+			}
+			continue
+		}
+
+		err := w.sender.Publish(ctx, job)
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if errors.Is(err, ports.ErrRateLimited) {
+			return err
+		}
+		if errors.Is(err, ErrPaused) {
+			// ждём снятия паузы, задание остаётся в очереди
+			continue
+		}
+		if err != nil {
+			w.log.Error("Publish comment failed, dropping job",
+				"session", w.session,
+				"job_id", job.ID,
+				"error", err,
+			)
+		}
+		if !w.remove(ctx, job) {
+			return nil
+		}
+	}
+	return nil
+}
+
+// remove удаляет задание; false — очередь недоступна, повторим на следующем тике
+func (w *CommentWorker) remove(ctx context.Context, job domain.CommentJob) bool {
+	if err := w.queue.Remove(ctx, job); err != nil {
+		w.log.Error("CommentQueue.Remove failed", "session", w.session, "job_id", job.ID, "error", err)
+		return false
+	}
+	return true
+}
@@ -0,0 +1,147 @@
+package useCases
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/larriantoniy/tg_user_bot/internal/adapters/store"
+	"github.com/larriantoniy/tg_user_bot/internal/adapters/tgfake"
+	"github.com/larriantoniy/tg_user_bot/internal/domain"
+	"github.com/larriantoniy/tg_user_bot/internal/ports"
+)
+
+func testJob(id string, dueAt time.Time) domain.CommentJob {
+	return domain.CommentJob{
+		ID:        id,
+		Session:   testSession,
+		Post:      *testMessage(),
+		Text:      "Комментарий " + id,
+		DueAt:     dueAt,
+		CreatedAt: dueAt.Add(-20 * time.Minute),
+	}
+}
+
+func TestCommentWorkerProcessDue(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+	cli := tgfake.New(1, 0)
+	q := store.NewMemoryQueue()
+	s := newTestSender(cli, &stubNeuro{}, "")
+	w := NewCommentWorker(discardLogger(), testSession, q, s, time.Minute, time.Hour)
+
+	_ = q.Enqueue(ctx, testJob("due", now.Add(-time.Minute)))
+	_ = q.Enqueue(ctx, testJob("stale", now.Add(-3*time.Hour)))
+	_ = q.Enqueue(ctx, testJob("future", now.Add(time.Minute)))
+	other := testJob("other-session", now.Add(-time.Minute))
+	other.Session = "other"
+	_ = q.Enqueue(ctx, other)
+
+	if err := w.processDue(ctx, now); err != nil {
+		t.Fatalf("processDue() error = %v", err)
+	}
+
+	sent := cli.Sent()
+	if len(sent) != 1 || sent[0].Text != "Комментарий due" {
+		t.Fatalf("sent = %+v, want only the due job", sent)
+	}
+	if left, _ := q.Due(ctx, testSession, now.Add(time.Hour), 0); len(left) != 1 || left[0].ID != "future" {
+		t.Errorf("left in queue = %+v, want only future job", left)
+	}
+	if left, _ := q.Due(ctx, "other", now, 0); len(left) != 1 {
+		t.Errorf("other session jobs = %d, want 1 (untouched)", len(left))
+	}
+}
+
+func TestCommentWorkerKeepsJobOnRateLimit(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+	cli := tgfake.New(1, 0)
+	cli.FailNextSend(ports.ErrRateLimited)
+	q := store.NewMemoryQueue()
+	s := newTestSender(cli, &stubNeuro{}, "")
+	w := NewCommentWorker(discardLogger(), testSession, q, s, time.Minute, time.Hour)
+
+	_ = q.Enqueue(ctx, testJob("a", now.Add(-2*time.Minute)))
+	_ = q.Enqueue(ctx, testJob("b", now.Add(-time.Minute)))
+
+	if err := w.processDue(ctx, now); !errors.Is(err, ports.ErrRateLimited) {
+		t.Fatalf("processDue() error = %v, want ErrRateLimited", err)
+	}
+	if left, _ := q.Due(ctx, testSession, now, 0); len(left) != 2 {
+		t.Errorf("left in queue = %d, want 2", len(left))
+	}
+}
+
+func TestCommentWorkerRecoversQueueAfterRestart(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "comment_queue.json")
+
+	// SendComment до "рестарта": задание легло в файл, но не отправлено
+	q, err := store.NewFileQueue(path, discardLogger())
+	if err != nil {
+		t.Fatalf("NewFileQueue() error = %v", err)
+	}
+	before := newTestSender(tgfake.New(1, 0), &stubNeuro{text: "Переживу рестарт 👍"}, "")
+	before.queue = q
+	before.minDelay = time.Minute
+	before.maxDelay = 2 * time.Minute
+	if err := before.SendComment(ctx, testMessage()); err != nil {
+		t.Fatalf("SendComment() error = %v", err)
+	}
+
+	reopened, err := store.NewFileQueue(path, discardLogger())
+	if err != nil {
+		t.Fatalf("NewFileQueue() reopen error = %v", err)
+	}
+	cli := tgfake.New(1, 0)
+	after := newTestSender(cli, &stubNeuro{}, "")
+	w := NewCommentWorker(discardLogger(), testSession, reopened, after, time.Minute, time.Hour)
+
+	if err := w.processDue(ctx, time.Now().Add(5*time.Minute)); err != nil {
+		t.Fatalf("processDue() error = %v", err)
+	}
+	if sent := cli.Sent(); len(sent) != 1 || sent[0].Text != "Переживу рестарт 👍" {
+		t.Errorf("sent after restart = %+v, want the planned comment", sent)
+	}
+}
+
+func TestCommentWorkerKeepsPausedJobs(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+	cli := tgfake.New(1, 0)
+	q := store.NewMemoryQueue()
+	s := newTestSender(cli, &stubNeuro{}, "")
+	s.queue = q
+	w := NewCommentWorker(discardLogger(), testSession, q, s, time.Minute, time.Hour)
+
+	_ = q.Enqueue(ctx, testJob("a", now.Add(-time.Minute)))
+	s.SetChatPaused(testChatID, true)
+
+	if err := w.processDue(ctx, now); err != nil {
+		t.Fatalf("processDue() error = %v", err)
+	}
+	if got := len(cli.Sent()); got != 0 {
+		t.Fatalf("sent %d messages while chat paused, want 0", got)
+	}
+	if n, _ := q.Len(ctx, testSession); n != 1 {
+		t.Fatalf("pending = %d, want 1", n)
+	}
+
+	s.SetChatPaused(testChatID, false)
+	if err := w.processDue(ctx, now); err != nil {
+		t.Fatalf("processDue() error = %v", err)
+	}
+	if got := len(cli.Sent()); got != 1 {
+		t.Errorf("sent %d messages after resume, want 1", got)
+	}
+
+	var st domain.SessionStatus
+	st.State = domain.SessionAuthorized
+	s.fillStatus(ctx, &st)
+	if st.LastCommentAt == nil || st.PendingComments != 0 {
+		t.Errorf("status = %+v, want last comment time and empty queue", st)
+	}
+}
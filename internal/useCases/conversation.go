@@ -0,0 +1,178 @@
+package useCases
+
+import (
+	"context"
+	"time"
+
+	"github.com/larriantoniy/tg_user_bot/internal/domain"
+)
+
+// ConversationRules — ограничения режима переписки (ответы на реплики к нашим комментариям).
+// 0 в любом лимите — без ограничения.
+type ConversationRules struct {
+	MaxDepth     int           // наших ответов в одной цепочке, не считая исходного комментария
+	MaxPerUser   int           // ответов одному человеку за Window
+	MaxPerThread int           // ответов в одном треде за Window
+	Window       time.Duration // окно для MaxPerUser и MaxPerThread
+}
+
+// DefaultConversationRules — пара реплик, не больше: бот не должен втягиваться в спор
+var DefaultConversationRules = ConversationRules{
+	MaxDepth:     2,
+	MaxPerUser:   3,
+	MaxPerThread: 5,
+	Window:       24 * time.Hour,
+}
+
+// conversationHistory — сколько последних комментариев сессии смотреть для лимитов
+const conversationHistory = 1000
+
+// SetConversation включает ответы на реплики к нашим комментариям; nil — выключить
+func (s *Sender) SetConversation(rules *ConversationRules) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.conversation = rules
+}
+
+func (s *Sender) conversationRules() *ConversationRules {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.conversation
+}
+
+// sendReply отвечает собеседнику, который ответил на наш комментарий.
+// Тред уже прокомментирован, поэтому вместо Allow действуют лимиты ConversationRules.
+func (s *Sender) sendReply(ctx context.Context, msg *domain.Message) error {
+	rules := s.conversationRules()
+	if rules == nil || len(msg.ReplyChain) == 0 {
+		return nil
+	}
+	if stop, err := s.holdOff(msg, "SendReply"); stop {
+		return err
+	}
+	if reason := s.conversationLimit(ctx, msg, *rules); reason != "" {
+		s.skip(domain.SkipConversation)
+		s.log.Info("Skip SendReply: conversation limit",
+			"chat_id", msg.ChatID,
+			"msg_thread_id", msg.MessageThreadId,
+			"user_id", msg.SenderID,
+			"limit", reason,
+		)
+		return nil
+	}
+	if !s.tg.CanSendToChat(msg.ChatID) || !s.tg.IsMember(msg.ChatID) {
+		s.log.Info("Skip SendReply: cannot send to chat",
+			"chat_id", msg.ChatID,
+			"msg_thread_id", msg.MessageThreadId,
+		)
+		s.skip(domain.SkipCannotSend)
+		return nil
+	}
+	// и пост, и реплика собеседника: на грубость или опасную тему не отвечаем
+	for _, text := range []string{msg.Text, repliedText(msg)} {
+		post := *msg
+		post.Text = text
+		if reason := s.moderatePost(ctx, &post); reason != "" {
+			s.skip(reason)
+			return nil
+		}
+	}
+
+	replyText, reason, err := s.generate(ctx, msg)
+	if err != nil {
+		return s.neuroFailed(err, "SendReply")
+	}
+	if reason != "" {
+		s.log.Info("Skip SendReply: no usable LLM response", "reason", reason)
+		s.skip(reason)
+		return nil
+	}
+
+	if s.approvalEnabled() {
+		return s.proposeDraft(msg, replyText)
+	}
+	_, err = s.schedule(ctx, msg, replyText, false)
+	return err
+}
+
+// conversationLimit проверяет лимиты по истории отправленного и очереди; "" — можно отвечать.
+// Ошибки стора не открывают дорогу: без истории лимиты не проверить, и лучше промолчать.
+func (s *Sender) conversationLimit(ctx context.Context, msg *domain.Message, rules ConversationRules) string {
+	if rules.MaxDepth > 0 {
+		// начала переписки не видно — наших ответов могло быть сколько угодно, молчим
+		if msg.ReplyChainTruncated {
+			return "depth"
+		}
+		own := 0
+		for _, r := range msg.ReplyChain {
+			if r.Own {
+				own++
+			}
+		}
+		// первый наш — исходный комментарий к посту
+		if own-1 >= rules.MaxDepth {
+			return "depth"
+		}
+	}
+	if rules.MaxPerUser <= 0 && rules.MaxPerThread <= 0 {
+		return ""
+	}
+
+	sent, err := s.limiter.store.RecentSent(ctx, s.limiter.session, conversationHistory)
+	if err != nil {
+		s.log.Warn("RecentSent for conversation limits", "error", err)
+		return "history"
+	}
+	jobs, err := s.queue.Pending(ctx, s.limiter.session, 0)
+	if err != nil {
+		s.log.Warn("Pending jobs for conversation limits", "error", err)
+		return "history"
+	}
+
+	since := time.Now().Add(-rules.Window)
+	perUser, perThread := 0, 0
+	count := func(chatID, threadID, user int64) {
+		if user == 0 {
+			return
+		}
+		if user == msg.SenderID {
+			perUser++
+		}
+		if chatID == msg.ChatID && threadID == msg.MessageThreadId {
+			perThread++
+		}
+	}
+	for _, c := range sent {
+		if rules.Window <= 0 || c.SentAt.After(since) {
+			count(c.ChatID, c.ThreadID, c.ReplyToUser)
+		}
+	}
+	for _, j := range jobs {
+		if j.Post.IsReply {
+			count(j.Post.ChatID, j.Post.MessageThreadId, j.Post.SenderID)
+		}
+	}
+	s.mu.Lock()
+	for _, d := range s.drafts {
+		if d.post.IsReply {
+			count(d.post.ChatID, d.post.MessageThreadId, d.post.SenderID)
+		}
+	}
+	s.mu.Unlock()
+
+	switch {
+	case rules.MaxPerUser > 0 && perUser >= rules.MaxPerUser:
+		return "per_user"
+	case rules.MaxPerThread > 0 && perThread >= rules.MaxPerThread:
+		return "per_thread"
+	}
+	return ""
+}
+
+// repliedText — на что отвечаем: реплика собеседника или сам пост
+func repliedText(msg *domain.Message) string {
+	if msg.IsReply && len(msg.ReplyChain) > 0 {
+		return msg.ReplyChain[len(msg.ReplyChain)-1].Text
+	}
+	return msg.Text
+}
@@ -0,0 +1,131 @@
+package useCases
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/larriantoniy/tg_user_bot/internal/adapters/store"
+	"github.com/larriantoniy/tg_user_bot/internal/adapters/tgfake"
+	"github.com/larriantoniy/tg_user_bot/internal/domain"
+)
+
+const testUserID int64 = 777
+
+// testReply — собеседник ответил на наш комментарий к testMessage
+func testReply(ownReplies int) *domain.Message {
+	msg := testMessage()
+	msg.IsReply = true
+	msg.SenderID = testUserID
+	msg.ReplyToMessageID = 500
+	msg.ReplyChain = []domain.ThreadReply{{MessageID: 100, Text: "Отличный разбор 👍", Own: true}}
+	for i := 0; i < ownReplies; i++ {
+		msg.ReplyChain = append(msg.ReplyChain,
+			domain.ThreadReply{MessageID: int64(200 + 2*i), Text: "А почему?"},
+			domain.ThreadReply{MessageID: int64(201 + 2*i), Text: "Рынок так решил 📈", Own: true},
+		)
+	}
+	msg.ReplyChain = append(msg.ReplyChain, domain.ThreadReply{MessageID: 500, Text: "Не согласен"})
+	return msg
+}
+
+// truncatedReply — адаптер не дошёл до корня треда, начало переписки потеряно
+func truncatedReply(msg *domain.Message) *domain.Message {
+	msg.ReplyChain = msg.ReplyChain[1:]
+	msg.ReplyChainTruncated = true
+	return msg
+}
+
+func TestSenderConversation(t *testing.T) {
+	sentTo := func(user int64, thread int64, n int, age time.Duration) []domain.SentComment {
+		out := make([]domain.SentComment, n)
+		for i := range out {
+			out[i] = domain.SentComment{Session: testSession, ChatID: testChatID, ThreadID: thread, ReplyToUser: user, SentAt: time.Now().Add(-age)}
+		}
+		return out
+	}
+	tests := []struct {
+		name     string
+		disabled bool
+		msg      *domain.Message
+		history  []domain.SentComment
+		wantSent bool
+	}{
+		{name: "disabled by default", disabled: true, msg: testReply(0)},
+		{
+			name:     "reply in already commented thread",
+			msg:      testReply(0),
+			history:  sentTo(0, testThreadID, 1, time.Hour), // наш исходный комментарий
+			wantSent: true,
+		},
+		{name: "depth limit", msg: testReply(DefaultConversationRules.MaxDepth)},
+		{name: "truncated chain counts as too deep", msg: truncatedReply(testReply(0))},
+		{name: "per user limit", msg: testReply(0), history: sentTo(testUserID, testThreadID+1, DefaultConversationRules.MaxPerUser, time.Hour)},
+		{name: "per thread limit", msg: testReply(0), history: sentTo(testUserID+1, testThreadID, DefaultConversationRules.MaxPerThread, time.Hour)},
+		{
+			name:     "old replies are outside window",
+			msg:      testReply(0),
+			history:  sentTo(testUserID, testThreadID, 5, 48*time.Hour),
+			wantSent: true,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			st := store.NewMemoryStore(72 * time.Hour)
+			ctx := context.Background()
+			for _, c := range tt.history {
+				if err := st.AddSent(ctx, c); err != nil {
+					t.Fatalf("AddSent() error = %v", err)
+				}
+			}
+			// тред уже прокомментирован — обычный Allow ответ бы не пропустил
+			if _, err := st.MarkSeen(ctx, domain.ThreadKey{Session: testSession, ChatID: testChatID, ThreadID: testThreadID}); err != nil {
+				t.Fatalf("MarkSeen() error = %v", err)
+			}
+
+			cli := tgfake.New(1, 0)
+			n := &stubNeuro{text: "Каждый видит рынок по-своему 🙂"}
+			s := newTestSenderWithStore(cli, n, "", st)
+			if !tt.disabled {
+				s.SetConversation(&DefaultConversationRules)
+			}
+
+			if err := sendAndDeliver(s, tt.msg); err != nil {
+				t.Fatalf("SendComment() error = %v", err)
+			}
+			sent := cli.SentTo(testChatID)
+			if !tt.wantSent {
+				if len(sent) != 0 || n.calls != 0 {
+					t.Errorf("sent %+v after %d GetComment calls, want nothing", sent, n.calls)
+				}
+				return
+			}
+			if len(sent) != 1 || sent[0].ReplyToMessageID != 500 {
+				t.Fatalf("sent %+v, want one reply to message 500", sent)
+			}
+			recent, _ := st.RecentSent(ctx, testSession, 1)
+			if len(recent) != 1 || recent[0].ReplyToUser != testUserID {
+				t.Errorf("history = %+v, want reply to user %d", recent, testUserID)
+			}
+		})
+	}
+}
+
+func TestSenderConversationCountsQueuedReplies(t *testing.T) {
+	cli := tgfake.New(1, 0)
+	n := &stubNeuro{text: "Каждый видит рынок по-своему 🙂"}
+	s := newTestSender(cli, n, "")
+	s.minDelay, s.maxDelay = time.Hour, time.Hour // ответы ещё в очереди
+	s.SetConversation(&ConversationRules{MaxPerUser: 1})
+	ctx := context.Background()
+
+	for i := 0; i < 2; i++ {
+		if err := s.SendComment(ctx, testReply(0)); err != nil {
+			t.Fatalf("SendComment() error = %v", err)
+		}
+	}
+	if pending, _ := s.queue.Len(ctx, testSession); pending != 1 {
+		t.Errorf("pending = %d, want 1 (second reply to the same user over limit)", pending)
+	}
+}
@@ -0,0 +1,140 @@
+package useCases
+
+import (
+	"context"
+	"strings"
+)
+
+// DedupRules — насколько новый комментарий может быть похож на недавние.
+// Сравниваются последние SessionHistory комментариев сессии (вместе с ещё не отправленными
+// из очереди и черновиками) и последние GlobalHistory комментариев других сессий (вместе
+// с их очередью); 0 — не сравнивать.
+// Число перегенераций общее с валидатором.
+type DedupRules struct {
+	Threshold      float64 // похожесть (0..1), с которой черновик считается повтором
+	SessionHistory int
+	GlobalHistory  int
+}
+
+// DefaultDedupRules: 0.6 по триграммам — та же фраза с переставленными словами или другим эмодзи
+var DefaultDedupRules = DedupRules{
+	Threshold:      0.6,
+	SessionHistory: 100,
+	GlobalHistory:  300,
+}
+
+// shingleSize — длина символьных шинглов: триграммы устойчивы к окончаниям слов
+const shingleSize = 3
+
+// Similarity — коэффициент Жаккара по символьным триграммам нормализованных текстов:
+// регистр, знаки препинания и эмодзи не учитываются. 1 — тексты совпадают.
+func Similarity(a, b string) float64 {
+	sa, sb := shingles(a), shingles(b)
+	if len(sa) == 0 || len(sb) == 0 {
+		return 0
+	}
+	common := 0
+	for s := range sa {
+		if _, ok := sb[s]; ok {
+			common++
+		}
+	}
+	return float64(common) / float64(len(sa)+len(sb)-common)
+}
+
+func shingles(text string) map[string]struct{} {
+	runes := []rune(strings.Join(words(text), " "))
+	if len(runes) == 0 {
+		return nil
+	}
+	if len(runes) < shingleSize {
+		return map[string]struct{}{string(runes): {}}
+	}
+	out := make(map[string]struct{}, len(runes))
+	for i := 0; i+shingleSize <= len(runes); i++ {
+		out[string(runes[i:i+shingleSize])] = struct{}{}
+	}
+	return out
+}
+
+// SetDedup включает проверку на повторы недавних комментариев; nil — выключить
+func (s *Sender) SetDedup(rules *DedupRules) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.dedup = rules
+}
+
+func (s *Sender) dedupRules() *DedupRules {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.dedup
+}
+
+// duplicate — на что похож черновик
+type duplicate struct {
+	similarity float64
+	text       string
+	scope      string // "session" или "global"
+}
+
+// recentComments собирает тексты для сравнения: своя история, очередь и черновики, затем чужая история.
+// Ошибки стора не мешают комментировать — сравниваем с тем, что удалось прочитать.
+func (s *Sender) recentComments(ctx context.Context, rules DedupRules) (own, global []string) {
+	if rules.SessionHistory > 0 {
+		sent, err := s.limiter.store.RecentSent(ctx, s.limiter.session, rules.SessionHistory)
+		if err != nil {
+			s.log.Warn("RecentSent for dedup", "error", err)
+		}
+		for _, c := range sent {
+			own = append(own, c.Text)
+		}
+		// запланированные, но ещё не отправленные — тоже «недавние»
+		jobs, err := s.queue.Pending(ctx, s.limiter.session, rules.SessionHistory)
+		if err != nil {
+			s.log.Warn("Pending jobs for dedup", "error", err)
+		}
+		for _, j := range jobs {
+			own = append(own, j.Text)
+		}
+		s.mu.Lock()
+		for _, d := range s.drafts {
+			own = append(own, d.text)
+		}
+		s.mu.Unlock()
+	}
+	if rules.GlobalHistory > 0 {
+		sent, err := s.limiter.store.RecentSentExcept(ctx, s.limiter.session, rules.GlobalHistory)
+		if err != nil {
+			s.log.Warn("RecentSent for dedup", "error", err)
+		}
+		for _, c := range sent {
+			global = append(global, c.Text)
+		}
+		// очередь общая на все сессии: чужие запланированные комментарии тоже «недавние»
+		jobs, err := s.queue.Pending(ctx, "", 0)
+		if err != nil {
+			s.log.Warn("Pending jobs for dedup", "error", err)
+		}
+		queued := 0
+		for _, j := range jobs {
+			if j.Session != s.limiter.session && queued < rules.GlobalHistory {
+				global = append(global, j.Text)
+				queued++
+			}
+		}
+	}
+	return own, global
+}
+
+// findDuplicate возвращает самый похожий текст, если похожесть не ниже порога
+func findDuplicate(text string, threshold float64, own, global []string) (duplicate, bool) {
+	best := duplicate{}
+	for scope, list := range map[string][]string{"session": own, "global": global} {
+		for _, prev := range list {
+			if sim := Similarity(text, prev); sim > best.similarity {
+				best = duplicate{similarity: sim, text: prev, scope: scope}
+			}
+		}
+	}
+	return best, best.similarity >= threshold && best.similarity > 0
+}
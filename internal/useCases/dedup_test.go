@@ -0,0 +1,148 @@
+package useCases
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/larriantoniy/tg_user_bot/internal/adapters/store"
+	"github.com/larriantoniy/tg_user_bot/internal/adapters/tgfake"
+	"github.com/larriantoniy/tg_user_bot/internal/domain"
+)
+
+func TestSimilarity(t *testing.T) {
+	tests := []struct {
+		a, b string
+		dup  bool // выше порога по умолчанию
+	}{
+		{"Отличный разбор, согласен полностью 👍", "Отличный разбор, согласен полностью 👍", true},
+		{"Отличный разбор, согласен полностью 👍", "отличный разбор — полностью согласен 🔥", true},
+		{"Отличный разбор, согласен полностью 👍", "Отличный разбор, согласна полностью!", true},
+		{"Отличный разбор, согласен полностью 👍", "Ставку опять не тронули, ждём лета 📉", false},
+		{"Отличный разбор 👍", "Отличный прогноз по нефти на неделю 🛢", false},
+		{"👍", "👍", false}, // без слов сравнивать нечего
+	}
+	for _, tt := range tests {
+		sim := Similarity(tt.a, tt.b)
+		if got := sim >= DefaultDedupRules.Threshold; got != tt.dup {
+			t.Errorf("Similarity(%q, %q) = %.2f, duplicate = %v, want %v", tt.a, tt.b, sim, got, tt.dup)
+		}
+	}
+}
+
+func TestSenderDedup(t *testing.T) {
+	const recent = "Отличный разбор, согласен полностью 👍"
+	tests := []struct {
+		name      string
+		history   []domain.SentComment // старые первыми
+		queued    []domain.CommentJob
+		texts     []string
+		wantNeuro int
+		wantSent  string
+	}{
+		{
+			name:      "new phrasing is sent",
+			history:   []domain.SentComment{{Session: testSession, Text: recent}},
+			texts:     []string{"Ставку опять не тронули, ждём лета 📉"},
+			wantNeuro: 1,
+			wantSent:  "Ставку опять не тронули, ждём лета 📉",
+		},
+		{
+			name:      "repeat of own comment is regenerated",
+			history:   []domain.SentComment{{Session: testSession, Text: recent}},
+			texts:     []string{"Отличный разбор, полностью согласен 🔥", "Ставку опять не тронули, ждём лета 📉"},
+			wantNeuro: 2,
+			wantSent:  "Ставку опять не тронули, ждём лета 📉",
+		},
+		{
+			name:      "repeat of another session is dropped",
+			history:   []domain.SentComment{{Session: "other", Text: recent}},
+			texts:     []string{recent, recent, recent},
+			wantNeuro: 3,
+		},
+		{
+			// свои комментарии не вытесняют чужие из окна GlobalHistory
+			name:      "repeat of another session behind own history is dropped",
+			history:   append([]domain.SentComment{{Session: "other", Text: recent}}, ownHistory(DefaultDedupRules.GlobalHistory)...),
+			texts:     []string{recent, recent, recent},
+			wantNeuro: 3,
+		},
+		{
+			name:      "repeat of another session's queued comment is dropped",
+			queued:    []domain.CommentJob{{ID: "other-1", Session: "other", Text: recent, DueAt: time.Now().Add(time.Hour)}},
+			texts:     []string{recent, recent, recent},
+			wantNeuro: 3,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			st := store.NewMemoryStore(time.Hour)
+			for _, c := range tt.history {
+				c.SentAt = time.Now()
+				if err := st.AddSent(context.Background(), c); err != nil {
+					t.Fatalf("AddSent() error = %v", err)
+				}
+			}
+			cli := tgfake.New(1, 0)
+			n := &stubNeuro{texts: tt.texts}
+			s := newTestSenderWithStore(cli, n, "", st)
+			s.SetDedup(&DefaultDedupRules)
+			for _, j := range tt.queued {
+				if err := s.queue.Enqueue(context.Background(), j); err != nil {
+					t.Fatalf("Enqueue() error = %v", err)
+				}
+			}
+
+			if err := sendAndDeliver(s, testMessage()); err != nil {
+				t.Fatalf("SendComment() error = %v", err)
+			}
+			if n.calls != tt.wantNeuro {
+				t.Errorf("GetComment calls = %d, want %d", n.calls, tt.wantNeuro)
+			}
+			sent := cli.SentTo(testChatID)
+			switch {
+			case tt.wantSent == "" && len(sent) != 0:
+				t.Errorf("sent %+v, want nothing", sent)
+			case tt.wantSent != "" && (len(sent) != 1 || sent[0].Text != tt.wantSent):
+				t.Errorf("sent %+v, want %q", sent, tt.wantSent)
+			}
+		})
+	}
+}
+
+// ownHistory — n разных комментариев тестовой сессии, непохожих на проверяемые тексты
+func ownHistory(n int) []domain.SentComment {
+	out := make([]domain.SentComment, n)
+	for i := range out {
+		out[i] = domain.SentComment{Session: testSession, Text: fmt.Sprintf("Заметка %d о котировках", i)}
+	}
+	return out
+}
+
+func TestSenderDedupSeesQueuedComments(t *testing.T) {
+	cli := tgfake.New(1, 0)
+	n := &stubNeuro{text: "Отличный разбор, согласен полностью 👍"}
+	s := newTestSender(cli, n, "")
+	s.minDelay, s.maxDelay = time.Hour, time.Hour // первый комментарий ещё в очереди
+	s.SetDedup(&DefaultDedupRules)
+	ctx := context.Background()
+
+	first := testMessage()
+	if err := s.SendComment(ctx, first); err != nil {
+		t.Fatalf("first SendComment() error = %v", err)
+	}
+	second := testMessage()
+	second.MessageThreadId++
+	if err := s.SendComment(ctx, second); err != nil {
+		t.Fatalf("second SendComment() error = %v", err)
+	}
+
+	if pending, _ := s.queue.Len(ctx, testSession); pending != 1 {
+		t.Errorf("pending = %d, want 1 (repeat of the queued comment dropped)", pending)
+	}
+	if want := 2 + DefaultValidationRules.Regenerate; n.calls != want {
+		t.Errorf("GetComment calls = %d, want %d", n.calls, want)
+	}
+}
@@ -0,0 +1,55 @@
+package useCases
+
+import (
+	"context"
+	"time"
+
+	"github.com/larriantoniy/tg_user_bot/internal/domain"
+	"github.com/larriantoniy/tg_user_bot/internal/ports"
+)
+
+// CommentLimiter не даёт сессии комментировать один тред дважды
+// и ведёт историю отправленных комментариев. Состояние живёт в ports.CommentStore,
+// поэтому переживает рестарт, если стор персистентный.
+type CommentLimiter struct {
+	session string
+	store   ports.CommentStore
+}
+
+func NewCommentLimiter(session string, store ports.CommentStore) *CommentLimiter {
+	return &CommentLimiter{session: session, store: store}
+}
+
+// Allow атомарно отмечает тред и возвращает true, если раньше его не было
+func (l *CommentLimiter) Allow(ctx context.Context, chatID, threadID int64) (bool, error) {
+	return l.store.MarkSeen(ctx, domain.ThreadKey{
+		Session:  l.session,
+		ChatID:   chatID,
+		ThreadID: threadID,
+	})
+}
+
+// Release снимает отметку с треда: комментарий к нему так и не запланировали
+func (l *CommentLimiter) Release(ctx context.Context, chatID, threadID int64) error {
+	return l.store.UnmarkSeen(ctx, domain.ThreadKey{
+		Session:  l.session,
+		ChatID:   chatID,
+		ThreadID: threadID,
+	})
+}
+
+// Record сохраняет отправленный комментарий в историю
+func (l *CommentLimiter) Record(ctx context.Context, msg *domain.Message, text string) error {
+	c := domain.SentComment{
+		Session:  l.session,
+		ChatID:   msg.ChatID,
+		ThreadID: msg.MessageThreadId,
+		ChatName: msg.ChatName,
+		Text:     text,
+		SentAt:   time.Now(),
+	}
+	if msg.IsReply {
+		c.ReplyToUser = msg.SenderID
+	}
+	return l.store.AddSent(ctx, c)
+}
@@ -0,0 +1,179 @@
+package useCases
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/larriantoniy/tg_user_bot/internal/domain"
+)
+
+// FilterRules — какие посты канала вообще не показывать нейросети.
+// Нулевые MinChars/MaxChars — без ограничения; длина считается по тексту поста.
+// MinChars не действует на фото и альбомы (по ContentType): картинки нейросеть видит и без подписи.
+// Строки в Include, Exclude и AdMarkers ищутся без учёта регистра как подстроки, /.../ — регулярка.
+type FilterRules struct {
+	MinChars      int
+	MaxChars      int
+	Include       []string // если не пусто — в посте должно быть хотя бы одно
+	Exclude       []string // любое совпадение — пост пропускается
+	SkipForwarded bool
+	SkipLinks     bool
+	SkipAds       bool
+	AdMarkers     []string // метки рекламы и розыгрышей
+}
+
+// DefaultFilterRules: односложные посты, репосты, реклама и розыгрыши не комментируем
+var DefaultFilterRules = FilterRules{
+	MinChars:      15,
+	SkipForwarded: true,
+	SkipAds:       true,
+	AdMarkers: []string{
+		"#реклама", `/#ad\b/`, "#промо", "#партнер", "#партнёр", "#sponsored",
+		"на правах рекламы", "рекламный пост", `/\berid\b/`,
+		"розыгрыш", "разыгрываем", "giveaway",
+	},
+}
+
+// pattern — подстрока в нижнем регистре или регулярка
+type pattern struct {
+	source string // как в конфиге, для логов
+	text   string
+	re     *regexp.Regexp
+}
+
+func (p pattern) match(text, lower string) bool {
+	if p.re != nil {
+		return p.re.MatchString(text)
+	}
+	return strings.Contains(lower, p.text)
+}
+
+func compilePatterns(list []string) ([]pattern, error) {
+	out := make([]pattern, 0, len(list))
+	for _, s := range list {
+		s = strings.TrimSpace(s)
+		if s == "" {
+			continue
+		}
+		if len(s) > 2 && strings.HasPrefix(s, "/") && strings.HasSuffix(s, "/") {
+			re, err := regexp.Compile("(?i)" + s[1:len(s)-1])
+			if err != nil {
+				return nil, fmt.Errorf("pattern %s: %w", s, err)
+			}
+			out = append(out, pattern{source: s, re: re})
+			continue
+		}
+		out = append(out, pattern{source: s, text: strings.ToLower(s)})
+	}
+	return out, nil
+}
+
+type compiledFilter struct {
+	rules                      FilterRules
+	include, exclude, adMarker []pattern
+}
+
+func compileFilter(r FilterRules) (*compiledFilter, error) {
+	f := &compiledFilter{rules: r}
+	var err error
+	if f.include, err = compilePatterns(r.Include); err != nil {
+		return nil, fmt.Errorf("include: %w", err)
+	}
+	if f.exclude, err = compilePatterns(r.Exclude); err != nil {
+		return nil, fmt.Errorf("exclude: %w", err)
+	}
+	if f.adMarker, err = compilePatterns(r.AdMarkers); err != nil {
+		return nil, fmt.Errorf("ad markers: %w", err)
+	}
+	return f, nil
+}
+
+// PostFilter — правила для всех каналов и переопределения для отдельных
+type PostFilter struct {
+	def      *compiledFilter
+	channels map[string]*compiledFilter // chat id или название канала
+}
+
+// NewPostFilter компилирует правила; channels — готовые правила каналов (chat id или название)
+func NewPostFilter(def FilterRules, channels map[string]FilterRules) (*PostFilter, error) {
+	f := &PostFilter{channels: make(map[string]*compiledFilter, len(channels))}
+	var err error
+	if f.def, err = compileFilter(def); err != nil {
+		return nil, fmt.Errorf("post filter: %w", err)
+	}
+	for key, r := range channels {
+		if f.channels[key], err = compileFilter(r); err != nil {
+			return nil, fmt.Errorf("post filter: channel %q: %w", key, err)
+		}
+	}
+	return f, nil
+}
+
+// SetPostFilter включает фильтр постов до запроса к нейросети; nil — выключить
+func (s *Sender) SetPostFilter(f *PostFilter) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.filter = f
+}
+
+func (s *Sender) postFilter() *PostFilter {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.filter
+}
+
+// hasPictures — пост с картинками. Смотрим на тип содержимого, а не на PhotoFiles:
+// на этапе фильтра картинки ещё не скачаны.
+func hasPictures(msg *domain.Message) bool {
+	return msg.ContentType == domain.ContentPhoto || msg.ContentType == domain.ContentAlbum
+}
+
+// Check возвращает сработавшее правило; "" — пост можно комментировать
+func (f *PostFilter) Check(msg *domain.Message) string {
+	c := f.def
+	if ch, ok := f.channels[strconv.FormatInt(msg.ChannelID, 10)]; ok {
+		c = ch
+	} else if ch, ok := f.channels[msg.ChatName]; ok && msg.ChatName != "" {
+		c = ch
+	}
+	r := c.rules
+
+	if r.SkipForwarded && msg.ForwardOrigin != nil {
+		return "forwarded"
+	}
+	n := utf8.RuneCountInString(strings.TrimSpace(msg.Text))
+	if r.MinChars > 0 && n < r.MinChars && !hasPictures(msg) {
+		return fmt.Sprintf("min_chars %d < %d", n, r.MinChars)
+	}
+	if r.MaxChars > 0 && n > r.MaxChars {
+		return fmt.Sprintf("max_chars %d > %d", n, r.MaxChars)
+	}
+	if r.SkipLinks && (msg.HasLinks || linkRe.MatchString(msg.Text)) {
+		return "links"
+	}
+	lower := strings.ToLower(msg.Text)
+	if r.SkipAds {
+		for _, p := range c.adMarker {
+			if p.match(msg.Text, lower) {
+				return fmt.Sprintf("ad marker %s", p.source)
+			}
+		}
+	}
+	for _, p := range c.exclude {
+		if p.match(msg.Text, lower) {
+			return fmt.Sprintf("exclude %s", p.source)
+		}
+	}
+	if len(c.include) == 0 {
+		return ""
+	}
+	for _, p := range c.include {
+		if p.match(msg.Text, lower) {
+			return ""
+		}
+	}
+	return "include: no match"
+}
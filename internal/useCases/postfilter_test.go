@@ -0,0 +1,85 @@
+package useCases
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/larriantoniy/tg_user_bot/internal/adapters/tgfake"
+	"github.com/larriantoniy/tg_user_bot/internal/domain"
+)
+
+func TestPostFilter(t *testing.T) {
+	rules := DefaultFilterRules
+	rules.MaxChars = 500
+	rules.Exclude = []string{"ставки на спорт", `/крипт\S*/`}
+	news := rules
+	news.Include = []string{"ставк", "инфляц"}
+	news.SkipLinks = true
+	f, err := NewPostFilter(rules, map[string]FilterRules{"Новости": news})
+	if err != nil {
+		t.Fatalf("NewPostFilter() error = %v", err)
+	}
+
+	const long = "Центробанк сохранил ключевую ставку на прежнем уровне"
+	tests := []struct {
+		name string
+		msg  domain.Message
+		want string // начало правила; "" — пост проходит
+	}{
+		{name: "regular post", msg: domain.Message{Text: long}},
+		{name: "one word", msg: domain.Message{Text: "Огонь"}, want: "min_chars"},
+		{name: "photo without caption", msg: domain.Message{ContentType: domain.ContentPhoto, PhotoFileIDs: []int32{1}}},
+		{name: "album with short caption", msg: domain.Message{Text: "Огонь", ContentType: domain.ContentAlbum, PhotoFileIDs: []int32{1, 2}}},
+		{name: "video with short caption", msg: domain.Message{Text: "Огонь", ContentType: domain.ContentVideo}, want: "min_chars"},
+		{name: "too long", msg: domain.Message{Text: strings.Repeat("а", 501)}, want: "max_chars"},
+		{name: "forwarded", msg: domain.Message{Text: long, ForwardOrigin: &domain.ForwardOrigin{Kind: domain.ForwardFromChannel, ChatID: -1009}}, want: "forwarded"},
+		{name: "ad hashtag", msg: domain.Message{Text: long + " #Реклама"}, want: "ad marker #реклама"},
+		{name: "erid", msg: domain.Message{Text: long + " Реклама. ООО Ромашка, erid: 2Vtzqx"}, want: "ad marker"},
+		{name: "giveaway", msg: domain.Message{Text: "Большой розыгрыш среди подписчиков канала"}, want: "ad marker розыгрыш"},
+		{name: "hashtag prefix is not an ad", msg: domain.Message{Text: long + " #adidas"}},
+		{name: "exclude keyword", msg: domain.Message{Text: "Лучшие ставки на спорт этой недели"}, want: "exclude ставки на спорт"},
+		{name: "exclude regex", msg: domain.Message{Text: "Обзор рынка криптовалют за неделю"}, want: `exclude /крипт\S*/`},
+		{name: "links allowed by default", msg: domain.Message{Text: long, HasLinks: true}},
+		{name: "channel override: links", msg: domain.Message{ChatName: "Новости", Text: long, HasLinks: true}, want: "links"},
+		{name: "channel override: include", msg: domain.Message{ChatName: "Новости", Text: "Погода на выходные будет тёплой"}, want: "include"},
+		{name: "channel override: include matched", msg: domain.Message{ChatName: "Новости", Text: long}},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			got := f.Check(&tt.msg)
+			if (tt.want == "") != (got == "") || !strings.HasPrefix(got, tt.want) {
+				t.Errorf("Check() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	if _, err := NewPostFilter(FilterRules{Exclude: []string{"/(/"}}, nil); err == nil {
+		t.Error("NewPostFilter() with broken regex: want error")
+	}
+}
+
+func TestSenderSkipsFilteredPost(t *testing.T) {
+	cli := tgfake.New(1, 0)
+	n := &stubNeuro{text: "Отличный разбор 👍"}
+	s := newTestSender(cli, n, "")
+	f, err := NewPostFilter(DefaultFilterRules, nil)
+	if err != nil {
+		t.Fatalf("NewPostFilter() error = %v", err)
+	}
+	s.SetPostFilter(f)
+
+	msg := testMessage()
+	msg.Text = "Розыгрыш iPhone среди подписчиков!"
+	if err := sendAndDeliver(s, msg); err != nil {
+		t.Fatalf("SendComment() error = %v", err)
+	}
+	if n.calls != 0 || len(cli.SentTo(testChatID)) != 0 {
+		t.Errorf("GetComment calls = %d, sent = %d; want filtered before the LLM", n.calls, len(cli.SentTo(testChatID)))
+	}
+	// тред не отмечен: если правила ослабят, пост можно будет прокомментировать
+	if !s.Allow(context.Background(), msg.ChatID, msg.MessageThreadId) {
+		t.Error("filtered thread marked as seen")
+	}
+}
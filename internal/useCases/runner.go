@@ -2,9 +2,13 @@ package useCases
 
 import (
 	"context"
+	"errors"
 	"log/slog"
+	"sort"
 	"sync"
+	"time"
 
+	"github.com/larriantoniy/tg_user_bot/internal/domain"
 	"github.com/larriantoniy/tg_user_bot/internal/ports"
 )
 
@@ -12,59 +16,554 @@ type Runner struct {
 	cfgRepo ports.SessionConfigRepo
 	log     *slog.Logger
 	factory func(cfg *ports.SessionConfig, log *slog.Logger) (ports.TelegramClient, error)
+	policy  RestartPolicy
+
+	mu          sync.Mutex
+	sessions    map[string]*sessionEntry
+	subscribers map[chan domain.SessionEvent]struct{}
+
+	out      chan RunningSession
+	wg       sync.WaitGroup // супервизоры; out закрывается, когда все завершились
+	stopping bool           // ctx StartAll отменён, новые сессии не запускаем
+}
+
+// RestartPolicy — как супервизор перезапускает упавшую сессию
+type RestartPolicy struct {
+	InitialBackoff time.Duration // пауза перед первым рестартом, дальше удваивается
+	MaxBackoff     time.Duration
+	MaxRestarts    int           // подряд; 0 — без ограничения
+	ResetAfter     time.Duration // проработала дольше — счётчик рестартов обнуляется
+}
+
+// DefaultRestartPolicy используется, если в конфиге ничего не задано
+var DefaultRestartPolicy = RestartPolicy{
+	InitialBackoff: 10 * time.Second,
+	MaxBackoff:     10 * time.Minute,
+	MaxRestarts:    10,
+	ResetAfter:     30 * time.Minute,
+}
+
+// sessionEntry — то, что Runner помнит о сессии для админки
+type sessionEntry struct {
+	state     domain.SessionState
+	err       error
+	startedAt time.Time
+	restarts  int
+	sender    *Sender
+	done      chan error // сигнал от MarkStopped для текущего запуска
+
+	cancel   context.CancelFunc   // останавливает супервизор сессии
+	removed  bool                 // папка сессии пропала из BaseDir
+	client   ports.TelegramClient // клиент текущего запуска, nil между запусками
+	channels []string             // на что подписан текущий клиент
 }
 
 func NewRunner(
 	cfgRepo ports.SessionConfigRepo,
 	log *slog.Logger,
 	factory func(cfg *ports.SessionConfig, log *slog.Logger) (ports.TelegramClient, error),
+	policy RestartPolicy,
 ) *Runner {
-	return &Runner{cfgRepo: cfgRepo, log: log, factory: factory}
+	return &Runner{
+		cfgRepo:     cfgRepo,
+		log:         log,
+		factory:     factory,
+		policy:      policy,
+		sessions:    make(map[string]*sessionEntry),
+		subscribers: make(map[chan domain.SessionEvent]struct{}),
+	}
 }
 
-// StartAll запускает клиентов по всем доступным сессиям
-func (r *Runner) StartAll(ctx context.Context) (<-chan ports.TelegramClient, error) {
-	ch := make(chan ports.TelegramClient)
+// RunningSession — запущенный клиент вместе с именем и конфигом его сессии.
+// Ctx отменяется, когда этот запуск клиента закончился (в том числе перед рестартом):
+// всё, что привязано к клиенту, должно жить в нём.
+type RunningSession struct {
+	Name   string
+	Config *ports.SessionConfig
+	Client ports.TelegramClient
+	Ctx    context.Context
+}
 
+// StartAll запускает по супервизору на каждую доступную сессию.
+// После каждого (пере)запуска клиента в канал приходит новый RunningSession;
+// канал закрывается после отмены ctx, когда все супервизоры завершились.
+func (r *Runner) StartAll(ctx context.Context) (<-chan RunningSession, error) {
 	sessions, err := r.cfgRepo.ListSessions(ctx)
 	if err != nil {
+		ch := make(chan RunningSession)
 		close(ch)
 		return ch, err
 	}
 
+	r.out = make(chan RunningSession)
+
+	// держим wg, пока жив ctx: сессии из Watch могут добавиться в любой момент
+	r.wg.Add(1)
+	go func() {
+		<-ctx.Done()
+		r.mu.Lock()
+		r.stopping = true
+		r.mu.Unlock()
+		r.wg.Done()
+	}()
+
+	for _, sName := range sessions {
+		r.startSession(ctx, sName)
+	}
+
+	go func() {
+		r.wg.Wait()
+		close(r.out)
+	}()
+
+	return r.out, nil
+}
+
+// startSession запускает супервизор сессии; false — сессия уже известна или раннер останавливается.
+// Упавшая насовсем сессия остаётся в реестре и снова запустится, только если её папку убрать и вернуть.
+func (r *Runner) startSession(ctx context.Context, name string) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.stopping {
+		return false
+	}
+	if _, ok := r.sessions[name]; ok {
+		return false
+	}
+
+	sctx, cancel := context.WithCancel(ctx)
+	r.entry(name).cancel = cancel
+
+	r.wg.Add(1)
 	go func() {
-		var wg sync.WaitGroup
+		defer r.wg.Done()
+		defer cancel()
+		r.supervise(sctx, name, r.out)
 
-		for _, sName := range sessions {
-			wg.Add(1)
-			go func(sName string) {
-				defer wg.Done()
+		r.mu.Lock()
+		removed := r.sessions[name].removed
+		if removed {
+			delete(r.sessions, name)
+		}
+		r.mu.Unlock()
+		if removed {
+			r.log.Info("session removed", "session", name)
+			r.emit(domain.SessionEvent{Session: name, Type: domain.SessionEventRemoved})
+		}
+	}()
+	return true
+}
 
-				cfg, err := r.cfgRepo.GetSessionConfig(ctx, sName)
-				if err != nil {
-					r.log.Error("GetSessionConfig failed", "session", sName, "error", err)
-					return
-				}
+// Watch раз в interval перечитывает список сессий: запускает новые, останавливает
+// удалённые и переподписывает живых клиентов на изменившиеся Channels.
+// Остальные сессии не трогаются. Вызывать после StartAll.
+func (r *Runner) Watch(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
 
-				cli, err := r.factory(cfg, r.log)
-				if err != nil {
-					r.log.Error("factory failed", "session", sName, "error", err)
-					return
-				}
-				cli.JoinChannels(cfg.Channels)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			r.sync(ctx)
+		}
+	}
+}
 
-				r.log.Info("client started", "session", sName)
-				ch <- cli
+func (r *Runner) sync(ctx context.Context) {
+	names, err := r.cfgRepo.ListSessions(ctx)
+	if err != nil {
+		r.log.Error("ListSessions failed, keeping current sessions", "error", err)
+		return
+	}
 
-				<-ctx.Done()
-				cli.Close()
-				r.log.Info("client stopped", "session", sName)
-			}(sName)
+	listed := make(map[string]struct{}, len(names))
+	for _, name := range names {
+		listed[name] = struct{}{}
+		if r.startSession(ctx, name) {
+			r.log.Info("session added", "session", name)
+			r.emit(domain.SessionEvent{Session: name, Type: domain.SessionEventAdded})
 		}
+	}
 
-		wg.Wait()
-		close(ch)
+	var gone []context.CancelFunc
+	var goneNames []string
+	r.mu.Lock()
+	for name, e := range r.sessions {
+		if _, ok := listed[name]; ok || e.removed {
+			continue
+		}
+		e.removed = true
+		gone = append(gone, e.cancel)
+		goneNames = append(goneNames, name)
+	}
+	r.mu.Unlock()
+	for i, cancel := range gone {
+		r.log.Info("session folder removed, stopping", "session", goneNames[i])
+		cancel()
+	}
+
+	for _, name := range names {
+		r.reloadChannels(ctx, name)
+	}
+}
+
+// reloadChannels подписывает живой клиент на новые каналы из <session>.json и отписывает от убранных
+func (r *Runner) reloadChannels(ctx context.Context, name string) {
+	r.mu.Lock()
+	e, ok := r.sessions[name]
+	if !ok || e.client == nil || e.removed {
+		r.mu.Unlock()
+		return
+	}
+	cli, current := e.client, e.channels
+	r.mu.Unlock()
+
+	cfg, err := r.cfgRepo.GetSessionConfig(ctx, name)
+	if err != nil {
+		r.log.Warn("GetSessionConfig failed, channels not reloaded", "session", name, "error", err)
+		return
+	}
+
+	added, removed := diffChannels(current, cfg.Channels)
+	if len(added) == 0 && len(removed) == 0 {
+		return
+	}
+
+	r.log.Info("session channels changed", "session", name, "added", added, "removed", removed)
+	if len(added) > 0 {
+		cli.JoinChannels(added)
+	}
+	for _, ch := range removed {
+		if err := cli.LeaveChannel(ch); err != nil {
+			r.log.Warn("LeaveChannel failed", "session", name, "channel", ch, "error", err)
+		}
+	}
+
+	r.mu.Lock()
+	if e.client == cli {
+		e.channels = cfg.Channels
+	}
+	r.mu.Unlock()
+	r.emit(domain.SessionEvent{Session: name, Type: domain.SessionEventReloaded})
+}
+
+// diffChannels возвращает каналы, которые появились в next, и пропавшие из prev
+func diffChannels(prev, next []string) (added, removed []string) {
+	in := func(list []string, ch string) bool {
+		for _, c := range list {
+			if c == ch {
+				return true
+			}
+		}
+		return false
+	}
+	for _, ch := range next {
+		if !in(prev, ch) {
+			added = append(added, ch)
+		}
+	}
+	for _, ch := range prev {
+		if !in(next, ch) {
+			removed = append(removed, ch)
+		}
+	}
+	return added, removed
+}
+
+// supervise держит сессию живой: перезапускает клиента с экспоненциальным backoff,
+// пока не отменён ctx, не случилась постоянная ошибка или не исчерпан лимит рестартов.
+func (r *Runner) supervise(ctx context.Context, name string, ch chan<- RunningSession) {
+	backoff := r.policy.InitialBackoff
+	restarts := 0
+
+	for {
+		r.setState(name, domain.SessionStarting, nil)
+		startedAt := time.Now()
+
+		err := r.runOnce(ctx, name, ch)
+		if ctx.Err() != nil {
+			r.setState(name, domain.SessionStopped, nil)
+			r.emit(domain.SessionEvent{Session: name, Type: domain.SessionEventShutdown, Restarts: restarts})
+			r.log.Info("client stopped", "session", name)
+			return
+		}
+
+		r.emit(domain.SessionEvent{Session: name, Type: domain.SessionEventStopped, Error: errString(err), Restarts: restarts})
+
+		if ports.IsPermanent(err) {
+			r.log.Error("session failed permanently, not restarting", "session", name, "error", err)
+			r.setState(name, domain.SessionFailed, err)
+			r.emit(domain.SessionEvent{Session: name, Type: domain.SessionEventFailed, Error: errString(err), Permanent: true, Restarts: restarts})
+			return
+		}
+
+		if r.policy.ResetAfter > 0 && time.Since(startedAt) >= r.policy.ResetAfter {
+			restarts = 0
+			backoff = r.policy.InitialBackoff
+		}
+		if r.policy.MaxRestarts > 0 && restarts >= r.policy.MaxRestarts {
+			r.log.Error("session restart limit reached", "session", name, "restarts", restarts, "error", err)
+			r.setState(name, domain.SessionFailed, err)
+			r.emit(domain.SessionEvent{Session: name, Type: domain.SessionEventFailed, Error: errString(err), Restarts: restarts})
+			return
+		}
+
+		restarts++
+		r.setRestarts(name, restarts)
+		r.setState(name, domain.SessionRestarting, err)
+		r.emit(domain.SessionEvent{Session: name, Type: domain.SessionEventRestarting, Error: errString(err), Restarts: restarts, Backoff: backoff})
+		r.log.Warn("session stopped, restarting", "session", name, "restarts", restarts, "backoff", backoff, "error", err)
+
+		timer := time.NewTimer(backoff)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			r.setState(name, domain.SessionStopped, nil)
+			r.emit(domain.SessionEvent{Session: name, Type: domain.SessionEventShutdown, Restarts: restarts})
+			return
+		case <-timer.C:
+		}
+
+		backoff *= 2
+		if r.policy.MaxBackoff > 0 && backoff > r.policy.MaxBackoff {
+			backoff = r.policy.MaxBackoff
+		}
+	}
+}
+
+// runOnce поднимает клиента и ждёт, пока он не остановится. Возвращает причину остановки.
+func (r *Runner) runOnce(ctx context.Context, name string, ch chan<- RunningSession) error {
+	cfg, err := r.cfgRepo.GetSessionConfig(ctx, name)
+	if err != nil {
+		r.log.Error("GetSessionConfig failed", "session", name, "error", err)
+		return err
+	}
+
+	cli, err := r.factory(cfg, r.log)
+	if err != nil {
+		r.log.Error("factory failed", "session", name, "error", err)
+		return err
+	}
+	defer cli.Close()
+	cli.JoinChannels(cfg.Channels)
+
+	runCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	done := make(chan error, 1)
+	r.mu.Lock()
+	e := r.entry(name)
+	e.done = done
+	e.client = cli
+	e.channels = cfg.Channels
+	r.mu.Unlock()
+	defer func() {
+		r.mu.Lock()
+		e.client = nil
+		e.channels = nil
+		r.mu.Unlock()
 	}()
 
-	return ch, nil
+	r.log.Info("client started", "session", name)
+	r.setState(name, domain.SessionAuthorized, nil)
+	r.emit(domain.SessionEvent{Session: name, Type: domain.SessionEventStarted, Restarts: r.restartsOf(name)})
+
+	select {
+	case ch <- RunningSession{Name: name, Config: cfg, Client: cli, Ctx: runCtx}:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case err := <-done:
+		if err == nil {
+			err = errClientClosed
+		}
+		return err
+	}
+}
+
+// errClientClosed — клиент закрылся без объяснения причины
+var errClientClosed = errors.New("telegram client closed")
+
+// Subscribe подписывает на события жизненного цикла сессий. Медленный подписчик
+// теряет события, а не тормозит супервизоры. cancel отписывает и закрывает канал.
+func (r *Runner) Subscribe(buffer int) (<-chan domain.SessionEvent, func()) {
+	ch := make(chan domain.SessionEvent, buffer)
+	r.mu.Lock()
+	r.subscribers[ch] = struct{}{}
+	r.mu.Unlock()
+
+	var once sync.Once
+	cancel := func() {
+		once.Do(func() {
+			r.mu.Lock()
+			delete(r.subscribers, ch)
+			r.mu.Unlock()
+			close(ch)
+		})
+	}
+	return ch, cancel
+}
+
+func (r *Runner) emit(ev domain.SessionEvent) {
+	if ev.At.IsZero() {
+		ev.At = time.Now()
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for ch := range r.subscribers {
+		select {
+		case ch <- ev:
+		default:
+			r.log.Warn("session event dropped: subscriber is slow", "session", ev.Session, "type", ev.Type)
+		}
+	}
+}
+
+func errString(err error) string {
+	if err == nil {
+		return ""
+	}
+	return err.Error()
+}
+
+// entry возвращает запись сессии, создавая её при необходимости. Вызывать под r.mu.
+func (r *Runner) entry(name string) *sessionEntry {
+	e, ok := r.sessions[name]
+	if !ok {
+		e = &sessionEntry{}
+		r.sessions[name] = e
+	}
+	return e
+}
+
+func (r *Runner) setState(name string, state domain.SessionState, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	e := r.entry(name)
+	if state == domain.SessionStarting {
+		e.startedAt = time.Now()
+	}
+	e.state = state
+	e.err = err
+}
+
+func (r *Runner) setRestarts(name string, restarts int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.entry(name).restarts = restarts
+}
+
+func (r *Runner) restartsOf(name string) int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.entry(name).restarts
+}
+
+// Bind привязывает Sender к сессии, чтобы админка видела очередь и могла ставить паузы.
+// После рестарта паузы переезжают со старого Sender на новый.
+func (r *Runner) Bind(name string, sender *Sender) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if e, ok := r.sessions[name]; ok {
+		if e.sender != nil && e.sender != sender {
+			sender.copyPauses(e.sender)
+		}
+		e.sender = sender
+	}
+}
+
+// MarkStopped сообщает супервизору, что сессия перестала слушать обновления.
+// err — причина (например, StopReason клиента); супервизор решает, перезапускать ли.
+func (r *Runner) MarkStopped(name string, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	e, ok := r.sessions[name]
+	if !ok || e.done == nil {
+		return
+	}
+	select {
+	case e.done <- err:
+	default:
+	}
+}
+
+// Sessions возвращает состояние всех известных сессий, отсортированных по имени
+func (r *Runner) Sessions(ctx context.Context) []domain.SessionStatus {
+	r.mu.Lock()
+	names := make([]string, 0, len(r.sessions))
+	for name := range r.sessions {
+		names = append(names, name)
+	}
+	r.mu.Unlock()
+	sort.Strings(names)
+
+	out := make([]domain.SessionStatus, 0, len(names))
+	for _, name := range names {
+		if st, err := r.Session(ctx, name); err == nil {
+			out = append(out, st)
+		}
+	}
+	return out
+}
+
+// Session возвращает состояние одной сессии
+func (r *Runner) Session(ctx context.Context, name string) (domain.SessionStatus, error) {
+	r.mu.Lock()
+	e, ok := r.sessions[name]
+	if !ok {
+		r.mu.Unlock()
+		return domain.SessionStatus{}, domain.ErrSessionNotFound
+	}
+	st := domain.SessionStatus{
+		Name:      name,
+		State:     e.state,
+		StartedAt: e.startedAt,
+		Restarts:  e.restarts,
+	}
+	if e.err != nil {
+		st.Error = e.err.Error()
+	}
+	sender := e.sender
+	r.mu.Unlock()
+
+	if sender != nil {
+		sender.fillStatus(ctx, &st)
+	}
+	return st, nil
+}
+
+// PauseSession ставит сессию на паузу (paused=false — снимает)
+func (r *Runner) PauseSession(name string, paused bool) error {
+	sender, err := r.sender(name)
+	if err != nil {
+		return err
+	}
+	sender.SetPaused(paused)
+	return nil
+}
+
+// PauseChat ставит на паузу один чат обсуждения в сессии (paused=false — снимает)
+func (r *Runner) PauseChat(name string, chatID int64, paused bool) error {
+	sender, err := r.sender(name)
+	if err != nil {
+		return err
+	}
+	sender.SetChatPaused(chatID, paused)
+	return nil
+}
+
+func (r *Runner) sender(name string) (*Sender, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	e, ok := r.sessions[name]
+	if !ok || e.sender == nil {
+		return nil, domain.ErrSessionNotFound
+	}
+	return e.sender, nil
 }
@@ -0,0 +1,261 @@
+package useCases
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/larriantoniy/tg_user_bot/internal/adapters/tgfake"
+	"github.com/larriantoniy/tg_user_bot/internal/domain"
+	"github.com/larriantoniy/tg_user_bot/internal/ports"
+)
+
+type stubConfigRepo struct {
+	mu       sync.Mutex
+	sessions []string
+	channels map[string][]string // по умолчанию ["@news"]
+}
+
+func (r *stubConfigRepo) ListSessions(ctx context.Context) ([]string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]string(nil), r.sessions...), nil
+}
+
+func (r *stubConfigRepo) GetSessionConfig(ctx context.Context, name string) (*ports.SessionConfig, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	channels, ok := r.channels[name]
+	if !ok {
+		channels = []string{"@news"}
+	}
+	return &ports.SessionConfig{SessionName: name, Channels: channels}, nil
+}
+
+func (r *stubConfigRepo) set(sessions []string, channels map[string][]string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.sessions = sessions
+	r.channels = channels
+}
+
+// stubFactory по очереди отдаёт заготовленные результаты; когда они кончились — повторяет последний
+type stubFactory struct {
+	mu      sync.Mutex
+	results []func() (ports.TelegramClient, error)
+	calls   int
+}
+
+func (f *stubFactory) factory(cfg *ports.SessionConfig, log *slog.Logger) (ports.TelegramClient, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	i := f.calls
+	if i >= len(f.results) {
+		i = len(f.results) - 1
+	}
+	f.calls++
+	return f.results[i]()
+}
+
+func (f *stubFactory) callCount() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.calls
+}
+
+func returns(c *tgfake.Client) func() (ports.TelegramClient, error) {
+	return func() (ports.TelegramClient, error) { return c, nil }
+}
+
+func failure(err error) func() (ports.TelegramClient, error) {
+	return func() (ports.TelegramClient, error) { return nil, err }
+}
+
+var testRestartPolicy = RestartPolicy{
+	InitialBackoff: time.Millisecond,
+	MaxBackoff:     4 * time.Millisecond,
+	MaxRestarts:    2,
+	ResetAfter:     time.Hour,
+}
+
+// collectEvents читает события до события типа last или таймаута
+func collectEvents(t *testing.T, events <-chan domain.SessionEvent, last domain.SessionEventType) []domain.SessionEventType {
+	t.Helper()
+	var got []domain.SessionEventType
+	timeout := time.After(2 * time.Second)
+	for {
+		select {
+		case ev := <-events:
+			got = append(got, ev.Type)
+			if ev.Type == last {
+				return got
+			}
+		case <-timeout:
+			t.Fatalf("timeout waiting for %q, got events %v", last, got)
+		}
+	}
+}
+
+func TestRunnerRestartsClosedClient(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	first, second := tgfake.New(1, 0), tgfake.New(1, 0)
+	f := &stubFactory{results: []func() (ports.TelegramClient, error){returns(first), returns(second)}}
+	r := NewRunner(&stubConfigRepo{sessions: []string{testSession}}, discardLogger(), f.factory, testRestartPolicy)
+	events, unsubscribe := r.Subscribe(16)
+	defer unsubscribe()
+
+	sessions, err := r.StartAll(ctx)
+	if err != nil {
+		t.Fatalf("StartAll() error = %v", err)
+	}
+
+	rs := <-sessions
+	first.Disconnect(nil)
+	r.MarkStopped(rs.Name, rs.Client.StopReason())
+
+	restarted := <-sessions
+	if restarted.Client != second {
+		t.Fatalf("restarted client is not the second one")
+	}
+	select {
+	case <-rs.Ctx.Done():
+	default:
+		t.Error("context of the first run is not canceled after restart")
+	}
+
+	want := []domain.SessionEventType{
+		domain.SessionEventStarted,
+		domain.SessionEventStopped,
+		domain.SessionEventRestarting,
+		domain.SessionEventStarted,
+	}
+	got := collectEvents(t, events, domain.SessionEventStarted)
+	got = append(got, collectEvents(t, events, domain.SessionEventStarted)...)
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("events = %v, want %v", got, want)
+	}
+
+	st, err := r.Session(ctx, testSession)
+	if err != nil {
+		t.Fatalf("Session() error = %v", err)
+	}
+	if st.State != domain.SessionAuthorized || st.Restarts != 1 {
+		t.Errorf("status = %+v, want authorized after 1 restart", st)
+	}
+
+	cancel()
+	collectEvents(t, events, domain.SessionEventShutdown)
+	if !second.Closed() {
+		t.Error("second client is not closed on shutdown")
+	}
+}
+
+func TestRunnerDoesNotRestartPermanentError(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	f := &stubFactory{results: []func() (ports.TelegramClient, error){
+		failure(fmt.Errorf("%w: USER_DEACTIVATED_BAN", ports.ErrBanned)),
+	}}
+	r := NewRunner(&stubConfigRepo{sessions: []string{testSession}}, discardLogger(), f.factory, testRestartPolicy)
+	events, unsubscribe := r.Subscribe(16)
+	defer unsubscribe()
+
+	if _, err := r.StartAll(ctx); err != nil {
+		t.Fatalf("StartAll() error = %v", err)
+	}
+
+	got := collectEvents(t, events, domain.SessionEventFailed)
+	want := []domain.SessionEventType{domain.SessionEventStopped, domain.SessionEventFailed}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("events = %v, want %v", got, want)
+	}
+	if n := f.callCount(); n != 1 {
+		t.Errorf("factory calls = %d, want 1", n)
+	}
+	st, _ := r.Session(ctx, testSession)
+	if st.State != domain.SessionFailed || st.Error == "" {
+		t.Errorf("status = %+v, want failed with error", st)
+	}
+}
+
+func TestRunnerGivesUpAfterMaxRestarts(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	f := &stubFactory{results: []func() (ports.TelegramClient, error){failure(errors.New("network is unreachable"))}}
+	r := NewRunner(&stubConfigRepo{sessions: []string{testSession}}, discardLogger(), f.factory, testRestartPolicy)
+	events, unsubscribe := r.Subscribe(16)
+	defer unsubscribe()
+
+	if _, err := r.StartAll(ctx); err != nil {
+		t.Fatalf("StartAll() error = %v", err)
+	}
+	collectEvents(t, events, domain.SessionEventFailed)
+
+	// первая попытка + MaxRestarts рестартов
+	if n := f.callCount(); n != 1+testRestartPolicy.MaxRestarts {
+		t.Errorf("factory calls = %d, want %d", n, 1+testRestartPolicy.MaxRestarts)
+	}
+	st, _ := r.Session(ctx, testSession)
+	if st.State != domain.SessionFailed || st.Restarts != testRestartPolicy.MaxRestarts {
+		t.Errorf("status = %+v, want failed after %d restarts", st, testRestartPolicy.MaxRestarts)
+	}
+}
+
+func TestRunnerSyncAddsRemovesAndReloadsSessions(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	clients := map[string]*tgfake.Client{"alpha": tgfake.New(1, 0), "beta": tgfake.New(2, 0)}
+	factory := func(cfg *ports.SessionConfig, log *slog.Logger) (ports.TelegramClient, error) {
+		return clients[cfg.SessionName], nil
+	}
+	repo := &stubConfigRepo{sessions: []string{"alpha"}}
+	r := NewRunner(repo, discardLogger(), factory, testRestartPolicy)
+	events, unsubscribe := r.Subscribe(32)
+	defer unsubscribe()
+
+	sessions, err := r.StartAll(ctx)
+	if err != nil {
+		t.Fatalf("StartAll() error = %v", err)
+	}
+	if rs := <-sessions; rs.Name != "alpha" {
+		t.Fatalf("first session = %q, want alpha", rs.Name)
+	}
+
+	// появилась beta, у alpha поменялись каналы
+	repo.set([]string{"alpha", "beta"}, map[string][]string{"alpha": {"@news", "@markets"}})
+	r.sync(ctx)
+	if rs := <-sessions; rs.Name != "beta" {
+		t.Fatalf("added session = %q, want beta", rs.Name)
+	}
+	collectEvents(t, events, domain.SessionEventReloaded)
+	if got := clients["alpha"].Joined(); fmt.Sprint(got) != fmt.Sprint([]string{"@news", "@markets"}) {
+		t.Errorf("alpha joined = %v, want @news then @markets", got)
+	}
+
+	// alpha удалили: beta работает дальше
+	repo.set([]string{"beta"}, nil)
+	r.sync(ctx)
+	collectEvents(t, events, domain.SessionEventRemoved)
+
+	if _, err := r.Session(ctx, "alpha"); !errors.Is(err, domain.ErrSessionNotFound) {
+		t.Errorf("Session(alpha) error = %v, want ErrSessionNotFound", err)
+	}
+	if !clients["alpha"].Closed() {
+		t.Error("removed session client is not closed")
+	}
+	if st, err := r.Session(ctx, "beta"); err != nil || st.State != domain.SessionAuthorized {
+		t.Errorf("Session(beta) = %+v, %v; want authorized", st, err)
+	}
+	if clients["beta"].Closed() {
+		t.Error("unaffected session client is closed")
+	}
+}
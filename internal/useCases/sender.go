@@ -6,48 +6,69 @@ import (
 	"fmt"
 	"log/slog"
 	"math/rand"
+	"sort"
 	"strings"
 	"sync"
 	"time"
 
-	"github.com/larriantoniy/tg_user_bot/internal/adapters/tg"
+	"github.com/google/uuid"
 	"github.com/larriantoniy/tg_user_bot/internal/domain"
 	"github.com/larriantoniy/tg_user_bot/internal/ports"
 )
 
-type ThreadKey struct {
-	ChatID   int64
-	ThreadID int64
-}
-type CommentLimiter struct {
-	mu   sync.Mutex
-	seen map[ThreadKey]struct{}
-}
-
 type Sender struct {
 	log   *slog.Logger
 	tg    ports.TelegramClient
 	neuro ports.NeuroProccesor
 
 	ownerUsername string
-	ownerUserID   int64 // кеш, чтобы не делать каждый раз resolve
-	limited       bool  // флаг: сессия ушла в rate limit
+	ownerUserID   int64     // кеш, чтобы не делать каждый раз resolve
+	limitedUntil  time.Time // FLOOD_WAIT: до этого момента сессия ничего не отправляет
+	quotaUntil    time.Time // квота или бюджет LLM кончились: до этого момента нейросеть не зовём
+	quotaReason   domain.SkipReason
+	usage         ports.UsageReporter // для /usage владельца; nil — команда недоступна
+	validator     *CommentValidator   // nil — ответ нейросети только обрезается по краям
+	moderator     ports.Moderator     // nil — без модерации постов и ответов
+	dedup         *DedupRules         // nil — повторы недавних комментариев не ищем
+	conversation  *ConversationRules  // nil — на ответы к нашим комментариям не отвечаем
+	filter        *PostFilter         // nil — нейросеть видит все посты
 	limiter       *CommentLimiter
+	queue         ports.CommentQueue
+	metrics       ports.Metrics
 	mu            sync.Mutex
 	lastCommentAt time.Time
+	lastSentAt    time.Time
+	paused        bool               // пауза всей сессии из админки
+	pausedChats   map[int64]struct{} // пауза отдельных чатов обсуждений
+	approval      bool               // режим одобрения: черновики сначала уходят владельцу
+	drafts        map[string]*draft  // черновики, ждущие решения владельца
+	draftSeq      int
 	minInterval   time.Duration
+	minDelay      time.Duration
+	maxDelay      time.Duration
 }
 
+// ErrPaused — сессия или чат поставлены на паузу; задание остаётся в очереди
+var ErrPaused = errors.New("sender: paused")
+
 const (
 	minDelay = 15 * time.Minute
 	maxDelay = 30 * time.Minute
+
+	// floodWaitFallback — пауза, если Telegram не сообщил retry_after
+	floodWaitFallback = 5 * time.Minute
+
+	// neuroQuotaFallback — пауза, если провайдер не прислал Retry-After вместе с исчерпанной квотой
+	neuroQuotaFallback = 15 * time.Minute
 )
 
 func NewSender(
 	log *slog.Logger,
 	tg ports.TelegramClient,
 	neuro ports.NeuroProccesor,
-
+	limiter *CommentLimiter,
+	queue ports.CommentQueue,
+	metrics ports.Metrics,
 	owner string, // "@user"
 ) *Sender {
 	return &Sender{
@@ -56,28 +77,54 @@ func NewSender(
 		neuro:         neuro,
 		ownerUsername: owner,
 		minInterval:   10 * time.Minute,
-		limiter:       &CommentLimiter{seen: make(map[ThreadKey]struct{})},
+		minDelay:      minDelay,
+		maxDelay:      maxDelay,
+		limiter:       limiter,
+		queue:         queue,
+		metrics:       metrics,
+		pausedChats:   make(map[int64]struct{}),
+		drafts:        make(map[string]*draft),
 	}
 }
 func (s *Sender) SendComment(ctx context.Context, msg *domain.Message) error {
-	if !s.Allow(msg.ChatID, msg.MessageThreadId) {
-		return fmt.Errorf("SendComment: ChatID %d is not allowed because be send already", msg.ChatID)
+	if msg.IsReply {
+		return s.sendReply(ctx, msg)
 	}
-	s.mu.Lock()
-	limited := s.limited
-	s.mu.Unlock()
-	if limited {
-		s.log.Warn("Skip SendComment: session is already rate-limited",
-			"chat_id", msg.ChatID,
-			"msg_thread_id", msg.MessageThreadId,
-		)
-		return tg.ErrRateLimited
+	s.metrics.PostReceived(s.limiter.session)
+	if stop, err := s.holdOff(msg, "SendComment"); stop {
+		return err
+	}
+	// до Allow и до нейросети: реклама и короткие посты не стоят ни записи в сторе, ни токенов
+	if f := s.postFilter(); f != nil {
+		if rule := f.Check(msg); rule != "" {
+			s.log.Info("Skip SendComment: post filtered",
+				"chat_id", msg.ChatID,
+				"msg_thread_id", msg.MessageThreadId,
+				"channel", msg.ChatName,
+				"rule", rule,
+			)
+			s.skip(domain.SkipFiltered)
+			return nil
+		}
 	}
+	if !s.Allow(ctx, msg.ChatID, msg.MessageThreadId) {
+		s.skip(domain.SkipAlreadySeen)
+		return fmt.Errorf("SendComment: ChatID %d is not allowed because be send already", msg.ChatID)
+	}
+	// тред остаётся отмеченным, только если комментарий попал в очередь или к владельцу:
+	// отметка персистентная, и сбой нейросети иначе потерял бы пост навсегда
+	planned := false
+	defer func() {
+		if !planned {
+			s.release(msg.ChatID, msg.MessageThreadId)
+		}
+	}()
 	if !s.tg.CanSendToChat(msg.ChatID) {
 		s.log.Info("Skip SendComment: cannot send to chat",
 			"chat_id", msg.ChatID,
 			"msg_thread_id", msg.MessageThreadId,
 		)
+		s.skip(domain.SkipCannotSend)
 		return nil
 	}
 	if !s.tg.IsMember(msg.ChatID) {
@@ -85,35 +132,275 @@ func (s *Sender) SendComment(ctx context.Context, msg *domain.Message) error {
 			"chat_id", msg.ChatID,
 			"msg_thread_id", msg.MessageThreadId,
 		)
+		s.skip(domain.SkipNotMember)
 		return nil
 	}
+	if reason := s.moderatePost(ctx, msg); reason != "" {
+		s.skip(reason)
+		return nil
+	}
+	// картинки качаем только для поста, который точно уйдёт в нейросеть
+	s.tg.DownloadPhotos(msg)
 	//  сначала генерим текст от нейросети
 
-	replyText, err := s.neuro.GetComment(ctx, msg)
+	replyText, reason, err := s.generate(ctx, msg)
 	if err != nil {
-		s.log.Error("GetComment", "error", err)
-		return err
+		return s.neuroFailed(err, "SendComment")
 	}
-	replyText = strings.TrimSpace(replyText)
-	if replyText == "" {
-		s.log.Info("Skip SendComment: empty LLM response")
+	if reason != "" {
+		s.log.Info("Skip SendComment: no usable LLM response", "reason", reason)
+		s.skip(reason)
 		return nil
 	}
 
+	if s.approvalEnabled() {
+		err = s.proposeDraft(msg, replyText)
+	} else {
+		_, err = s.schedule(ctx, msg, replyText, false)
+	}
+	planned = err == nil
+	return err
+}
+
+// holdOff — сессия сейчас не комментирует: пауза, FLOOD_WAIT или кончилась квота LLM.
+// Проверяется до Allow, чтобы пропущенный тред не считался прокомментированным
+// и пост можно было прокомментировать позже. op — для логов.
+func (s *Sender) holdOff(msg *domain.Message, op string) (bool, error) {
+	if s.isPaused(msg.ChatID) {
+		s.log.Info("Skip "+op+": paused",
+			"chat_id", msg.ChatID,
+			"msg_thread_id", msg.MessageThreadId,
+		)
+		s.skip(domain.SkipPaused)
+		return true, nil
+	}
+	if until, limited := s.rateLimitedUntil(); limited {
+		s.log.Warn("Skip "+op+": session is in FLOOD_WAIT",
+			"chat_id", msg.ChatID,
+			"msg_thread_id", msg.MessageThreadId,
+			"limited_until", until,
+		)
+		s.skip(domain.SkipRateLimited)
+		return true, &ports.RateLimitError{RetryAfter: time.Until(until)}
+	}
+	if until, exhausted := s.neuroQuotaUntil(); exhausted {
+		reason := s.neuroQuotaReason()
+		s.log.Info("Skip "+op+": LLM quota exhausted",
+			"chat_id", msg.ChatID,
+			"msg_thread_id", msg.MessageThreadId,
+			"reason", reason,
+			"quota_until", until,
+		)
+		s.skip(reason)
+		return true, nil
+	}
+	return false, nil
+}
+
+// neuroFailed разбирает ошибку нейросети: квота и бюджет ставят паузу, остальное — пропуск поста
+func (s *Sender) neuroFailed(err error, op string) error {
+	if errors.Is(err, ports.ErrNeuroQuota) || errors.Is(err, ports.ErrNeuroBudget) {
+		s.enterNeuroQuota(err)
+		return err
+	}
+	if errors.Is(err, ports.ErrNeuroCircuitOpen) {
+		// провайдеры лежат, breaker уже отбил запрос без HTTP — не шумим ошибкой
+		s.log.Info("Skip "+op+": neuro circuit open", "error", err)
+		s.skip(domain.SkipNeuroCircuit)
+		return err
+	}
+	s.log.Error("GetComment", "transient", errors.Is(err, ports.ErrNeuroTransient), "error", err)
+	s.skip(domain.SkipNeuroError)
+	return err
+}
+
+// SetValidator включает проверку ответов нейросети; nil — выключить
+func (s *Sender) SetValidator(v *CommentValidator) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.validator = v
+}
+
+func (s *Sender) commentValidator() *CommentValidator {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.validator
+}
+
+// SetModerator включает модерацию постов и ответов; nil — выключить
+func (s *Sender) SetModerator(m ports.Moderator) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.moderator = m
+}
+
+func (s *Sender) postModerator() ports.Moderator {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.moderator
+}
+
+// moderatePost — пост на чувствительную тему не комментируем; непустой reason — пропустить
+func (s *Sender) moderatePost(ctx context.Context, msg *domain.Message) domain.SkipReason {
+	m := s.postModerator()
+	if m == nil {
+		return ""
+	}
+	v, err := m.Moderate(ctx, domain.ModeratePost, msg.Text)
+	if err != nil {
+		s.log.Warn("Skip SendComment: post moderation failed",
+			"chat_id", msg.ChatID,
+			"msg_thread_id", msg.MessageThreadId,
+			"error", err,
+		)
+		return domain.SkipModeration
+	}
+	if v.Flagged {
+		s.log.Info("Skip SendComment: sensitive post",
+			"chat_id", msg.ChatID,
+			"msg_thread_id", msg.MessageThreadId,
+			"category", v.Category,
+			"rule", v.Rule,
+			"source", v.Source,
+		)
+		return domain.SkipSensitivePost
+	}
+	return ""
+}
+
+// generate просит комментарий у нейросети и прогоняет его через валидатор и модерацию,
+// перегенерируя до rules.Regenerate раз. Непустой reason — годного текста нет.
+func (s *Sender) generate(ctx context.Context, msg *domain.Message) (string, domain.SkipReason, error) {
+	v := s.commentValidator()
+	m := s.postModerator()
+	d := s.dedupRules()
+	attempts := 1
+	switch {
+	case v != nil:
+		attempts += v.rules.Regenerate
+	case d != nil:
+		attempts += DefaultValidationRules.Regenerate
+	}
+	var own, global []string // недавние комментарии, читаем один раз на все попытки
+	loaded := false
+	reason := domain.SkipInvalidLLM
+	for i := 1; i <= attempts; i++ {
+		text, err := s.neuro.GetComment(ctx, msg)
+		if errors.Is(err, ports.ErrNeuroEmpty) {
+			return "", domain.SkipEmptyLLM, nil
+		}
+		if err != nil {
+			return "", "", err
+		}
+		text = strings.TrimSpace(text)
+		if text == "" {
+			// пустой ответ — сбой модели, а не плохой текст; перегенерация не поможет
+			return "", domain.SkipEmptyLLM, nil
+		}
+		if v != nil {
+			if bad := v.Validate(text, msg.Text); len(bad) > 0 {
+				s.log.Info("LLM comment rejected by validator",
+					"chat_id", msg.ChatID,
+					"msg_thread_id", msg.MessageThreadId,
+					"attempt", i,
+					"attempts", attempts,
+					"violations", bad,
+					"comment", text,
+				)
+				reason = domain.SkipInvalidLLM
+				continue
+			}
+		}
+		if d != nil {
+			if !loaded {
+				own, global = s.recentComments(ctx, *d)
+				loaded = true
+			}
+			if dup, ok := findDuplicate(text, d.Threshold, own, global); ok {
+				s.log.Info("LLM comment too similar to a recent one",
+					"chat_id", msg.ChatID,
+					"msg_thread_id", msg.MessageThreadId,
+					"attempt", i,
+					"attempts", attempts,
+					"similarity", dup.similarity,
+					"scope", dup.scope,
+					"similar_to", dup.text,
+					"comment", text,
+				)
+				reason = domain.SkipDuplicate
+				continue
+			}
+		}
+		if m == nil {
+			return text, "", nil
+		}
+		verdict, err := m.Moderate(ctx, domain.ModerateReply, text)
+		if err != nil {
+			// не знаем, безопасен ли ответ, — не отправляем
+			s.log.Warn("Reply moderation failed", "attempt", i, "error", err)
+			reason = domain.SkipModeration
+			continue
+		}
+		if !verdict.Flagged {
+			return text, "", nil
+		}
+		s.log.Info("LLM comment rejected by moderation",
+			"chat_id", msg.ChatID,
+			"msg_thread_id", msg.MessageThreadId,
+			"attempt", i,
+			"attempts", attempts,
+			"category", verdict.Category,
+			"rule", verdict.Rule,
+			"source", verdict.Source,
+			"comment", text,
+		)
+		reason = domain.SkipUnsafeReply
+	}
+	return "", reason, nil
+}
+
+// schedule ставит комментарий в очередь со случайной задержкой
+func (s *Sender) schedule(ctx context.Context, msg *domain.Message, text string, approved bool) (domain.CommentJob, error) {
+	delay := randomDelay(s.minDelay, s.maxDelay)
+	now := time.Now()
+	job := domain.CommentJob{
+		ID:        uuid.NewString(),
+		Session:   s.limiter.session,
+		Post:      *msg,
+		Text:      text,
+		DueAt:     now.Add(delay),
+		CreatedAt: now,
+		Approved:  approved,
+	}
+	if err := s.queue.Enqueue(ctx, job); err != nil {
+		s.log.Error("Enqueue comment failed", "error", err)
+		return job, err
+	}
+
 	s.log.Info("Planned comment delay",
 		"chat_id", msg.ChatID,
 		"msg_thread_id", msg.MessageThreadId,
-		"min_delay", minDelay,
-		"max_delay", maxDelay,
-		"comment", replyText,
+		"job_id", job.ID,
+		"delay", delay,
+		"due_at", job.DueAt,
+		"comment", text,
 	)
+	return job, nil
+}
 
-	if err := randomDelay(ctx, minDelay, maxDelay); err != nil {
-		s.log.Warn("Comment canceled during delay (shutdown?)", "error", err)
-		return err
+// Publish отправляет запланированный комментарий, когда подошёл его срок.
+// Вызывается CommentWorker'ом сессии.
+func (s *Sender) Publish(ctx context.Context, job domain.CommentJob) error {
+	msg := &job.Post
+
+	if until, limited := s.rateLimitedUntil(); limited {
+		return &ports.RateLimitError{RetryAfter: time.Until(until)}
+	}
+	if s.isPaused(msg.ChatID) {
+		return ErrPaused
 	}
 
-	// 3) общий rate-limit на аккаунт
+	// общий rate-limit на аккаунт
 	if err := s.waitRateLimit(ctx); err != nil {
 		s.log.Warn("Comment canceled by rate-limit wait (shutdown?)", "error", err)
 		return err
@@ -124,6 +411,7 @@ func (s *Sender) SendComment(ctx context.Context, msg *domain.Message) error {
 			"chat_id", msg.ChatID,
 			"msg_thread_id", msg.MessageThreadId,
 		)
+		s.skip(domain.SkipCannotSend)
 		return nil
 	}
 
@@ -131,83 +419,261 @@ func (s *Sender) SendComment(ctx context.Context, msg *domain.Message) error {
 		msg.ChatID,
 		msg.MessageThreadId,
 		msg.ReplyToMessageID,
-		replyText,
+		job.Text,
 	); err != nil {
-		if errors.Is(err, tg.ErrRateLimited) {
-			s.mu.Lock()
-			s.limited = true
-			s.mu.Unlock()
+		if errors.Is(err, ports.ErrRateLimited) {
+			s.enterFloodWait(err)
 		}
 		s.log.Error("SendComment", "error", err)
 		return err
 	}
+	s.mu.Lock()
+	s.lastSentAt = time.Now()
+	s.mu.Unlock()
+	s.metrics.CommentSent(s.limiter.session)
 	s.log.Info("Comment sent",
 		"chat_id", msg.ChatID,
 		"msg_thread_id", msg.MessageThreadId,
+		"job_id", job.ID,
 	)
-	// 5. отправляем уведомление Owner
-	err = s.sendOwnerNotify(msg, replyText)
-	if err != nil {
-		s.log.Warn("SendComment", "error", err)
+	if err := s.limiter.Record(ctx, msg, job.Text); err != nil {
+		s.log.Warn("Save sent comment to history failed", "error", err)
+	}
+	// отправляем уведомление Owner (одобренный черновик он уже видел)
+	if !job.Approved {
+		if err := s.sendOwnerNotify(msg, job.Text); err != nil {
+			s.log.Warn("SendComment", "error", err)
+		}
 	}
 
 	return nil
 }
 
-func (s *Sender) waitRateLimit(ctx context.Context) error {
+// enterFloodWait ставит сессию на паузу до истечения retry_after из ошибки Telegram
+func (s *Sender) enterFloodWait(err error) {
+	wait, ok := ports.RetryAfter(err)
+	if !ok || wait <= 0 {
+		wait = floodWaitFallback
+	}
+	until := time.Now().Add(wait)
+
+	s.mu.Lock()
+	if until.After(s.limitedUntil) {
+		s.limitedUntil = until
+	}
+	until = s.limitedUntil
+	s.mu.Unlock()
+
+	s.skip(domain.SkipRateLimited)
+	s.log.Warn("Session paused by FLOOD_WAIT",
+		"retry_after", wait,
+		"limited_until", until,
+	)
+}
+
+// rateLimitedUntil возвращает дедлайн FLOOD_WAIT, если он ещё не истёк.
+// Истёкший дедлайн сбрасывается — сессия продолжает работу сама.
+func (s *Sender) rateLimitedUntil() (time.Time, bool) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
+	if s.limitedUntil.IsZero() {
+		return time.Time{}, false
+	}
+	if time.Now().Before(s.limitedUntil) {
+		return s.limitedUntil, true
+	}
+	s.log.Info("FLOOD_WAIT expired, session resumed", "limited_until", s.limitedUntil)
+	s.limitedUntil = time.Time{}
+	return time.Time{}, false
+}
 
-	if s.lastCommentAt.IsZero() {
-		s.lastCommentAt = time.Now()
-		return nil
+// enterNeuroQuota перестаёт звать нейросеть до Retry-After из ошибки провайдера
+// (для исчерпанного бюджета — до следующего дня или месяца)
+func (s *Sender) enterNeuroQuota(err error) {
+	wait, ok := ports.NeuroRetryAfter(err)
+	if !ok {
+		wait = neuroQuotaFallback
+	}
+	until := time.Now().Add(wait)
+	reason := domain.SkipNeuroQuota
+	if errors.Is(err, ports.ErrNeuroBudget) {
+		reason = domain.SkipNeuroBudget
 	}
 
-	elapsed := time.Since(s.lastCommentAt)
-	if elapsed >= s.minInterval {
-		s.lastCommentAt = time.Now()
-		return nil
+	s.mu.Lock()
+	if until.After(s.quotaUntil) {
+		s.quotaUntil = until
+		s.quotaReason = reason
 	}
+	until = s.quotaUntil
+	s.mu.Unlock()
 
-	needWait := s.minInterval - elapsed
-	s.log.Info("Rate-limit delay before next comment", "wait", needWait)
+	s.skip(reason)
+	s.log.Warn("LLM quota exhausted, comments paused",
+		"reason", reason,
+		"retry_after", wait,
+		"quota_until", until,
+		"error", err,
+	)
+}
 
-	timer := time.NewTimer(needWait)
-	defer timer.Stop()
+func (s *Sender) neuroQuotaReason() domain.SkipReason {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.quotaReason
+}
 
-	select {
-	case <-ctx.Done():
-		return ctx.Err()
-	case <-timer.C:
-		s.lastCommentAt = time.Now()
-		return nil
+// neuroQuotaUntil возвращает дедлайн паузы по квоте, если он ещё не истёк
+func (s *Sender) neuroQuotaUntil() (time.Time, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.quotaUntil.IsZero() {
+		return time.Time{}, false
+	}
+	if time.Now().Before(s.quotaUntil) {
+		return s.quotaUntil, true
 	}
+	s.log.Info("LLM quota pause expired, comments resumed", "quota_until", s.quotaUntil)
+	s.quotaUntil = time.Time{}
+	return time.Time{}, false
 }
+
+func (s *Sender) skip(reason domain.SkipReason) {
+	s.metrics.CommentSkipped(s.limiter.session, reason)
+}
+
+// SetPaused ставит на паузу или снимает с паузы всю сессию
+func (s *Sender) SetPaused(paused bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.paused = paused
+	s.log.Info("Session pause changed", "paused", paused)
+}
+
+// SetChatPaused ставит на паузу или снимает с паузы один чат обсуждения
+func (s *Sender) SetChatPaused(chatID int64, paused bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if paused {
+		s.pausedChats[chatID] = struct{}{}
+	} else {
+		delete(s.pausedChats, chatID)
+	}
+	s.log.Info("Chat pause changed", "chat_id", chatID, "paused", paused)
+}
+
+// copyPauses переносит паузы с Sender'а предыдущего запуска сессии
+func (s *Sender) copyPauses(from *Sender) {
+	from.mu.Lock()
+	paused := from.paused
+	chats := make([]int64, 0, len(from.pausedChats))
+	for chatID := range from.pausedChats {
+		chats = append(chats, chatID)
+	}
+	from.mu.Unlock()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.paused = paused
+	for _, chatID := range chats {
+		s.pausedChats[chatID] = struct{}{}
+	}
+}
+
+func (s *Sender) isPaused(chatID int64) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.paused {
+		return true
+	}
+	_, ok := s.pausedChats[chatID]
+	return ok
+}
+
+// fillStatus дописывает в st то, что знает Sender: лимит, паузы, очередь, последний комментарий
+func (s *Sender) fillStatus(ctx context.Context, st *domain.SessionStatus) {
+	until, limited := s.rateLimitedUntil()
+	quotaUntil, exhausted := s.neuroQuotaUntil()
+	s.mu.Lock()
+	if limited {
+		if st.State == domain.SessionAuthorized {
+			st.State = domain.SessionRateLimited
+		}
+		st.RateLimitedUntil = &until
+	}
+	if exhausted {
+		st.NeuroQuotaUntil = &quotaUntil
+	}
+	st.Paused = s.paused
+	st.PendingDrafts = len(s.drafts)
+	for chatID := range s.pausedChats {
+		st.PausedChats = append(st.PausedChats, chatID)
+	}
+	if !s.lastSentAt.IsZero() {
+		t := s.lastSentAt
+		st.LastCommentAt = &t
+	}
+	s.mu.Unlock()
+
+	sort.Slice(st.PausedChats, func(i, j int) bool { return st.PausedChats[i] < st.PausedChats[j] })
+
+	pending, err := s.queue.Len(ctx, s.limiter.session)
+	if err != nil {
+		s.log.Warn("CommentQueue.Len failed", "error", err)
+		return
+	}
+	st.PendingComments = pending
+}
+
+// waitRateLimit ждёт, пока с прошлого комментария пройдёт minInterval, и отмечает отправку.
+// Спим без s.mu, чтобы статус, пауза и приём постов не вставали на всё ожидание.
+func (s *Sender) waitRateLimit(ctx context.Context) error {
+	for {
+		s.mu.Lock()
+		var needWait time.Duration
+		if !s.lastCommentAt.IsZero() {
+			needWait = s.minInterval - time.Since(s.lastCommentAt)
+		}
+		if needWait <= 0 {
+			s.lastCommentAt = time.Now()
+			s.mu.Unlock()
+			return nil
+		}
+		s.mu.Unlock()
+
+		s.log.Info("Rate-limit delay before next comment", "wait", needWait)
+
+		timer := time.NewTimer(needWait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+		// за время сна отправить мог кто-то ещё — перепроверяем под локом
+	}
+}
+
 func (s *Sender) sendOwnerNotify(msg *domain.Message, replyText string) error {
 	if s.ownerUsername == "" {
 		return nil
 	}
 
-	// lazy init → resolve username once
-	if s.ownerUserID == 0 {
-		uid, err := s.tg.ResolveUsername(s.ownerUsername)
-		if err != nil {
-			s.log.Error("Resolve owner username failed", "owner", s.ownerUsername, "error", err)
-			return err
-		}
-		s.ownerUserID = uid
+	ownerID, err := s.resolveOwner()
+	if err != nil {
+		return err
 	}
 
 	toOwner := fmt.Sprintf(
 		"💬 Новый комментарий:\n\n%s\n\nНа сообщение: %s",
 		replyText,
-		msg.Text,
+		repliedText(msg),
 	)
 	chatLink := s.buildChatLink(msg)
 	if chatLink != "" {
 		toOwner = fmt.Sprintf("%s\n\nСсылка: %s", toOwner, chatLink)
 	}
-	err := s.tg.SendMessage(s.ownerUserID, 0, 0, toOwner)
+	err = s.tg.SendMessage(ownerID, 0, 0, toOwner)
 	if err != nil {
 		s.log.Warn("Send Owner Notify", "error", err)
 		return err
@@ -215,8 +681,35 @@ func (s *Sender) sendOwnerNotify(msg *domain.Message, replyText string) error {
 	return nil
 }
 
+// resolveOwner возвращает user id владельца; username резолвится один раз
+func (s *Sender) resolveOwner() (int64, error) {
+	s.mu.Lock()
+	uid := s.ownerUserID
+	s.mu.Unlock()
+	if uid != 0 {
+		return uid, nil
+	}
+
+	uid, err := s.tg.ResolveUsername(s.ownerUsername)
+	if err != nil {
+		s.log.Error("Resolve owner username failed", "owner", s.ownerUsername, "error", err)
+		return 0, err
+	}
+	s.mu.Lock()
+	s.ownerUserID = uid
+	s.mu.Unlock()
+	return uid, nil
+}
+
+// buildChatLink — ссылка на пост, а если адаптер её не дал — на чат обсуждения
 func (s *Sender) buildChatLink(msg *domain.Message) string {
-	if msg == nil || msg.ChatID == 0 {
+	if msg == nil {
+		return ""
+	}
+	if msg.Link != "" {
+		return msg.Link
+	}
+	if msg.ChatID == 0 {
 		return ""
 	}
 
@@ -233,34 +726,40 @@ func (s *Sender) buildChatLink(msg *domain.Message) string {
 	return fmt.Sprintf("https://t.me/c/%d", absID)
 }
 
-func randomDelay(ctx context.Context, min, max time.Duration) error {
+// release снимает отметку с треда; ошибку стора только логируем — хуже, чем раньше, не станет.
+// Контекст свой: SendComment мог завершиться из-за отмены ctx.
+func (s *Sender) release(chatID, threadID int64) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := s.limiter.Release(ctx, chatID, threadID); err != nil {
+		s.log.Warn("CommentLimiter.Release failed, thread stays marked",
+			"chat_id", chatID,
+			"msg_thread_id", threadID,
+			"error", err,
+		)
+	}
+}
 
+// randomDelay выбирает задержку перед отправкой в [min, max)
+func randomDelay(min, max time.Duration) time.Duration {
 	delta := max - min
 	if delta <= 0 {
-		delta = min
-	}
-
-	wait := min + time.Duration(rand.Int63n(int64(delta)))
-
-	timer := time.NewTimer(wait)
-	defer timer.Stop()
-
-	select {
-	case <-ctx.Done():
-		return ctx.Err()
-	case <-timer.C:
-		return nil
+		return min
 	}
+	return min + time.Duration(rand.Int63n(int64(delta)))
 }
 
-func (s *Sender) Allow(chatID, threadID int64) bool {
-	s.mu.Lock()
-	defer s.mu.Unlock()
-
-	key := ThreadKey{ChatID: chatID, ThreadID: threadID}
-	if _, ok := s.limiter.seen[key]; ok {
+// Allow отмечает тред как прокомментированный; false — сессия уже писала в этот тред.
+// При ошибке стора тред не пропускаем, чтобы не задублировать комментарий.
+func (s *Sender) Allow(ctx context.Context, chatID, threadID int64) bool {
+	ok, err := s.limiter.Allow(ctx, chatID, threadID)
+	if err != nil {
+		s.log.Error("CommentLimiter.Allow failed, skipping thread",
+			"chat_id", chatID,
+			"msg_thread_id", threadID,
+			"error", err,
+		)
 		return false
 	}
-	s.limiter.seen[key] = struct{}{}
-	return true
+	return ok
 }
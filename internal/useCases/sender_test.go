@@ -0,0 +1,574 @@
+package useCases
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/larriantoniy/tg_user_bot/internal/adapters/metrics"
+	"github.com/larriantoniy/tg_user_bot/internal/adapters/store"
+	"github.com/larriantoniy/tg_user_bot/internal/adapters/tgfake"
+	"github.com/larriantoniy/tg_user_bot/internal/domain"
+	"github.com/larriantoniy/tg_user_bot/internal/ports"
+)
+
+const (
+	testSession        = "test-session"
+	testChatID   int64 = -1001234567890
+	testThreadID int64 = 77
+	testReplyTo  int64 = 78
+	testOwnerID  int64 = 42
+)
+
+type stubNeuro struct {
+	text   string
+	texts  []string // ответы по очереди, потом text
+	err    error
+	calls  int
+	photos []string // картинки последнего запроса
+}
+
+func (n *stubNeuro) GetComment(ctx context.Context, msg *domain.Message) (string, error) {
+	n.calls++
+	n.photos = msg.PhotoFiles
+	if len(n.texts) > 0 {
+		text := n.texts[0]
+		n.texts = n.texts[1:]
+		return text, n.err
+	}
+	return n.text, n.err
+}
+
+func newTestSender(cli ports.TelegramClient, n ports.NeuroProccesor, owner string) *Sender {
+	return newTestSenderWithStore(cli, n, owner, store.NewMemoryStore(time.Hour))
+}
+
+func newTestSenderWithStore(cli ports.TelegramClient, n ports.NeuroProccesor, owner string, st ports.CommentStore) *Sender {
+	limiter := NewCommentLimiter(testSession, st)
+	s := NewSender(discardLogger(), cli, n, limiter, store.NewMemoryQueue(), metrics.Nop{}, owner)
+	s.minDelay = 0
+	s.maxDelay = 0
+	s.minInterval = 0
+	return s
+}
+
+func discardLogger() *slog.Logger {
+	return slog.New(slog.NewTextHandler(io.Discard, nil))
+}
+
+// sendAndDeliver планирует комментарий и сразу отправляет всё, что попало в очередь
+func sendAndDeliver(s *Sender, msg *domain.Message) error {
+	ctx := context.Background()
+	if err := s.SendComment(ctx, msg); err != nil {
+		return err
+	}
+	jobs, err := s.queue.Due(ctx, testSession, time.Now(), 0)
+	if err != nil {
+		return err
+	}
+	for _, job := range jobs {
+		if err := s.Publish(ctx, job); err != nil {
+			return err
+		}
+		_ = s.queue.Remove(ctx, job)
+	}
+	return nil
+}
+
+func testMessage() *domain.Message {
+	return &domain.Message{
+		ChannelID:        -1009999,
+		ChatID:           testChatID,
+		ChatName:         "Test channel",
+		Text:             "Пост про рынок",
+		MessageThreadId:  testThreadID,
+		ReplyToMessageID: testReplyTo,
+	}
+}
+
+func TestSenderSendComment(t *testing.T) {
+	neuroErr := errors.New("neuro down")
+	circuitErr := &ports.NeuroError{Kind: ports.ErrNeuroCircuitOpen, Err: errors.New("circuit open")}
+
+	tests := []struct {
+		name      string
+		setup     func(c *tgfake.Client)
+		neuroText string
+		neuroErr  error
+		owner     string
+		wantErr   error
+		wantNeuro int
+		wantSent  []tgfake.SentMessage
+	}{
+		{
+			name:      "comment sent and owner notified",
+			neuroText: "  Отличный разбор 👍  ",
+			owner:     "@owner",
+			wantNeuro: 1,
+			wantSent: []tgfake.SentMessage{
+				{ChatID: testChatID, ThreadID: testThreadID, ReplyToMessageID: testReplyTo, Text: "Отличный разбор 👍"},
+				{ChatID: testOwnerID},
+			},
+		},
+		{
+			name:      "comment sent without owner",
+			neuroText: "Отличный разбор 👍",
+			wantNeuro: 1,
+			wantSent: []tgfake.SentMessage{
+				{ChatID: testChatID, ThreadID: testThreadID, ReplyToMessageID: testReplyTo, Text: "Отличный разбор 👍"},
+			},
+		},
+		{
+			name:      "owner resolve failure does not fail comment",
+			neuroText: "Отличный разбор 👍",
+			owner:     "@unknown",
+			wantNeuro: 1,
+			wantSent: []tgfake.SentMessage{
+				{ChatID: testChatID, ThreadID: testThreadID, ReplyToMessageID: testReplyTo, Text: "Отличный разбор 👍"},
+			},
+		},
+		{
+			name:      "cannot send to chat",
+			setup:     func(c *tgfake.Client) { c.SetCanSend(testChatID, false) },
+			neuroText: "Отличный разбор 👍",
+		},
+		{
+			name:      "not a member",
+			setup:     func(c *tgfake.Client) { c.SetMember(testChatID, false) },
+			neuroText: "Отличный разбор 👍",
+		},
+		{
+			name:      "empty LLM response",
+			neuroText: "   ",
+			wantNeuro: 1,
+		},
+		{
+			name:      "empty response from provider",
+			neuroErr:  fmt.Errorf("openai: request failed: empty choices: %w", ports.ErrNeuroEmpty),
+			wantNeuro: 1,
+		},
+		{
+			name:      "neuro error",
+			neuroErr:  neuroErr,
+			wantErr:   neuroErr,
+			wantNeuro: 1,
+		},
+		{
+			name:      "neuro circuit open",
+			neuroErr:  circuitErr,
+			wantErr:   ports.ErrNeuroCircuitOpen,
+			wantNeuro: 1,
+		},
+		{
+			name:      "rate limited on send",
+			setup:     func(c *tgfake.Client) { c.FailNextSend(ports.ErrRateLimited) },
+			neuroText: "Отличный разбор 👍",
+			wantErr:   ports.ErrRateLimited,
+			wantNeuro: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			cli := tgfake.New(1, 0)
+			cli.AddUsername("@owner", testOwnerID)
+			if tt.setup != nil {
+				tt.setup(cli)
+			}
+			n := &stubNeuro{text: tt.neuroText, err: tt.neuroErr}
+			s := newTestSender(cli, n, tt.owner)
+
+			err := sendAndDeliver(s, testMessage())
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("SendComment() error = %v, want %v", err, tt.wantErr)
+			}
+			if n.calls != tt.wantNeuro {
+				t.Errorf("GetComment calls = %d, want %d", n.calls, tt.wantNeuro)
+			}
+
+			sent := cli.Sent()
+			if len(sent) != len(tt.wantSent) {
+				t.Fatalf("sent %d messages, want %d: %+v", len(sent), len(tt.wantSent), sent)
+			}
+			for i, want := range tt.wantSent {
+				got := sent[i]
+				if got.ChatID != want.ChatID || got.ThreadID != want.ThreadID || got.ReplyToMessageID != want.ReplyToMessageID {
+					t.Errorf("sent[%d] = %+v, want %+v", i, got, want)
+				}
+				if want.Text != "" && got.Text != want.Text {
+					t.Errorf("sent[%d].Text = %q, want %q", i, got.Text, want.Text)
+				}
+			}
+		})
+	}
+}
+
+func TestSenderSkipsAlreadySeenThread(t *testing.T) {
+	cli := tgfake.New(1, 0)
+	n := &stubNeuro{text: "Отличный разбор 👍"}
+	s := newTestSender(cli, n, "")
+
+	if err := sendAndDeliver(s, testMessage()); err != nil {
+		t.Fatalf("first SendComment() error = %v", err)
+	}
+	if err := sendAndDeliver(s, testMessage()); err == nil {
+		t.Fatal("second SendComment() on the same thread: want error, got nil")
+	}
+	if n.calls != 1 {
+		t.Errorf("GetComment calls = %d, want 1", n.calls)
+	}
+	if got := len(cli.Sent()); got != 1 {
+		t.Errorf("sent %d messages, want 1", got)
+	}
+}
+
+func TestSenderSeenThreadsSharedThroughStore(t *testing.T) {
+	st := store.NewMemoryStore(time.Hour)
+	n := &stubNeuro{text: "Отличный разбор 👍"}
+
+	first := newTestSenderWithStore(tgfake.New(1, 0), n, "", st)
+	if err := sendAndDeliver(first, testMessage()); err != nil {
+		t.Fatalf("SendComment() error = %v", err)
+	}
+
+	// новый Sender той же сессии (как после рестарта) не должен писать в тот же тред
+	cli := tgfake.New(1, 0)
+	restarted := newTestSenderWithStore(cli, n, "", st)
+	if err := sendAndDeliver(restarted, testMessage()); err == nil {
+		t.Fatal("SendComment() after restart on the same thread: want error, got nil")
+	}
+	if got := len(cli.Sent()); got != 0 {
+		t.Errorf("sent %d messages after restart, want 0", got)
+	}
+
+	history, err := st.RecentSent(context.Background(), testSession, 10)
+	if err != nil {
+		t.Fatalf("RecentSent() error = %v", err)
+	}
+	if len(history) != 1 || history[0].Text != "Отличный разбор 👍" || history[0].ThreadID != testThreadID {
+		t.Errorf("history = %+v, want one sent comment", history)
+	}
+}
+
+func TestSenderSendCommentOnlyPlans(t *testing.T) {
+	cli := tgfake.New(1, 0)
+	s := newTestSender(cli, &stubNeuro{text: "Отличный разбор 👍"}, "")
+	s.minDelay = time.Hour
+	s.maxDelay = 2 * time.Hour
+
+	if err := s.SendComment(context.Background(), testMessage()); err != nil {
+		t.Fatalf("SendComment() error = %v", err)
+	}
+	if got := len(cli.Sent()); got != 0 {
+		t.Errorf("sent %d messages right away, want 0", got)
+	}
+
+	due, _ := s.queue.Due(context.Background(), testSession, time.Now().Add(3*time.Hour), 0)
+	if len(due) != 1 {
+		t.Fatalf("queued jobs = %d, want 1", len(due))
+	}
+	job := due[0]
+	if job.Text != "Отличный разбор 👍" || job.Post.MessageThreadId != testThreadID || job.Session != testSession {
+		t.Errorf("job = %+v", job)
+	}
+	if wait := time.Until(job.DueAt); wait < 59*time.Minute || wait > 2*time.Hour {
+		t.Errorf("job due in %v, want within [1h, 2h)", wait)
+	}
+}
+
+func TestSenderWaitsFloodWaitAndResumes(t *testing.T) {
+	cli := tgfake.New(1, 0)
+	cli.FailNextSend(&ports.RateLimitError{RetryAfter: 50 * time.Millisecond})
+	n := &stubNeuro{text: "Отличный разбор 👍"}
+	s := newTestSender(cli, n, "")
+
+	if err := sendAndDeliver(s, testMessage()); !errors.Is(err, ports.ErrRateLimited) {
+		t.Fatalf("first SendComment() error = %v, want ErrRateLimited", err)
+	}
+
+	st := domain.SessionStatus{State: domain.SessionAuthorized}
+	s.fillStatus(context.Background(), &st)
+	if st.State != domain.SessionRateLimited || st.RateLimitedUntil == nil {
+		t.Errorf("status during FLOOD_WAIT = %+v, want rate_limited with deadline", st)
+	}
+
+	next := testMessage()
+	next.MessageThreadId++
+	if err := sendAndDeliver(s, next); !errors.Is(err, ports.ErrRateLimited) {
+		t.Fatalf("SendComment() during FLOOD_WAIT error = %v, want ErrRateLimited", err)
+	}
+	if n.calls != 1 {
+		t.Errorf("GetComment calls = %d, want 1 (limited session must not call LLM)", n.calls)
+	}
+
+	time.Sleep(60 * time.Millisecond)
+
+	// тред, пропущенный во время FLOOD_WAIT, не должен считаться прокомментированным
+	if err := sendAndDeliver(s, next); err != nil {
+		t.Fatalf("SendComment() after FLOOD_WAIT error = %v", err)
+	}
+	// отложенный комментарий первого треда тоже уходит после паузы
+	if got := len(cli.Sent()); got != 2 {
+		t.Errorf("sent %d messages, want 2", got)
+	}
+
+	st = domain.SessionStatus{State: domain.SessionAuthorized}
+	s.fillStatus(context.Background(), &st)
+	if st.State != domain.SessionAuthorized || st.RateLimitedUntil != nil {
+		t.Errorf("status after FLOOD_WAIT = %+v, want authorized", st)
+	}
+}
+
+func TestSenderRateLimitWaitDoesNotBlockStatus(t *testing.T) {
+	s := newTestSender(tgfake.New(1, 0), &stubNeuro{}, "")
+	s.minInterval = time.Hour
+	s.lastCommentAt = time.Now()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	waitErr := make(chan error, 1)
+	go func() { waitErr <- s.waitRateLimit(ctx) }()
+
+	statusDone := make(chan struct{})
+	go func() {
+		st := domain.SessionStatus{State: domain.SessionAuthorized}
+		s.fillStatus(context.Background(), &st)
+		s.SetPaused(true)
+		close(statusDone)
+	}()
+	select {
+	case <-statusDone:
+	case <-time.After(time.Second):
+		t.Fatal("fillStatus/SetPaused blocked while waiting for rate limit")
+	}
+
+	cancel()
+	if err := <-waitErr; !errors.Is(err, context.Canceled) {
+		t.Errorf("waitRateLimit() error = %v, want context.Canceled", err)
+	}
+}
+
+func TestSenderPausesOnNeuroQuota(t *testing.T) {
+	cli := tgfake.New(1, 0)
+	n := &stubNeuro{err: &ports.NeuroError{
+		Status:     http.StatusPaymentRequired,
+		RetryAfter: 50 * time.Millisecond,
+		Kind:       ports.ErrNeuroQuota,
+		Err:        errors.New("status 402"),
+	}}
+	s := newTestSender(cli, n, "")
+
+	if err := sendAndDeliver(s, testMessage()); !errors.Is(err, ports.ErrNeuroQuota) {
+		t.Fatalf("first SendComment() error = %v, want ErrNeuroQuota", err)
+	}
+	st := domain.SessionStatus{State: domain.SessionAuthorized}
+	s.fillStatus(context.Background(), &st)
+	if st.NeuroQuotaUntil == nil {
+		t.Errorf("status during quota pause = %+v, want quota deadline", st)
+	}
+
+	next := testMessage()
+	next.MessageThreadId++
+	if err := sendAndDeliver(s, next); err != nil {
+		t.Fatalf("SendComment() during quota pause error = %v, want silent skip", err)
+	}
+	if n.calls != 1 {
+		t.Errorf("GetComment calls = %d, want 1 (no LLM calls while quota is exhausted)", n.calls)
+	}
+
+	time.Sleep(60 * time.Millisecond)
+	n.err = nil
+	n.text = "Отличный разбор 👍"
+
+	// тред, пропущенный из-за квоты, не считается прокомментированным
+	if err := sendAndDeliver(s, next); err != nil {
+		t.Fatalf("SendComment() after quota pause error = %v", err)
+	}
+	if got := len(cli.Sent()); got != 1 {
+		t.Errorf("sent %d messages, want 1", got)
+	}
+}
+
+func TestSenderTransientNeuroErrorDoesNotPause(t *testing.T) {
+	cli := tgfake.New(1, 0)
+	n := &stubNeuro{err: &ports.NeuroError{Status: http.StatusBadGateway, Kind: ports.ErrNeuroTransient, Err: errors.New("status 502")}}
+	s := newTestSender(cli, n, "")
+
+	if err := sendAndDeliver(s, testMessage()); !errors.Is(err, ports.ErrNeuroTransient) {
+		t.Fatalf("SendComment() error = %v, want ErrNeuroTransient", err)
+	}
+	next := testMessage()
+	next.MessageThreadId++
+	_ = sendAndDeliver(s, next)
+	if n.calls != 2 {
+		t.Errorf("GetComment calls = %d, want 2 (transient failure must not pause LLM)", n.calls)
+	}
+}
+
+func TestSenderNeuroFailureReleasesThread(t *testing.T) {
+	cli := tgfake.New(1, 0)
+	n := &stubNeuro{err: &ports.NeuroError{Status: http.StatusBadGateway, Kind: ports.ErrNeuroTransient, Err: errors.New("status 502")}}
+	s := newTestSender(cli, n, "")
+
+	if err := sendAndDeliver(s, testMessage()); !errors.Is(err, ports.ErrNeuroTransient) {
+		t.Fatalf("SendComment() error = %v, want ErrNeuroTransient", err)
+	}
+	// нейросеть ожила — тот же пост комментируется
+	n.err, n.text = nil, "Отличный разбор 👍"
+	if err := sendAndDeliver(s, testMessage()); err != nil {
+		t.Fatalf("SendComment() after recovery error = %v", err)
+	}
+	if got := len(cli.SentTo(testChatID)); got != 1 {
+		t.Errorf("sent %d comments, want 1", got)
+	}
+	// а запланированный тред остаётся отмеченным
+	if s.Allow(context.Background(), testChatID, testThreadID) {
+		t.Error("thread with a scheduled comment is not marked")
+	}
+}
+
+func TestSenderDownloadsPhotosOnlyForCommentedPosts(t *testing.T) {
+	cli := tgfake.New(1, 0)
+	n := &stubNeuro{text: "Отличный разбор 👍"}
+	s := newTestSender(cli, n, "")
+	f, err := NewPostFilter(DefaultFilterRules, nil)
+	if err != nil {
+		t.Fatalf("NewPostFilter() error = %v", err)
+	}
+	s.SetPostFilter(f)
+
+	ad := testMessage()
+	ad.Text = "Розыгрыш iPhone среди подписчиков!"
+	ad.PhotoFileIDs = []int32{1}
+	if err := sendAndDeliver(s, ad); err != nil {
+		t.Fatalf("SendComment() error = %v", err)
+	}
+	if got := cli.Downloads(); len(got) != 0 {
+		t.Errorf("downloads for filtered post = %v, want none", got)
+	}
+
+	post := testMessage()
+	post.MessageThreadId++
+	post.Text = "Итоги дня" // короткая подпись к альбому не мешает: картинки ещё не скачаны, но ContentType известен
+	post.ContentType = domain.ContentAlbum
+	post.PhotoFileIDs = []int32{2, 3}
+	if err := sendAndDeliver(s, post); err != nil {
+		t.Fatalf("SendComment() error = %v", err)
+	}
+	if got := cli.Downloads(); len(got) != 2 {
+		t.Errorf("downloads = %v, want both album photos", got)
+	}
+	if len(n.photos) != 2 {
+		t.Errorf("LLM got photos %v, want 2", n.photos)
+	}
+}
+
+func TestSenderOwnerNotifyContainsLink(t *testing.T) {
+	withPostLink := testMessage()
+	withPostLink.Link = "https://t.me/testchannel/42"
+	tests := []struct {
+		name     string
+		msg      *domain.Message
+		wantLink string
+	}{
+		{name: "discussion chat", msg: testMessage(), wantLink: "https://t.me/c/1234567890"},
+		{name: "post link from adapter", msg: withPostLink, wantLink: "https://t.me/testchannel/42"},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			cli := tgfake.New(1, 0)
+			cli.AddUsername("owner", testOwnerID)
+			s := newTestSender(cli, &stubNeuro{text: "Отличный разбор 👍"}, "@owner")
+
+			if err := sendAndDeliver(s, tt.msg); err != nil {
+				t.Fatalf("SendComment() error = %v", err)
+			}
+
+			notes := cli.SentTo(testOwnerID)
+			if len(notes) != 1 {
+				t.Fatalf("owner notifications = %d, want 1", len(notes))
+			}
+			for _, want := range []string{"Отличный разбор 👍", "Пост про рынок", tt.wantLink} {
+				if !strings.Contains(notes[0].Text, want) {
+					t.Errorf("owner notification %q does not contain %q", notes[0].Text, want)
+				}
+			}
+		})
+	}
+}
+
+// stubModerator помечает тексты, содержащие слово из bad
+type stubModerator struct {
+	bad []string
+}
+
+func (m *stubModerator) Moderate(ctx context.Context, target domain.ModerationTarget, text string) (domain.ModerationVerdict, error) {
+	for _, w := range m.bad {
+		if strings.Contains(text, w) {
+			return domain.ModerationVerdict{Flagged: true, Category: "test", Rule: w, Source: "rules"}, nil
+		}
+	}
+	return domain.ModerationVerdict{}, nil
+}
+
+func TestSenderModeration(t *testing.T) {
+	tests := []struct {
+		name      string
+		post      string
+		texts     []string
+		wantNeuro int
+		wantSent  string
+	}{
+		{
+			name:      "sensitive post is not commented",
+			post:      "Трагедия на производстве",
+			wantNeuro: 0,
+		},
+		{
+			name:      "unsafe reply is regenerated",
+			post:      "Пост про рынок",
+			texts:     []string{"Грубость 👍", "Отличный разбор 👍"},
+			wantNeuro: 2,
+			wantSent:  "Отличный разбор 👍",
+		},
+		{
+			name:      "unsafe reply dropped",
+			post:      "Пост про рынок",
+			texts:     []string{"Грубость 👍", "Грубость 👍", "Грубость 👍"},
+			wantNeuro: 3,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			cli := tgfake.New(1, 0)
+			n := &stubNeuro{texts: tt.texts, text: "Отличный разбор 👍"}
+			s := newTestSender(cli, n, "")
+			s.SetValidator(NewCommentValidator(DefaultValidationRules))
+			s.SetModerator(&stubModerator{bad: []string{"Трагедия", "Грубость"}})
+
+			msg := testMessage()
+			msg.Text = tt.post
+			if err := sendAndDeliver(s, msg); err != nil {
+				t.Fatalf("SendComment() error = %v", err)
+			}
+			if n.calls != tt.wantNeuro {
+				t.Errorf("GetComment calls = %d, want %d", n.calls, tt.wantNeuro)
+			}
+			sent := cli.SentTo(testChatID)
+			switch {
+			case tt.wantSent == "" && len(sent) != 0:
+				t.Errorf("sent %+v, want nothing", sent)
+			case tt.wantSent != "" && (len(sent) != 1 || sent[0].Text != tt.wantSent):
+				t.Errorf("sent %+v, want %q", sent, tt.wantSent)
+			}
+		})
+	}
+}
@@ -0,0 +1,103 @@
+package useCases
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/larriantoniy/tg_user_bot/internal/domain"
+	"github.com/larriantoniy/tg_user_bot/internal/ports"
+)
+
+// usageTop — сколько каналов и моделей показываем владельцу в /usage
+const usageTop = 10
+
+// SetUsage подключает отчёт о расходах для команды владельца /usage
+func (s *Sender) SetUsage(r ports.UsageReporter) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.usage = r
+}
+
+func (s *Sender) usageReporter() ports.UsageReporter {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.usage
+}
+
+// replyUsage отвечает владельцу сводкой расходов сессии за месяц и общим итогом
+func (s *Sender) replyUsage(ctx context.Context, r ports.UsageReporter) error {
+	session := s.limiter.session
+	own, err := r.UsageReport(ctx, session)
+	if err != nil {
+		s.log.Error("UsageReport", "error", err)
+		return s.replyOwner(fmt.Sprintf("Не удалось посчитать расход: %v", err))
+	}
+	all, err := r.UsageReport(ctx, "")
+	if err != nil {
+		s.log.Error("UsageReport", "error", err)
+		return s.replyOwner(fmt.Sprintf("Не удалось посчитать расход: %v", err))
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "💰 Расход LLM сессии %s, %s\n\n", session, own.Currency)
+	fmt.Fprintf(&b, "Сегодня: %s\n", formatTotals(own.Today))
+	fmt.Fprintf(&b, "С %s: %s\n", own.Since.Format("02.01"), formatTotals(own.Month))
+	if limit := formatBudget(own.Budget); limit != "" {
+		fmt.Fprintf(&b, "Лимит сессии: %s\n", limit)
+	}
+	fmt.Fprintf(&b, "Все сессии за месяц: %s\n", formatTotals(all.Month))
+	if limit := formatBudget(all.Budget); limit != "" {
+		fmt.Fprintf(&b, "Общий лимит: %s\n", limit)
+	}
+	if until, paused := s.neuroQuotaUntil(); paused {
+		fmt.Fprintf(&b, "\n⏸ Нейросеть на паузе до %s (%s)\n", until.Format("02.01 15:04"), s.neuroQuotaReason())
+	}
+	writeUsageTop(&b, "Каналы", own.ByChannel)
+	writeUsageTop(&b, "Модели", own.ByModel)
+	return s.replyOwner(strings.TrimRight(b.String(), "\n"))
+}
+
+func formatTotals(t domain.UsageTotals) string {
+	return fmt.Sprintf("%.4f · %d запросов · %d токенов", t.Cost, t.Requests, t.PromptTokens+t.CompletionTokens)
+}
+
+func formatBudget(b domain.UsageBudget) string {
+	var parts []string
+	if b.Daily > 0 {
+		parts = append(parts, fmt.Sprintf("день %.2f", b.Daily))
+	}
+	if b.Monthly > 0 {
+		parts = append(parts, fmt.Sprintf("месяц %.2f", b.Monthly))
+	}
+	return strings.Join(parts, " · ")
+}
+
+// writeUsageTop печатает самые дорогие (при равной цене — самые частые) строки разбивки
+func writeUsageTop(b *strings.Builder, title string, m map[string]domain.UsageTotals) {
+	if len(m) == 0 {
+		return
+	}
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		a, c := m[keys[i]], m[keys[j]]
+		if a.Cost != c.Cost {
+			return a.Cost > c.Cost
+		}
+		if a.Requests != c.Requests {
+			return a.Requests > c.Requests
+		}
+		return keys[i] < keys[j]
+	})
+	if len(keys) > usageTop {
+		keys = keys[:usageTop]
+	}
+	fmt.Fprintf(b, "\n%s за месяц:\n", title)
+	for _, k := range keys {
+		fmt.Fprintf(b, "• %s — %.4f (%d)\n", k, m[k].Cost, m[k].Requests)
+	}
+}
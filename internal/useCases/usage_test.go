@@ -0,0 +1,87 @@
+package useCases
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/larriantoniy/tg_user_bot/internal/adapters/tgfake"
+	"github.com/larriantoniy/tg_user_bot/internal/domain"
+	"github.com/larriantoniy/tg_user_bot/internal/ports"
+)
+
+type stubUsage struct {
+	reports map[string]domain.UsageReport
+}
+
+func (u *stubUsage) UsageReport(ctx context.Context, session string) (domain.UsageReport, error) {
+	return u.reports[session], nil
+}
+
+func TestOwnerUsageCommand(t *testing.T) {
+	cli := tgfake.New(1, 0)
+	cli.AddUsername("@owner", testOwnerID)
+	s := newTestSender(cli, &stubNeuro{}, "@owner")
+	s.SetUsage(&stubUsage{reports: map[string]domain.UsageReport{
+		testSession: {
+			Currency: "USD",
+			Today:    domain.UsageTotals{Requests: 2, PromptTokens: 150, CompletionTokens: 30, Cost: 0.0123},
+			Month:    domain.UsageTotals{Requests: 40, Cost: 0.5},
+			Budget:   domain.UsageBudget{Daily: 1},
+			ByChannel: map[string]domain.UsageTotals{
+				"Рынки":  {Requests: 30, Cost: 0.4},
+				"Крипта": {Requests: 10, Cost: 0.1},
+			},
+		},
+		"": {Month: domain.UsageTotals{Requests: 90, Cost: 1.75}, Budget: domain.UsageBudget{Monthly: 20}},
+	}})
+
+	// режим одобрения выключен — /usage всё равно работает
+	if err := s.HandleOwnerMessage(context.Background(), ownerCommand("/usage")); err != nil {
+		t.Fatalf("HandleOwnerMessage() error = %v", err)
+	}
+	got := lastOwnerMessage(t, cli)
+	for _, want := range []string{testSession, "0.0123 · 2 запросов · 180 токенов", "день 1.00", "1.7500 · 90 запросов", "месяц 20.00", "• Рынки — 0.4000 (30)"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("usage reply %q does not contain %q", got, want)
+		}
+	}
+	if strings.Index(got, "Рынки") > strings.Index(got, "Крипта") {
+		t.Errorf("channels are not sorted by cost: %q", got)
+	}
+
+	// чужие сообщения игнорируются
+	stranger := ownerCommand("/usage")
+	stranger.SenderID = testOwnerID + 1
+	_ = s.HandleOwnerMessage(context.Background(), stranger)
+	if n := len(cli.SentTo(testOwnerID)); n != 1 {
+		t.Errorf("owner messages = %d, want 1", n)
+	}
+}
+
+func TestSenderPausesOnNeuroBudget(t *testing.T) {
+	cli := tgfake.New(1, 0)
+	n := &stubNeuro{err: &ports.NeuroError{
+		RetryAfter: time.Hour,
+		Kind:       ports.ErrNeuroBudget,
+		Err:        errors.New("global daily budget spent"),
+	}}
+	s := newTestSender(cli, n, "")
+
+	if err := sendAndDeliver(s, testMessage()); !errors.Is(err, ports.ErrNeuroBudget) {
+		t.Fatalf("SendComment() error = %v, want ErrNeuroBudget", err)
+	}
+	next := testMessage()
+	next.MessageThreadId++
+	if err := sendAndDeliver(s, next); err != nil {
+		t.Fatalf("SendComment() over budget error = %v, want silent skip", err)
+	}
+	if n.calls != 1 {
+		t.Errorf("GetComment calls = %d, want 1 (no LLM calls over budget)", n.calls)
+	}
+	if got := s.neuroQuotaReason(); got != domain.SkipNeuroBudget {
+		t.Errorf("pause reason = %s, want %s", got, domain.SkipNeuroBudget)
+	}
+}
@@ -0,0 +1,170 @@
+package useCases
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// ValidationRules — что проверяется в ответе нейросети перед отправкой.
+// Нулевые MaxWords/MaxChars/EchoWords — без проверки.
+type ValidationRules struct {
+	MaxWords       int // слов (эмодзи и знаки препинания не считаются)
+	MaxChars       int // символов (рун)
+	MinEmoji       int
+	MaxEmoji       int // <0 — без ограничения
+	AllowLinks     bool
+	AllowMentions  bool
+	AllowQuestions bool
+	EchoWords      int      // столько слов подряд из поста — уже цитата
+	BannedPhrases  []string // без учёта регистра: отказы модели и обрывки инструкций
+	Regenerate     int      // сколько раз перегенерировать, прежде чем выбросить комментарий
+}
+
+// DefaultValidationRules повторяют встроенный промпт: до 12 слов, без вопросов, ровно одно эмодзи
+var DefaultValidationRules = ValidationRules{
+	MaxWords:  12,
+	MaxChars:  200,
+	MinEmoji:  1,
+	MaxEmoji:  1,
+	EchoWords: 5,
+	BannedPhrases: []string{
+		"as an ai", "language model", "i'm sorry", "i cannot",
+		"как ии", "как искусственный интеллект", "языковая модель", "я не могу", "к сожалению, я",
+		"<post>", "</post>", "до 12 слов", "ровно одно эмодзи",
+	},
+	Regenerate: 2,
+}
+
+var (
+	linkRe    = regexp.MustCompile(`(?i)(https?://|www\.|t\.me/|\b[a-z0-9-]+\.(com|ru|org|net|io|me|su)\b)`)
+	mentionRe = regexp.MustCompile(`(^|[^\w@])@[A-Za-z0-9_]{3,}`)
+)
+
+// CommentValidator проверяет текст комментария по ValidationRules
+type CommentValidator struct {
+	rules  ValidationRules
+	banned []string // в нижнем регистре
+}
+
+func NewCommentValidator(rules ValidationRules) *CommentValidator {
+	banned := make([]string, 0, len(rules.BannedPhrases))
+	for _, p := range rules.BannedPhrases {
+		if p = strings.ToLower(strings.TrimSpace(p)); p != "" {
+			banned = append(banned, p)
+		}
+	}
+	return &CommentValidator{rules: rules, banned: banned}
+}
+
+// Validate возвращает нарушенные правила; пусто — текст годится.
+// post — текст поста, чтобы поймать его пересказ дословно.
+func (v *CommentValidator) Validate(text, post string) []string {
+	r := v.rules
+	var bad []string
+	if n := countWords(text); r.MaxWords > 0 && n > r.MaxWords {
+		bad = append(bad, fmt.Sprintf("words %d > %d", n, r.MaxWords))
+	}
+	if n := utf8.RuneCountInString(text); r.MaxChars > 0 && n > r.MaxChars {
+		bad = append(bad, fmt.Sprintf("chars %d > %d", n, r.MaxChars))
+	}
+	if n := countEmoji(text); n < r.MinEmoji || (r.MaxEmoji >= 0 && n > r.MaxEmoji) {
+		bad = append(bad, fmt.Sprintf("emoji %d", n))
+	}
+	if !r.AllowLinks && linkRe.MatchString(text) {
+		bad = append(bad, "link")
+	}
+	if !r.AllowMentions && mentionRe.MatchString(text) {
+		bad = append(bad, "mention")
+	}
+	if !r.AllowQuestions && strings.ContainsAny(text, "?？¿") {
+		bad = append(bad, "question")
+	}
+	lower := strings.ToLower(text)
+	for _, p := range v.banned {
+		if strings.Contains(lower, p) {
+			bad = append(bad, fmt.Sprintf("phrase %q", p))
+			break
+		}
+	}
+	if r.EchoWords > 0 && echoes(text, post, r.EchoWords) {
+		bad = append(bad, "quotes post")
+	}
+	return bad
+}
+
+// countWords считает слова: токены, где есть буква или цифра
+func countWords(text string) int {
+	return len(words(text))
+}
+
+func words(text string) []string {
+	var out []string
+	for _, f := range strings.Fields(text) {
+		w := strings.ToLower(strings.TrimFunc(f, func(r rune) bool { return !unicode.IsLetter(r) && !unicode.IsDigit(r) }))
+		if w != "" {
+			out = append(out, w)
+		}
+	}
+	return out
+}
+
+// echoes — в text есть n слов подряд из post
+func echoes(text, post string, n int) bool {
+	pw := words(post)
+	if len(pw) < n {
+		return false
+	}
+	shingles := make(map[string]struct{}, len(pw))
+	for i := 0; i+n <= len(pw); i++ {
+		shingles[strings.Join(pw[i:i+n], " ")] = struct{}{}
+	}
+	tw := words(text)
+	for i := 0; i+n <= len(tw); i++ {
+		if _, ok := shingles[strings.Join(tw[i:i+n], " ")]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// countEmoji считает видимые эмодзи: ZWJ-последовательность, эмодзи с модификатором
+// кожи и флаг из двух региональных символов считаются за одно
+func countEmoji(text string) int {
+	n := 0
+	joined := false   // предыдущая руна — ZWJ
+	regional := false // ждём вторую половину флага
+	for _, r := range text {
+		switch {
+		case r == 0x200D:
+			joined = true
+			continue
+		case r == 0xFE0F || (r >= 0x1F3FB && r <= 0x1F3FF):
+			// вариационный селектор и оттенок кожи — часть предыдущего эмодзи
+			continue
+		case r >= 0x1F1E6 && r <= 0x1F1FF:
+			if !regional {
+				n++
+			}
+			regional = !regional
+		case isEmoji(r):
+			if !joined {
+				n++
+			}
+			regional = false
+		default:
+			regional = false
+		}
+		joined = false
+	}
+	return n
+}
+
+func isEmoji(r rune) bool {
+	return (r >= 0x1F300 && r <= 0x1FAFF) || // пиктограммы, смайлы, транспорт, дополнительные символы
+		(r >= 0x2600 && r <= 0x27BF) || // разные символы и dingbats
+		(r >= 0x2B00 && r <= 0x2BFF) || // стрелки и звёзды ⭐
+		(r >= 0x1F000 && r <= 0x1F2FF) // карты, маджонг, буквы в квадратах
+}
@@ -0,0 +1,115 @@
+package useCases
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/larriantoniy/tg_user_bot/internal/adapters/tgfake"
+)
+
+func TestCommentValidator(t *testing.T) {
+	v := NewCommentValidator(DefaultValidationRules)
+	post := "Центробанк сохранил ключевую ставку на уровне шестнадцати процентов"
+
+	tests := []struct {
+		text string
+		want string // подстрока нарушения; "" — текст годится
+	}{
+		{"Отличный разбор, согласен полностью 👍", ""},
+		{"Сильный ход регулятора 👨‍👩‍👧", ""}, // ZWJ-семья — одно эмодзи
+		{"Рынок оценит 🇷🇺", ""},              // флаг — одно эмодзи
+		{"Хорошо 👍🏽", ""},                    // оттенок кожи не отдельное эмодзи
+		{"Отличный разбор без эмодзи", "emoji 0"},
+		{"Отличный разбор 👍🔥", "emoji 2"},
+		{"Раз два три четыре пять шесть семь восемь девять десять одиннадцать двенадцать тринадцать 👍", "words 13 > 12"},
+		{"А что дальше? 👍", "question"},
+		{"Подробнее на example.com 👍", "link"},
+		{"Читайте t.me/channel 👍", "link"},
+		{"Согласен с @someone 👍", "mention"},
+		{"Пишите на mail@example 👍", ""},
+		{"Как языковая модель, я согласен 👍", "phrase"},
+		{"<post> Отличный разбор 👍", "phrase"},
+		{"Сильный отчёт, рост без вопросов 👍", ""}, // обычная фраза, а не утечка промпта
+		{"Сохранил ключевую ставку на уровне 👍", "quotes post"},
+	}
+	for _, tt := range tests {
+		bad := v.Validate(tt.text, post)
+		got := strings.Join(bad, "; ")
+		if tt.want == "" && len(bad) != 0 {
+			t.Errorf("Validate(%q) = %q, want valid", tt.text, got)
+		}
+		if tt.want != "" && !strings.Contains(got, tt.want) {
+			t.Errorf("Validate(%q) = %q, want %q", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestSenderRegeneratesInvalidComment(t *testing.T) {
+	tests := []struct {
+		name      string
+		texts     []string
+		wantNeuro int
+		wantSent  string
+	}{
+		{
+			name:      "second attempt passes",
+			texts:     []string{"Интересно, а что дальше?", "Отличный разбор 👍"},
+			wantNeuro: 2,
+			wantSent:  "Отличный разбор 👍",
+		},
+		{
+			name:      "dropped after regenerations",
+			texts:     []string{"Что дальше?", "Как ИИ, не могу 👍", "Без эмодзи"},
+			wantNeuro: 3,
+		},
+		{
+			name:      "empty response is not regenerated",
+			texts:     []string{"  "},
+			wantNeuro: 1,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			cli := tgfake.New(1, 0)
+			n := &stubNeuro{texts: tt.texts}
+			s := newTestSender(cli, n, "")
+			s.SetValidator(NewCommentValidator(DefaultValidationRules))
+
+			if err := sendAndDeliver(s, testMessage()); err != nil {
+				t.Fatalf("SendComment() error = %v", err)
+			}
+			if n.calls != tt.wantNeuro {
+				t.Errorf("GetComment calls = %d, want %d", n.calls, tt.wantNeuro)
+			}
+			sent := cli.SentTo(testChatID)
+			switch {
+			case tt.wantSent == "" && len(sent) != 0:
+				t.Errorf("sent %+v, want nothing", sent)
+			case tt.wantSent != "" && (len(sent) != 1 || sent[0].Text != tt.wantSent):
+				t.Errorf("sent %+v, want %q", sent, tt.wantSent)
+			}
+		})
+	}
+}
+
+func TestApprovalRegenIsValidated(t *testing.T) {
+	n := &stubNeuro{text: "Отличный разбор 👍"}
+	s, cli := newApprovalSender(t, n)
+	s.SetValidator(NewCommentValidator(DefaultValidationRules))
+	n.texts = []string{"Что дальше?", "Что дальше?", "Что дальше?"}
+
+	if err := s.HandleOwnerMessage(context.Background(), ownerCommand("/regen 1")); err != nil {
+		t.Fatalf("HandleOwnerMessage() error = %v", err)
+	}
+	if got := lastOwnerMessage(t, cli); !strings.Contains(got, "прошедшего проверку") {
+		t.Errorf("owner reply = %q, want validation failure notice", got)
+	}
+	s.mu.Lock()
+	text := s.drafts["1"].text
+	s.mu.Unlock()
+	if text != "Отличный разбор 👍" {
+		t.Errorf("draft text = %q, want the original kept", text)
+	}
+}